@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// AbsentResult describes what EnsureAbsent did.
+type AbsentResult struct {
+	// Zone is the DonDominio domain of the record
+	Zone string `json:"zone"`
+	// Record is the name of the record, as used by the DonDominio API
+	Record string `json:"record"`
+	// Deleted lists the entity IDs of the records deleted
+	Deleted []string `json:"deleted,omitempty"`
+	// Kept lists the entity IDs of the records with the value that were not
+	// created for the challenge being cleaned up, and were left alone
+	Kept []string `json:"kept,omitempty"`
+	// ZoneMissing is set when the zone itself does not exist
+	ZoneMissing bool `json:"zoneMissing,omitempty"`
+}
+
+// EnsureAbsent makes sure that zone has no TXT record named fqdn with the
+// given value, deleting all the ones found. Other records with the same name,
+// e.g. the one of a wildcard challenge sharing the name of an apex challenge,
+// are kept. It is safe to call repeatedly, and is the single deletion
+// primitive behind CleanUp and every other cleaner.
+func EnsureAbsent(ctx context.Context, provider DNSProvider, zone, fqdn, value string) (*AbsentResult, error) {
+	return ensureAbsent(ctx, provider, zone, fqdn, value, nil)
+}
+
+// ensureAbsent is EnsureAbsent for a challenge whose records were created
+// at the times given by their IDs. When some of the records found are among
+// them, only those are deleted: the others were created by another webhook,
+// e.g. in another cluster sharing the zone. When none is known, all the
+// records found are deleted.
+func ensureAbsent(ctx context.Context, provider DNSProvider, zone, fqdn, value string, created map[string]time.Time) (*AbsentResult, error) {
+	logger := klog.FromContext(ctx)
+	result := &AbsentResult{
+		Zone:   zone,
+		Record: recordName(zone, getSubDomain(zone, fqdn)),
+	}
+
+	records, err := provider.ListTXT(ctx, zone, result.Record, value)
+	switch {
+	case errors.Is(err, ErrServiceNotActive):
+		// The record went away with the zone
+		result.ZoneMissing = true
+		logger.Info("DonDominio service not found, nothing to delete", "zone", zone, "record", result.Record)
+		return result, nil
+	case err != nil:
+		return result, err
+	}
+
+	owned := false
+	for _, record := range records {
+		if _, ok := created[record.ID]; ok {
+			owned = true
+		}
+	}
+
+	now := time.Now()
+	for _, record := range records {
+		createdAt, ok := created[record.ID]
+		if owned && !ok {
+			result.Kept = append(result.Kept, record.ID)
+			logger.Info("TXT record not created for this challenge, keeping it", "record", result.Record, "entityID", record.ID)
+			continue
+		}
+		err = provider.DeleteTXT(ctx, zone, record.ID)
+		if err != nil && !errors.Is(err, ErrRecordNotFound) {
+			// ErrRecordNotFound means that a retried delete already succeeded
+			return result, err
+		}
+		result.Deleted = append(result.Deleted, record.ID)
+		if ok {
+			logger.Info("TXT record deleted", "record", result.Record, "entityID", record.ID, "age", now.Sub(createdAt).Round(time.Second))
+		} else {
+			logger.Info("TXT record deleted", "record", result.Record, "entityID", record.ID, "age", "unknown")
+		}
+	}
+
+	if len(records) == 0 {
+		logger.Info("No TXT record with the value, nothing to delete", "record", result.Record)
+	}
+	return result, nil
+}
+
+// deleteCreated deletes the records created for a challenge by their IDs,
+// without listing the records of the zone. It returns false, for the caller
+// to fall back to ensureAbsent, when no ID is known, e.g. after a restart
+// without a persisted ledger, or when none of the records was still there,
+// in case the record was recreated under another ID.
+func deleteCreated(ctx context.Context, provider DNSProvider, zone, fqdn string, created map[string]time.Time) (*AbsentResult, bool, error) {
+	logger := klog.FromContext(ctx)
+	result := &AbsentResult{
+		Zone:   zone,
+		Record: recordName(zone, getSubDomain(zone, fqdn)),
+	}
+
+	ids := make([]string, 0, len(created))
+	for id := range created {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	now := time.Now()
+	for _, id := range ids {
+		createdAt := created[id]
+		err := provider.DeleteTXT(ctx, zone, id)
+		switch {
+		case errors.Is(err, ErrServiceNotActive):
+			result.ZoneMissing = true
+			logger.Info("DonDominio service not found, nothing to delete", "zone", zone, "record", result.Record)
+			return result, true, nil
+		case errors.Is(err, ErrRecordNotFound):
+			continue
+		case err != nil:
+			return result, true, err
+		}
+		result.Deleted = append(result.Deleted, id)
+		logger.Info("TXT record deleted", "record", result.Record, "entityID", id, "age", now.Sub(createdAt).Round(time.Second))
+	}
+	return result, len(result.Deleted) > 0, nil
+}
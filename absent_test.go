@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEnsureAbsent(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, []Dns{
+		{Name: "_acme-challenge.example.com", Type: "TXT", Value: "challenge-key"},
+		{Name: "_acme-challenge.example.com", Type: "TXT", Value: "other-key"},
+		{Name: "_acme-challenge.example.com", Type: "TXT", Value: "challenge-key"},
+	})
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	ddClient, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		zone string
+		want AbsentResult
+	}{
+		{
+			name: "duplicates",
+			zone: "example.com",
+			want: AbsentResult{Zone: "example.com", Record: "_acme-challenge.example.com", Deleted: []string{"1", "3"}},
+		},
+		{
+			name: "already absent",
+			zone: "example.com",
+			want: AbsentResult{Zone: "example.com", Record: "_acme-challenge.example.com"},
+		},
+		{
+			name: "missing zone",
+			zone: "example.org",
+			want: AbsentResult{Zone: "example.org", Record: "_acme-challenge.example.org", ZoneMissing: true},
+		},
+	}
+	for _, tt := range tests {
+		got, err := EnsureAbsent(context.Background(), ddClient, tt.zone, "_acme-challenge."+tt.zone+".", "challenge-key")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("%s: EnsureAbsent() = %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+
+	if _, records := api.result(); !reflect.DeepEqual(records, []Dns{{Name: "_acme-challenge.example.com", Type: "TXT", Value: "other-key"}}) {
+		t.Errorf("records = %+v, want only other-key", records)
+	}
+}
+
+func TestEnsureAbsentCreatedRecords(t *testing.T) {
+	newProvider := func() *fakeProvider {
+		return &fakeProvider{zones: map[string][]TXTRecord{"example.com": {
+			{ID: "1", Name: "_acme-challenge.example.com", Value: "challenge-key"},
+			{ID: "2", Name: "_acme-challenge.example.com", Value: "challenge-key"},
+			{ID: "3", Name: "_acme-challenge.example.com", Value: "challenge-key"},
+		}}}
+	}
+	tests := []struct {
+		name    string
+		created map[string]time.Time
+		deleted []string
+		kept    []string
+	}{
+		{"created for the challenge", map[string]time.Time{"2": time.Now()}, []string{"2"}, []string{"1", "3"}},
+		{"unknown", map[string]time.Time{"9": time.Now()}, []string{"1", "2", "3"}, nil},
+		{"not tracked", nil, []string{"1", "2", "3"}, nil},
+	}
+	for _, tt := range tests {
+		provider := newProvider()
+		got, err := ensureAbsent(context.Background(), provider, "example.com", "_acme-challenge.example.com.", "challenge-key", tt.created)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got.Deleted, tt.deleted) || !reflect.DeepEqual(got.Kept, tt.kept) {
+			t.Errorf("%s: deleted %q and kept %q, want %q and %q", tt.name, got.Deleted, got.Kept, tt.deleted, tt.kept)
+		}
+	}
+}
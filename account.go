@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+	"k8s.io/klog/v2"
+)
+
+var (
+	apiQuotaRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_quota_remaining",
+		Help:      "Number of DonDominio API calls the account may still make, as last reported by /account/info.",
+	}, []string{"account"})
+	apiQuotaLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_quota_limit",
+		Help:      "Number of DonDominio API calls allowed to the account, as last reported by /account/info.",
+	}, []string{"account"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(apiQuotaRemaining, apiQuotaLimit)
+}
+
+const accountInfoPath = "/account/info"
+
+// AccountInfo is the DonDominio account of the credentials, as returned by
+// /account/info.
+type AccountInfo struct {
+	ClientName string  `json:"clientName,omitempty"`
+	APIUser    string  `json:"apiuser,omitempty"`
+	Balance    float64 `json:"balance,omitempty"`
+	Threshold  float64 `json:"threshold,omitempty"`
+	Currency   string  `json:"currency,omitempty"`
+	// APICallsLimit and APICallsRemaining are the API quota of the account,
+	// zero for the accounts without a quota
+	APICallsLimit     uint64 `json:"apiCallsLimit,omitempty"`
+	APICallsRemaining uint64 `json:"apiCallsRemaining,omitempty"`
+}
+
+type ddAccountInfo struct {
+	ddResponse
+	ResponseData AccountInfo `json:"responseData"`
+}
+
+// AccountInfo returns the account of the credentials of the client.
+func (c *Client) AccountInfo(ctx context.Context) (AccountInfo, error) {
+	info := ddAccountInfo{}
+	if err := c.PostWithContext(ctx, accountInfoPath, nil, &info); err != nil {
+		return AccountInfo{}, fmt.Errorf("DonDominio API call failed: POST %s - %w", accountInfoPath, err)
+	}
+	return info.ResponseData, nil
+}
+
+// accountQuota slows down the rate limiter of an account when its API quota
+// runs low, so that the requests are spread over the rest of the quota
+// rather than failing with ErrQuotaExceeded. It is shared by the clients of
+// the account.
+type accountQuota struct {
+	account string
+	// interval between two checks of the quota
+	interval time.Duration
+	// threshold is the fraction of the quota below which the requests are
+	// limited to lowLimit
+	threshold float64
+	lowLimit  rate.Limit
+	// limiter is the token bucket of the account, with its normal limit
+	limiter     *rate.Limiter
+	normalLimit rate.Limit
+
+	mu      sync.Mutex
+	checked time.Time
+}
+
+// low reports whether the remaining quota of info is below the threshold.
+// The accounts without a quota never run low.
+func (q *accountQuota) low(info AccountInfo) bool {
+	if info.APICallsLimit == 0 {
+		return false
+	}
+	return float64(info.APICallsRemaining) < q.threshold*float64(info.APICallsLimit)
+}
+
+// check refreshes the quota of the account with c if it was last checked
+// more than the interval ago, and adjusts the limit of its requests. Only one
+// of the concurrent callers checks it, and a failed check leaves the limit
+// unchanged: the requests are then sent and fail by themselves.
+func (q *accountQuota) check(ctx context.Context, c *Client) {
+	q.mu.Lock()
+	if time.Since(q.checked) < q.interval {
+		q.mu.Unlock()
+		return
+	}
+	q.checked = time.Now()
+	q.mu.Unlock()
+
+	info, err := c.AccountInfo(ctx)
+	if err != nil {
+		klog.FromContext(ctx).V(2).Info("Failed to check the API quota", "account", q.account, "err", err)
+		return
+	}
+	q.update(ctx, info)
+}
+
+// update reports the quota of info and adjusts the limit of the requests.
+func (q *accountQuota) update(ctx context.Context, info AccountInfo) {
+	if info.APICallsLimit == 0 {
+		return
+	}
+	apiQuotaRemaining.WithLabelValues(q.account).Set(float64(info.APICallsRemaining))
+	apiQuotaLimit.WithLabelValues(q.account).Set(float64(info.APICallsLimit))
+
+	limit := q.normalLimit
+	if q.low(info) && q.lowLimit < limit {
+		limit = q.lowLimit
+	}
+	if q.limiter.Limit() != limit {
+		klog.FromContext(ctx).Info("Rate limit of the account adjusted to its API quota", "account", q.account,
+			"remaining", info.APICallsRemaining, "limit", info.APICallsLimit, "rate", float64(limit))
+		q.limiter.SetLimit(limit)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestQuotaThrottling(t *testing.T) {
+	var remaining, checks int64 = 500, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case accountInfoPath:
+			atomic.AddInt64(&checks, 1)
+			fmt.Fprintf(w, `{"success": true, "responseData": {"apiuser": "apiuser", "apiCallsLimit": 1000, "apiCallsRemaining": %d}}`, atomic.LoadInt64(&remaining))
+		default:
+			fmt.Fprint(w, `{"success": true, "responseData": {"dns": []}}`)
+		}
+	}))
+	defer server.Close()
+
+	limits, err := newAccountRateLimits("", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := limits.withQuota(time.Nanosecond, 0.1, "10/m"); err != nil {
+		t.Fatal(err)
+	}
+	ddClient, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ddClient.RateLimiter = limits.forAccount("apiuser")
+	ddClient.Quota = limits.quotaForAccount("apiuser")
+	if ddClient.RateLimiter == nil || ddClient.RateLimiter.Limit() != rate.Inf {
+		t.Fatalf("limiter of an account without limit = %+v, want an unlimited one", ddClient.RateLimiter)
+	}
+
+	ctx := context.Background()
+	if _, err := ddClient.ListTXT(ctx, "example.com", "_acme-challenge.example.com", ""); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt64(&checks) != 1 || ddClient.RateLimiter.Limit() != rate.Inf {
+		t.Errorf("after %d checks with half of the quota left, limit = %v, want unlimited", checks, ddClient.RateLimiter.Limit())
+	}
+
+	atomic.StoreInt64(&remaining, 50)
+	time.Sleep(time.Millisecond)
+	if _, err := ddClient.ListTXT(ctx, "example.com", "_acme-challenge.example.com", ""); err != nil {
+		t.Fatal(err)
+	}
+	if ddClient.RateLimiter.Limit() != 10.0/60 {
+		t.Errorf("with 5%% of the quota left, limit = %v, want 10/m", ddClient.RateLimiter.Limit())
+	}
+
+	info, err := ddClient.AccountInfo(ctx)
+	if err != nil || info.APICallsRemaining != 50 || info.APICallsLimit != 1000 {
+		t.Errorf("AccountInfo() = %+v, %v", info, err)
+	}
+}
+
+func TestAccountQuotaLow(t *testing.T) {
+	q := &accountQuota{threshold: 0.2}
+	for _, tt := range []struct {
+		info AccountInfo
+		want bool
+	}{
+		{AccountInfo{}, false},
+		{AccountInfo{APICallsLimit: 100, APICallsRemaining: 50}, false},
+		{AccountInfo{APICallsLimit: 100, APICallsRemaining: 19}, true},
+		{AccountInfo{APICallsLimit: 100}, true},
+	} {
+		if got := q.low(tt.info); got != tt.want {
+			t.Errorf("low(%+v) = %v, want %v", tt.info, got, tt.want)
+		}
+	}
+
+	limits, err := newAccountRateLimits("", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, threshold := range []float64{0, 1.5} {
+		if err := limits.withQuota(time.Minute, threshold, "10/m"); err == nil {
+			t.Errorf("withQuota(%v) did not fail", threshold)
+		}
+	}
+	if quota := limits.quotaForAccount("apiuser"); quota != nil {
+		t.Errorf("quota without checks = %+v, want none", quota)
+	}
+}
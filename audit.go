@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"k8s.io/klog/v2"
+)
+
+// Actions of the audit entries.
+const (
+	auditCreate = "create"
+	auditUpdate = "update"
+	auditDelete = "delete"
+)
+
+// auditValueHashLength is the number of hex digits of the hash of the
+// record values in the audit entries, enough to match them with the
+// challenge keys, which the entries do not reveal.
+const auditValueHashLength = 16
+
+// auditEntry is a line of the audit log, recording a mutation of a zone.
+type auditEntry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Zone   string    `json:"zone"`
+	// Name and Type of the record, empty for the deletions, which only
+	// know its ID
+	Name string `json:"name,omitempty"`
+	Type string `json:"type,omitempty"`
+	ID   string `json:"id,omitempty"`
+	// ValueHash is the truncated SHA-256 of the value of the record
+	ValueHash string `json:"valueHash,omitempty"`
+	// Namespace and FQDN of the challenge that made the change, empty for
+	// the other changes, e.g. of the orphan collector or ddctl
+	Namespace string `json:"namespace,omitempty"`
+	FQDN      string `json:"fqdn,omitempty"`
+	// Outcome is success, read-only for the changes not sent because of
+	// --read-only, or failure, with the Error of the failures. A failure may
+	// still have changed the zone.
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Outcomes of the audit entries.
+const (
+	auditSuccess  = "success"
+	auditReadOnly = "read-only"
+	auditFailure  = "failure"
+)
+
+// auditLogger appends the audit entries to its output, one JSON object per
+// line.
+type auditLogger struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	// file is the output to close on shutdown, nil for stdout
+	file   io.Closer
+	closed bool
+}
+
+// auditLog is the audit log set up by setupAuditLog, nil if disabled.
+var auditLog *auditLogger
+
+// setupAuditLog sets up the audit log of the DNS mutations: to stdout if
+// path is "-", appended to the file at path otherwise, and disabled if it is
+// empty. The file is closed by the shutdown, once the challenges in progress
+// are drained.
+func setupAuditLog(path string) error {
+	switch path {
+	case "":
+		auditLog = nil
+		return nil
+	case "-":
+		auditLog = newAuditLogger(os.Stdout)
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open the audit log: %w", err)
+	}
+	auditLog = newAuditLogger(file)
+	auditLog.file = file
+	return nil
+}
+
+func newAuditLogger(w io.Writer) *auditLogger {
+	return &auditLogger{enc: json.NewEncoder(w)}
+}
+
+// close closes the file of the audit log, after which the entries are
+// dropped. It does nothing on a nil auditLogger.
+func (a *auditLogger) close() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.closed = true
+	if a.file != nil {
+		if err := a.file.Close(); err != nil {
+			klog.ErrorS(err, "Failed to close the audit log")
+		}
+	}
+}
+
+// record completes the entry with the time, the challenge of ctx and the
+// outcome of err, unless already set, and appends it. It does nothing on a
+// nil auditLogger.
+func (a *auditLogger) record(ctx context.Context, entry auditEntry, err error) {
+	if a == nil {
+		return
+	}
+	entry.Time = time.Now().UTC()
+	if ch, ok := ctx.Value(auditChallengeKey{}).(*v1alpha1.ChallengeRequest); ok {
+		entry.Namespace = ch.ResourceNamespace
+		entry.FQDN = ch.ResolvedFQDN
+	}
+	if err != nil {
+		entry.Outcome = auditFailure
+		entry.Error = redactSecrets(err.Error())
+	} else if entry.Outcome == "" {
+		entry.Outcome = auditSuccess
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		klog.FromContext(ctx).Error(nil, "Audit entry dropped after the audit log was closed", "action", entry.Action, "zone", entry.Zone, "id", entry.ID)
+		return
+	}
+	if err := a.enc.Encode(entry); err != nil {
+		klog.FromContext(ctx).Error(err, "Failed to write the audit log", "action", entry.Action, "zone", entry.Zone)
+	}
+}
+
+// auditValueHash returns the truncated SHA-256 of a record value, empty for
+// an empty value.
+func auditValueHash(value string) string {
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])[:auditValueHashLength]
+}
+
+// audit appends the entry of a mutation made with c to the audit log, with
+// the read-only outcome if c does not send the mutations.
+func (c *Client) audit(ctx context.Context, entry auditEntry, err error) {
+	if c.ReadOnly {
+		entry.Outcome = auditReadOnly
+	}
+	auditLog.record(ctx, entry, err)
+}
+
+type auditChallengeKey struct{}
+
+// auditContext returns ctx with the challenge whose changes are audited.
+func auditContext(ctx context.Context, ch *v1alpha1.ChallengeRequest) context.Context {
+	return context.WithValue(ctx, auditChallengeKey{}, ch)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func TestAuditLog(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, nil)
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	var buf bytes.Buffer
+	auditLog = newAuditLogger(&buf)
+	defer func() { auditLog = nil }()
+
+	ddClient, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := &v1alpha1.ChallengeRequest{ResourceNamespace: "team", ResolvedFQDN: "_acme-challenge.www.example.com."}
+	ctx := auditContext(context.Background(), ch)
+	record, err := ddClient.CreateTXT(ctx, "example.com", "_acme-challenge.www.example.com", "key", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ddClient.DeleteTXT(context.Background(), "example.com", record.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []auditEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry auditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("audit line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("audit entries = %+v, want the creation and the deletion", entries)
+	}
+	created, deleted := entries[0], entries[1]
+	if created.Action != auditCreate || created.Zone != "example.com" || created.Name != "_acme-challenge.www.example.com" || created.Type != RecordTypeTXT ||
+		created.ID != record.ID || created.ValueHash != auditValueHash("key") || created.Namespace != "team" || created.FQDN != ch.ResolvedFQDN || created.Outcome != auditSuccess {
+		t.Errorf("creation entry = %+v", created)
+	}
+	if strings.Contains(buf.String(), `"key"`) {
+		t.Errorf("audit log %q reveals the record value", buf.String())
+	}
+	if deleted.Action != auditDelete || deleted.ID != record.ID || deleted.Namespace != "" || deleted.Outcome != auditSuccess {
+		t.Errorf("deletion entry = %+v", deleted)
+	}
+}
+
+func TestAuditLogFailure(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, nil)
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	var buf bytes.Buffer
+	auditLog = newAuditLogger(&buf)
+	defer func() { auditLog = nil }()
+
+	ddClient, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ddClient.DeleteTXT(context.Background(), "example.com", "404"); err == nil {
+		t.Fatal("deleting a missing record succeeded")
+	}
+	var entry auditEntry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Action != auditDelete || entry.Outcome != auditFailure || entry.Error == "" {
+		t.Errorf("failed deletion entry = %+v", entry)
+	}
+}
+
+func TestAuditLogReadOnly(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, nil)
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	var buf bytes.Buffer
+	auditLog = newAuditLogger(&buf)
+	defer func() { auditLog = nil }()
+
+	ddClient, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ddClient.ReadOnly = true
+	if err := ddClient.DeleteTXT(context.Background(), "example.com", "1"); err != nil {
+		t.Fatal(err)
+	}
+	var entry auditEntry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Action != auditDelete || entry.Outcome != auditReadOnly {
+		t.Errorf("read-only deletion entry = %+v, want the read-only outcome", entry)
+	}
+
+	buf.Reset()
+	auditLog.close()
+	auditLog.record(context.Background(), auditEntry{Action: auditDelete, Zone: "example.com"}, nil)
+	if buf.Len() != 0 {
+		t.Errorf("entry %q written after the audit log was closed", buf.String())
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/url"
+	"testing"
+)
+
+func TestHMACAuthenticator(t *testing.T) {
+	ddClient, err := NewClient("https://simple-api.dondominio.net", "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ddClient.Auth = newAuthenticator(authSchemeHMAC, ddClient.AppKey, ddClient.AppSecret)
+	req, err := ddClient.NewRequest("POST", "/service/dnslist", &ddServiceStatusParams{ServiceName: "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(req.Body)
+	form, err := url.ParseQuery(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if form.Has("apipasswd") || form.Get("apiuser") != "apiuser" || form.Get("timestamp") == "" || form.Get("serviceName") != "example.com" {
+		t.Errorf("signed form = %v, want the user, the timestamp and no password", form)
+	}
+
+	mac := hmac.New(sha256.New, []byte("apipasswd"))
+	mac.Write([]byte("POST\nhttps://simple-api.dondominio.net/service/dnslist\n" + string(data)))
+	if got, want := req.Header.Get(signatureHeader), hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+}
+
+func TestFormAuthenticator(t *testing.T) {
+	ddClient, err := NewClient("https://simple-api.dondominio.net", "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, auth := range []Authenticator{nil, newAuthenticator("", "apiuser", "apipasswd")} {
+		ddClient.Auth = auth
+		req, err := ddClient.NewRequest("POST", "/auth/time", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(req.Body)
+		form, _ := url.ParseQuery(string(data))
+		if form.Get("apiuser") != "apiuser" || form.Get("apipasswd") != "apipasswd" || req.Header.Get(signatureHeader) != "" {
+			t.Errorf("form = %v with the signature %q, want the credentials unsigned", form, req.Header.Get(signatureHeader))
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// zoneBatcher coalesces the lookups of the concurrent challenges of a same
+// zone, e.g. of a certificate with dozens of DNS names, into fewer API round
+// trips. The record creations cannot be batched, since DonDominio creates
+// one record per call, but their zone validation and conflict lookups can:
+//   - the concurrent validations of a zone share a single call;
+//   - the TXT lookups of a zone starting within the window share a single
+//     listing of all the TXT records of the zone, filtered locally.
+//
+// The results are never reused past the calls that waited for them, so a
+// lookup always sees the records created before it started.
+type zoneBatcher struct {
+	// window is how long the first lookup of a zone waits for others
+	window time.Duration
+
+	mu          sync.Mutex
+	validations map[string]*batchCall
+	listings    map[string]*txtListing
+}
+
+// batchCall is a call shared by the callers that joined it.
+type batchCall struct {
+	done chan struct{}
+	err  error
+}
+
+// txtListing is a listing of the TXT records of a zone, shared by the
+// lookups that joined it during the window.
+type txtListing struct {
+	batchCall
+	lookups int
+	records []TXTRecord
+}
+
+// validate calls check for zone, unless a validation of zone is in flight,
+// whose result is then returned.
+func (b *zoneBatcher) validate(ctx context.Context, zone string, check func(ctx context.Context, zone string) error) error {
+	b.mu.Lock()
+	if call, ok := b.validations[zone]; ok {
+		b.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if isContextError(call.err) && ctx.Err() == nil {
+			// The caller that made the call gave up, not this one
+			return check(ctx, zone)
+		}
+		return call.err
+	}
+	call := &batchCall{done: make(chan struct{})}
+	if b.validations == nil {
+		b.validations = make(map[string]*batchCall)
+	}
+	b.validations[zone] = call
+	b.mu.Unlock()
+
+	call.err = check(ctx, zone)
+	b.mu.Lock()
+	delete(b.validations, zone)
+	b.mu.Unlock()
+	close(call.done)
+	return call.err
+}
+
+// listTXT returns the TXT records of zone with the given name and, if not
+// empty, value. The first lookup of the zone waits for the window: if no
+// other lookup joined it, it calls list for its own name, and otherwise once
+// for all the TXT records of the zone.
+func (b *zoneBatcher) listTXT(ctx context.Context, zone, name, value string, list func(ctx context.Context, zone, name, value string) ([]TXTRecord, error)) ([]TXTRecord, error) {
+	b.mu.Lock()
+	listing, joined := b.listings[zone]
+	if joined {
+		listing.lookups++
+	} else {
+		listing = &txtListing{batchCall: batchCall{done: make(chan struct{})}, lookups: 1}
+		if b.listings == nil {
+			b.listings = make(map[string]*txtListing)
+		}
+		b.listings[zone] = listing
+	}
+	b.mu.Unlock()
+
+	if joined {
+		select {
+		case <-listing.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if isContextError(listing.err) && ctx.Err() == nil {
+			return list(ctx, zone, name, value)
+		}
+		return filterTXT(listing.records, name, value), listing.err
+	}
+
+	timer := time.NewTimer(b.window)
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		timer.Stop()
+	}
+	b.mu.Lock()
+	delete(b.listings, zone)
+	lookups := listing.lookups
+	b.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		listing.err = err
+		close(listing.done)
+		return nil, err
+	}
+	if lookups == 1 {
+		listing.records, listing.err = list(ctx, zone, name, value)
+		close(listing.done)
+		return listing.records, listing.err
+	}
+	listing.records, listing.err = list(ctx, zone, "", "")
+	close(listing.done)
+	return filterTXT(listing.records, name, value), listing.err
+}
+
+// filterTXT returns the records with the given name and, if not empty,
+// value.
+func filterTXT(records []TXTRecord, name, value string) []TXTRecord {
+	var selected []TXTRecord
+	for _, record := range records {
+		if record.Name == name && (value == "" || record.Value == value) {
+			selected = append(selected, record)
+		}
+	}
+	return selected
+}
+
+// isContextError reports whether err is the cancellation or the deadline of
+// a context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// batchedProvider coalesces the lookups of the concurrent challenges of a
+// same zone with its zoneBatcher.
+type batchedProvider struct {
+	DNSProvider
+	batcher *zoneBatcher
+}
+
+// ValidateZone implements DNSProvider.
+func (p batchedProvider) ValidateZone(ctx context.Context, zone string) error {
+	return p.batcher.validate(ctx, zone, p.DNSProvider.ValidateZone)
+}
+
+// ListTXT implements DNSProvider.
+func (p batchedProvider) ListTXT(ctx context.Context, zone, name, value string) ([]TXTRecord, error) {
+	return p.batcher.listTXT(ctx, zone, name, value, p.DNSProvider.ListTXT)
+}
+
+// ListRecords implements recordManager, if the provider does.
+func (p batchedProvider) ListRecords(ctx context.Context, zone string, filter RecordFilter) ([]Record, error) {
+	manager, ok := p.DNSProvider.(recordManager)
+	if !ok {
+		return nil, errRecordTypesNotSupported
+	}
+	return manager.ListRecords(ctx, zone, filter)
+}
+
+// CreateRecord implements recordManager, if the provider does.
+func (p batchedProvider) CreateRecord(ctx context.Context, zone string, params RecordParams) (Record, error) {
+	manager, ok := p.DNSProvider.(recordManager)
+	if !ok {
+		return Record{}, errRecordTypesNotSupported
+	}
+	return manager.CreateRecord(ctx, zone, params)
+}
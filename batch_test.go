@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingProvider counts the lookups of a read-only fakeProvider, whose
+// ListTXT lists the whole zone for an empty name.
+type countingProvider struct {
+	*fakeProvider
+	validations, listings int64
+}
+
+func (p *countingProvider) ValidateZone(ctx context.Context, zone string) error {
+	atomic.AddInt64(&p.validations, 1)
+	time.Sleep(50 * time.Millisecond)
+	return p.fakeProvider.ValidateZone(ctx, zone)
+}
+
+func (p *countingProvider) ListTXT(ctx context.Context, zone, name, value string) ([]TXTRecord, error) {
+	atomic.AddInt64(&p.listings, 1)
+	if name == "" {
+		return p.zones[zone], nil
+	}
+	return p.fakeProvider.ListTXT(ctx, zone, name, value)
+}
+
+func TestBatchedProvider(t *testing.T) {
+	counting := &countingProvider{fakeProvider: &fakeProvider{zones: map[string][]TXTRecord{
+		"example.com": {
+			{ID: "1", Name: "_acme-challenge.a.example.com", Value: "key-a"},
+			{ID: "2", Name: "_acme-challenge.b.example.com", Value: "key-b"},
+		},
+	}}}
+	provider := batchedProvider{DNSProvider: counting, batcher: &zoneBatcher{window: 100 * time.Millisecond}}
+
+	names := []string{"a", "b", "c", "d"}
+	found := make([][]TXTRecord, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			ctx := context.Background()
+			if err := provider.ValidateZone(ctx, "example.com"); err != nil {
+				t.Error(err)
+			}
+			records, err := provider.ListTXT(ctx, "example.com", "_acme-challenge."+name+".example.com", "")
+			if err != nil {
+				t.Error(err)
+			}
+			found[i] = records
+		}(i, name)
+	}
+	wg.Wait()
+
+	if counting.validations != 1 {
+		t.Errorf("%d zone validations, want 1", counting.validations)
+	}
+	if counting.listings != 1 {
+		t.Errorf("%d listings, want 1", counting.listings)
+	}
+	if len(found[0]) != 1 || found[0][0].Value != "key-a" || len(found[1]) != 1 || found[1][0].Value != "key-b" || len(found[2]) != 0 {
+		t.Errorf("records found = %+v", found)
+	}
+
+	// A lookup alone lists its own name
+	records, err := provider.ListTXT(context.Background(), "example.com", "_acme-challenge.a.example.com", "key-a")
+	if err != nil || len(records) != 1 || counting.listings != 2 {
+		t.Errorf("ListTXT() = %+v, %v after %d listings", records, err, counting.listings)
+	}
+	if err := provider.ValidateZone(context.Background(), "example.org"); err == nil {
+		t.Error("the validation of an unknown zone succeeded")
+	}
+}
+
+func TestBatchedProviderCanceled(t *testing.T) {
+	counting := &countingProvider{fakeProvider: &fakeProvider{zones: map[string][]TXTRecord{"example.com": nil}}}
+	batcher := &zoneBatcher{window: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := batcher.listTXT(ctx, "example.com", "_acme-challenge.example.com", "", counting.ListTXT); err != context.Canceled {
+		t.Errorf("listTXT() error = %v, want the cancellation", err)
+	}
+	if counting.listings != 0 {
+		t.Errorf("%d listings after the cancellation, want none", counting.listings)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// DefaultMaxResponseSize is the size above which the body of a DonDominio
+// API response is rejected with ErrResponseTooLarge. The largest responses,
+// the DNS record lists of big zones, are well below.
+const DefaultMaxResponseSize = 8 << 20
+
+// decodeResponse makes the body of the response transparently decompressed,
+// if the API compressed it, and bounded to limit bytes once decompressed, so
+// that a misbehaving proxy or a compression bomb cannot exhaust the memory.
+func decodeResponse(resp *http.Response, limit int64) error {
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return fmt.Errorf("invalid gzip response body: %w", err)
+		}
+		resp.Body = gzipBody{Reader: gz, body: resp.Body}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+	}
+	if limit > 0 {
+		resp.Body = &limitedBody{body: resp.Body, limit: limit}
+	}
+	return nil
+}
+
+// gzipBody decompresses the body of a response.
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
+
+// limitedBody fails with ErrResponseTooLarge once more than limit bytes have
+// been read from the body.
+type limitedBody struct {
+	body  io.ReadCloser
+	limit int64
+	read  int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.read > b.limit {
+		return 0, b.tooLarge()
+	}
+	// Read at most one byte past the limit
+	if remaining := b.limit - b.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := b.body.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		return n, b.tooLarge()
+	}
+	return n, err
+}
+
+func (b *limitedBody) tooLarge() error {
+	return fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, b.limit)
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
+
+// maxResponseSizeBytes returns the limit of --max-response-size.
+func maxResponseSizeBytes() (int64, error) {
+	size, err := resource.ParseQuantity(*maxResponseSize)
+	if err != nil || size.Sign() <= 0 {
+		return 0, fmt.Errorf("invalid --max-response-size %q, must be a positive quantity like 8Mi", *maxResponseSize)
+	}
+	return size.Value(), nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzipResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("Accept-Encoding = %q, want gzip", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`{"success": true, "errorCode": 0, "responseData": {"ip": "192.0.2.1"}}`))
+		gz.Close()
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res struct {
+		ddResponse
+		ResponseData struct {
+			IP string `json:"ip"`
+		} `json:"responseData"`
+	}
+	if err := client.PostWithContext(context.Background(), "/test/gzip", nil, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.ResponseData.IP != "192.0.2.1" {
+		t.Errorf("responseData = %+v", res.ResponseData)
+	}
+}
+
+func TestResponseTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success": true, "errorCode": 0, "messages": ["` + strings.Repeat("x", 4096) + `"]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.MaxResponseSize = 1024
+	err = client.PostWithContext(context.Background(), "/test/large", nil, &ddResponse{})
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("PostWithContext() = %v, want %v", err, ErrResponseTooLarge)
+	}
+
+	client.MaxResponseSize = DefaultMaxResponseSize
+	if err := client.PostWithContext(context.Background(), "/test/large", nil, &ddResponse{}); err != nil {
+		t.Errorf("PostWithContext() = %v", err)
+	}
+}
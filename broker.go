@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// credentialsBrokerConfig configures the exchange of the service account
+// token of the webhook for short-lived DonDominio credentials.
+type credentialsBrokerConfig struct {
+	// URL of the broker, which receives the token as a bearer token
+	URL string `json:"url"`
+	// TokenPath is the file of the projected service account token. It
+	// defaults to the --broker-token-file flag.
+	TokenPath string `json:"tokenPath,omitempty"`
+}
+
+// brokerCredentials are the DonDominio credentials returned by a broker.
+type brokerCredentials struct {
+	APIUser     string    `json:"apiUser"`
+	APIPassword string    `json:"apiPassword"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// brokerRenewBefore is how long before their expiry the credentials are
+// renewed, so that they do not expire in the middle of a challenge.
+const brokerRenewBefore = time.Minute
+
+// credentialsBroker gets credentials from the brokers and caches them until
+// shortly before they expire. Its zero value is ready to use.
+type credentialsBroker struct {
+	// httpClient defaults to http.DefaultClient
+	httpClient *http.Client
+
+	// exchanges runs a single exchange at a time per broker and token
+	exchanges callGroup
+
+	mu sync.Mutex
+	// cache maps the broker URLs and token files to their last credentials
+	cache map[string]brokerCredentials
+}
+
+// tokenPath returns the file of the service account token sent to the
+// broker.
+func (cfg *credentialsBrokerConfig) tokenPath() string {
+	if cfg.TokenPath == "" {
+		return *brokerTokenFile
+	}
+	return cfg.TokenPath
+}
+
+// credentials returns valid credentials from the broker of cfg. The
+// concurrent callers for the same broker and token share a single exchange,
+// while the other brokers are called in parallel.
+func (b *credentialsBroker) credentials(ctx context.Context, cfg *credentialsBrokerConfig) (brokerCredentials, error) {
+	key := cfg.URL + "\x00" + cfg.tokenPath()
+	if creds, ok := b.cached(key); ok && time.Until(creds.ExpiresAt) > brokerRenewBefore {
+		return creds, nil
+	}
+
+	_, err := b.exchanges.do(ctx, key, func() error {
+		creds, err := b.exchange(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.cache == nil {
+			b.cache = map[string]brokerCredentials{}
+		}
+		b.cache[key] = creds
+		return nil
+	})
+	if err != nil {
+		return brokerCredentials{}, err
+	}
+	creds, _ := b.cached(key)
+	return creds, nil
+}
+
+// cached returns the last credentials of key, if any.
+func (b *credentialsBroker) cached(key string) (brokerCredentials, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	creds, ok := b.cache[key]
+	return creds, ok
+}
+
+// exchange sends the service account token to the broker. The token is read
+// on each exchange, since the kubelet rotates it.
+func (b *credentialsBroker) exchange(ctx context.Context, cfg *credentialsBrokerConfig) (brokerCredentials, error) {
+	token, err := os.ReadFile(cfg.tokenPath())
+	if err != nil {
+		return brokerCredentials{}, fmt.Errorf("failed to read the service account token for the credentials broker: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, nil)
+	if err != nil {
+		return brokerCredentials{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+
+	httpClient := b.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return brokerCredentials{}, fmt.Errorf("credentials broker call failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return brokerCredentials{}, fmt.Errorf("credentials broker call failed: POST %s - %s", cfg.URL, resp.Status)
+	}
+	var creds brokerCredentials
+	if err := json.NewDecoder(resp.Body).Decode(&creds); err != nil {
+		return brokerCredentials{}, fmt.Errorf("invalid response from the credentials broker: %w", err)
+	}
+	if creds.APIUser == "" || creds.APIPassword == "" {
+		return brokerCredentials{}, fmt.Errorf("invalid response from the credentials broker: missing credentials")
+	}
+	return creds, nil
+}
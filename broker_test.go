@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCredentialsBroker(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte("sa-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	expiresIn := 30 * time.Second
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if got := r.Header.Get("Authorization"); got != "Bearer sa-token" {
+			http.Error(w, "invalid token "+got, http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(brokerCredentials{
+			APIUser:     "apiuser",
+			APIPassword: "apipasswd",
+			ExpiresAt:   time.Now().Add(expiresIn),
+		})
+	}))
+	defer server.Close()
+
+	var b credentialsBroker
+	cfg := &credentialsBrokerConfig{URL: server.URL, TokenPath: tokenPath}
+
+	// Credentials expiring within brokerRenewBefore are not reused
+	for i := 1; i <= 2; i++ {
+		creds, err := b.credentials(context.Background(), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds.APIUser != "apiuser" || creds.APIPassword != "apipasswd" {
+			t.Errorf("credentials() = %+v", creds)
+		}
+		if calls != i {
+			t.Errorf("broker called %d times, want %d", calls, i)
+		}
+	}
+
+	expiresIn = time.Hour
+	for i := 0; i < 2; i++ {
+		if _, err := b.credentials(context.Background(), cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("broker called %d times, want 3", calls)
+	}
+
+	if _, err := b.credentials(context.Background(), &credentialsBrokerConfig{URL: server.URL + "/other", TokenPath: filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("credentials() without token succeeded")
+	}
+}
+
+func TestCredentialsBrokerConcurrentExchanges(t *testing.T) {
+	dir := t.TempDir()
+	tokenPaths := []string{filepath.Join(dir, "token"), filepath.Join(dir, "other-token")}
+	for _, tokenPath := range tokenPaths {
+		if err := os.WriteFile(tokenPath, []byte(filepath.Base(tokenPath)), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var calls int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		json.NewEncoder(w).Encode(brokerCredentials{
+			APIUser:     r.Header.Get("Authorization"),
+			APIPassword: "apipasswd",
+			ExpiresAt:   time.Now().Add(time.Hour),
+		})
+	}))
+	defer server.Close()
+
+	// The exchanges of each token are shared, and both tokens of the same
+	// broker are exchanged in parallel
+	var b credentialsBroker
+	var wg sync.WaitGroup
+	users := make([]string, 6)
+	for i := range users {
+		i := i
+		cfg := &credentialsBrokerConfig{URL: server.URL, TokenPath: tokenPaths[i%2]}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			creds, err := b.credentials(context.Background(), cfg)
+			if err != nil {
+				t.Error(err)
+			}
+			users[i] = creds.APIUser
+		}()
+	}
+	for deadline := time.Now().Add(5 * time.Second); atomic.LoadInt32(&calls) < 2 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("broker called %d times, want once per token", got)
+	}
+	for i, user := range users {
+		if want := "Bearer " + filepath.Base(tokenPaths[i%2]); user != want {
+			t.Errorf("credentials of token %d = %q, want %q", i%2, user, want)
+		}
+	}
+}
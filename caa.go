@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
+)
+
+// caaConfig configures the CAA records that Present makes sure the zone has
+// before presenting a challenge, so that only the ACME CA, and optionally
+// only one of its accounts (RFC 8657), may issue certificates for the zone.
+// Once a zone has CAA records, the CAs they don't list can no longer issue.
+type caaConfig struct {
+	// IssuerDomain is the domain identifying the CA, e.g. letsencrypt.org
+	IssuerDomain string `json:"issuerDomain"`
+	// AccountURI, if set, is the URI of the ACME account, e.g.
+	// https://acme-v02.api.letsencrypt.org/acme/acct/123456, the only one
+	// allowed to issue
+	AccountURI string `json:"accountURI,omitempty"`
+	// Wildcard also authorizes the CA to issue wildcard certificates with
+	// an issuewild record
+	Wildcard bool `json:"wildcard,omitempty"`
+}
+
+// values returns the values of the CAA records authorizing the CA, in the
+// format of DonDominio, e.g. 0 issue "letsencrypt.org; accounturi=...".
+func (c *caaConfig) values() []string {
+	value := c.IssuerDomain
+	if c.AccountURI != "" {
+		value += "; accounturi=" + c.AccountURI
+	}
+	values := []string{`0 issue "` + value + `"`}
+	if c.Wildcard {
+		values = append(values, `0 issuewild "`+value+`"`)
+	}
+	return values
+}
+
+// validate returns the problems of the CAA config.
+func (c *caaConfig) validate(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	switch {
+	case c.IssuerDomain == "":
+		allErrs = append(allErrs, field.Required(path.Child("issuerDomain"), ""))
+	case strings.ContainsAny(c.IssuerDomain, "\"; \t"):
+		allErrs = append(allErrs, field.Invalid(path.Child("issuerDomain"), c.IssuerDomain, "must be a domain name, e.g. letsencrypt.org"))
+	}
+	if c.AccountURI != "" {
+		if strings.ContainsAny(c.AccountURI, "\"; \t") {
+			allErrs = append(allErrs, field.Invalid(path.Child("accountURI"), c.AccountURI, "must not contain quotes, semicolons or spaces"))
+		} else {
+			allErrs = append(allErrs, validateURL(path.Child("accountURI"), c.AccountURI)...)
+		}
+	}
+	return allErrs
+}
+
+// recordManager is implemented by the providers managing every record type,
+// like the DonDominio Client.
+type recordManager interface {
+	ListRecords(ctx context.Context, zone string, filter RecordFilter) ([]Record, error)
+	CreateRecord(ctx context.Context, zone string, params RecordParams) (Record, error)
+}
+
+// errRecordTypesNotSupported is returned when a provider only manages TXT
+// records.
+var errRecordTypesNotSupported = errors.New("the DNS provider only manages TXT records")
+
+// ensureCAA creates the CAA records of the config missing at the apex of
+// zone. The other CAA records of the zone are left alone.
+func ensureCAA(ctx context.Context, provider DNSProvider, zone string, caa *caaConfig) error {
+	manager, ok := provider.(recordManager)
+	if !ok {
+		return errRecordTypesNotSupported
+	}
+	existing, err := manager.ListRecords(ctx, zone, RecordFilter{Name: zone, Type: RecordTypeCAA})
+	if err != nil {
+		return err
+	}
+	for _, value := range caa.values() {
+		if hasRecordValue(existing, value) {
+			continue
+		}
+		if _, err := manager.CreateRecord(ctx, zone, RecordParams{Name: zone, Type: RecordTypeCAA, Value: value}); err != nil {
+			return err
+		}
+		klog.FromContext(ctx).Info("CAA record created", "zone", zone, "value", value)
+	}
+	return nil
+}
+
+// hasRecordValue reports whether one of the records has the value, ignoring
+// the case of the tag and the issuer, which DonDominio may normalize.
+func hasRecordValue(records []Record, value string) bool {
+	for _, record := range records {
+		if strings.EqualFold(record.Value, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestCAAConfigValidate(t *testing.T) {
+	tests := []struct {
+		caa    caaConfig
+		fields []string
+	}{
+		{caaConfig{IssuerDomain: "letsencrypt.org", AccountURI: "https://acme-v02.api.letsencrypt.org/acme/acct/123"}, nil},
+		{caaConfig{}, []string{"caa.issuerDomain"}},
+		{caaConfig{IssuerDomain: `letsencrypt.org"; issue "evil.example`}, []string{"caa.issuerDomain"}},
+		{caaConfig{IssuerDomain: "letsencrypt.org", AccountURI: "acct/123"}, []string{"caa.accountURI"}},
+	}
+	for _, tt := range tests {
+		var fields []string
+		for _, err := range tt.caa.validate(field.NewPath("caa")) {
+			fields = append(fields, err.Field)
+		}
+		if len(fields) != len(tt.fields) || (len(fields) > 0 && fields[0] != tt.fields[0]) {
+			t.Errorf("validate(%+v) fields = %v, want %v", tt.caa, fields, tt.fields)
+		}
+	}
+}
+
+func TestEnsureCAAUnsupportedProvider(t *testing.T) {
+	provider := zoneSerializedProvider{DNSProvider: &fakeProvider{zones: map[string][]TXTRecord{"example.com": nil}}, zones: &keyedMutex{}}
+	err := ensureCAA(context.Background(), provider, "example.com", &caaConfig{IssuerDomain: "letsencrypt.org"})
+	if !errors.Is(err, errRecordTypesNotSupported) {
+		t.Errorf("ensureCAA() error = %v, want %v", err, errRecordTypesNotSupported)
+	}
+}
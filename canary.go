@@ -0,0 +1,139 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// schemaCanaryPath is the read-only endpoint called by the schema canary.
+const schemaCanaryPath = "/tool/hello"
+
+// schemaCanaryFixture is a pinned response of schemaCanaryPath. Only its
+// structure (field names and value kinds) is compared, values are ignored.
+//
+//go:embed schemas/tool_hello.json
+var schemaCanaryFixture []byte
+
+// runSchemaCanary calls schemaCanaryPath every interval with the client
+// returned by newClient and warns when the structure of the response no
+// longer matches schemaCanaryFixture, so that changes to the DonDominio API
+// are noticed before they break issuance. It returns when stopCh is closed.
+func runSchemaCanary(newClient func() (*Client, error), interval time.Duration, stopCh <-chan struct{}) {
+	var expected interface{}
+	if err := json.Unmarshal(schemaCanaryFixture, &expected); err != nil {
+		klog.ErrorS(err, "Schema canary disabled: invalid pinned schema")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if ddClient, err := newClient(); err != nil {
+			klog.ErrorS(err, "Schema canary: no DonDominio client")
+		} else {
+			checkSchema(ddClient, expected)
+		}
+
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func checkSchema(ddClient *Client, expected interface{}) {
+	var actual interface{}
+	err := ddClient.Post(schemaCanaryPath, nil, &actual)
+	if err != nil {
+		klog.ErrorS(err, "Schema canary: DonDominio API call failed", "path", schemaCanaryPath)
+		return
+	}
+
+	drifts := schemaDrift("", expected, actual)
+	if len(drifts) == 0 {
+		return
+	}
+	klog.Warningf("DonDominio API schema drift detected, issuance may break: path=%s drifts=%q", schemaCanaryPath, drifts)
+}
+
+// schemaDrift compares the structure of two decoded JSON documents and
+// returns a description of every difference found below path.
+func schemaDrift(path string, expected, actual interface{}) []string {
+	if expected == nil {
+		// null in the fixture matches any value
+		return nil
+	}
+	if jsonKind(expected) != jsonKind(actual) {
+		return []string{fmt.Sprintf("%s: expected %s, got %s", schemaPath(path), jsonKind(expected), jsonKind(actual))}
+	}
+
+	var drifts []string
+	switch expected := expected.(type) {
+	case map[string]interface{}:
+		actual := actual.(map[string]interface{})
+		for _, key := range sortedKeys(expected) {
+			value, ok := actual[key]
+			if !ok {
+				drifts = append(drifts, fmt.Sprintf("%s: missing field", schemaPath(path+"."+key)))
+				continue
+			}
+			drifts = append(drifts, schemaDrift(path+"."+key, expected[key], value)...)
+		}
+		for _, key := range sortedKeys(actual) {
+			if _, ok := expected[key]; !ok {
+				drifts = append(drifts, fmt.Sprintf("%s: unexpected field", schemaPath(path+"."+key)))
+			}
+		}
+	case []interface{}:
+		// The first element of the fixture describes every element
+		actual := actual.([]interface{})
+		if len(expected) == 0 {
+			break
+		}
+		for i, value := range actual {
+			drifts = append(drifts, schemaDrift(fmt.Sprintf("%s[%d]", path, i), expected[0], value)...)
+		}
+	}
+	return drifts
+}
+
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func schemaPath(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
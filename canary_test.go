@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaDrift(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		actual   string
+		drifts   []string
+	}{
+		{
+			name:     "identical structure",
+			expected: `{"success":true,"responseData":{"ip":"203.0.113.10"}}`,
+			actual:   `{"success":false,"responseData":{"ip":"198.51.100.1"}}`,
+		},
+		{
+			name:     "missing and unexpected fields",
+			expected: `{"success":true,"errorCode":0}`,
+			actual:   `{"success":true,"code":0}`,
+			drifts:   []string{".errorCode: missing field", ".code: unexpected field"},
+		},
+		{
+			name:     "changed kind",
+			expected: `{"responseData":{"version":"1.0.20"}}`,
+			actual:   `{"responseData":{"version":1}}`,
+			drifts:   []string{".responseData.version: expected string, got number"},
+		},
+		{
+			name:     "array elements",
+			expected: `{"dns":[{"name":"a"}]}`,
+			actual:   `{"dns":[{"name":"a"},{"name":2}]}`,
+			drifts:   []string{".dns[1].name: expected string, got number"},
+		},
+		{
+			name:     "null matches anything",
+			expected: `{"queryInfo":null}`,
+			actual:   `{"queryInfo":{"page":1}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var expected, actual interface{}
+			if err := json.Unmarshal([]byte(tt.expected), &expected); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.actual), &actual); err != nil {
+				t.Fatal(err)
+			}
+			drifts := schemaDrift("", expected, actual)
+			if !reflect.DeepEqual(drifts, tt.drifts) {
+				t.Errorf("schemaDrift() = %q, want %q", drifts, tt.drifts)
+			}
+		})
+	}
+}
+
+func TestSchemaCanaryFixture(t *testing.T) {
+	var expected interface{}
+	if err := json.Unmarshal(schemaCanaryFixture, &expected); err != nil {
+		t.Fatalf("invalid pinned schema: %v", err)
+	}
+}
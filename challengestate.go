@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// challengeStateKey is the ConfigMap key holding the challenge records.
+	challengeStateKey = "challenges.json"
+	// challengeStatePersistInterval is the interval at which the challenge
+	// records are written to their ConfigMap, if they changed.
+	challengeStatePersistInterval = 10 * time.Second
+	// challengeStateMaxAge is the age after which the challenge records
+	// never cleaned up, e.g. of Challenges deleted while the webhook was
+	// down, are dropped when loaded.
+	challengeStateMaxAge = 7 * 24 * time.Hour
+)
+
+// persistedChallenge is a challenge record of the ledger, in the format in
+// which it is persisted.
+type persistedChallenge struct {
+	FQDN      string               `json:"fqdn"`
+	Key       string               `json:"key"`
+	CreatedAt time.Time            `json:"createdAt"`
+	Created   map[string]time.Time `json:"created,omitempty"`
+}
+
+// exportRecords returns the challenge records of the ledger and the number
+// of changes they reflect.
+func (l *ledger) exportRecords() ([]persistedChallenge, uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records := make([]persistedChallenge, 0, len(l.records))
+	for key, entry := range l.records {
+		records = append(records, persistedChallenge{FQDN: key.FQDN, Key: key.Key, CreatedAt: entry.CreatedAt, Created: entry.Created})
+	}
+	return records, l.changes
+}
+
+// loadRecords adds the persisted challenge records younger than
+// challengeStateMaxAge to the ledger, without overriding the ones it has.
+func (l *ledger) loadRecords(records []persistedChallenge, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.records == nil {
+		l.records = make(map[challengeKey]ledgerEntry)
+	}
+	for _, record := range records {
+		key := challengeKey{FQDN: record.FQDN, Key: record.Key}
+		if _, ok := l.records[key]; ok || now.Sub(record.CreatedAt) > challengeStateMaxAge {
+			continue
+		}
+		l.records[key] = ledgerEntry{FQDN: record.FQDN, CreatedAt: record.CreatedAt, Created: record.Created}
+	}
+}
+
+// loadChallengeState loads the challenge records persisted in the
+// "namespace/name" ConfigMap, if any, so that the records presented before a
+// restart are still cleaned up by their IDs.
+func (s *ddDNSProviderSolver) loadChallengeState(configMap string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	data, ok, err := s.readConfigMapKey(ctx, configMap, challengeStateKey)
+	if err != nil || !ok {
+		return err
+	}
+	var records []persistedChallenge
+	if err := json.Unmarshal([]byte(data), &records); err != nil {
+		return err
+	}
+	s.ledger.loadRecords(records, time.Now())
+	return nil
+}
+
+// persistChallengeState writes the challenge records to the
+// "namespace/name" ConfigMap if they changed since the persisted changes,
+// and returns the changes persisted.
+func (s *ddDNSProviderSolver) persistChallengeState(configMap string, persisted uint64) uint64 {
+	records, changes := s.ledger.exportRecords()
+	if changes == persisted {
+		return persisted
+	}
+	data, err := json.Marshal(records)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = s.writeConfigMapKey(ctx, configMap, challengeStateKey, data)
+	}
+	if err != nil {
+		klog.ErrorS(err, "Failed to write the challenge records", "configMap", configMap)
+		return persisted
+	}
+	return changes
+}
+
+// runChallengeStatePersister writes the challenge records to the
+// "namespace/name" ConfigMap every challengeStatePersistInterval in which
+// they changed, and a last time when stopCh is closed.
+func (s *ddDNSProviderSolver) runChallengeStatePersister(configMap string, stopCh <-chan struct{}) {
+	_, persisted := s.ledger.exportRecords()
+	ticker := time.NewTicker(challengeStatePersistInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			persisted = s.persistChallengeState(configMap, persisted)
+		case <-stopCh:
+			s.persistChallengeState(configMap, persisted)
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestChallengeStatePersistence(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	solver := &ddDNSProviderSolver{client: client}
+	key := challengeKey{FQDN: "_acme-challenge.example.com.", Key: "challenge-key"}
+	solver.ledger.presented(key, "42")
+
+	persisted := solver.persistChallengeState("cert-manager/dd-challenges", 0)
+	if persisted == 0 {
+		t.Fatal("the challenge records were not persisted")
+	}
+	if again := solver.persistChallengeState("cert-manager/dd-challenges", persisted); again != persisted {
+		t.Errorf("unchanged records persisted again, changes = %d, want %d", again, persisted)
+	}
+
+	// After a restart, the record is still deleted by its ID
+	restarted := &ddDNSProviderSolver{client: client}
+	if err := restarted.loadChallengeState("cert-manager/dd-challenges"); err != nil {
+		t.Fatal(err)
+	}
+	if created := restarted.ledger.createdRecords(key); len(created) != 1 || created["42"].IsZero() {
+		t.Errorf("created records after the restart = %v, want 42", created)
+	}
+
+	// The records never cleaned up are eventually dropped
+	var dropped ledger
+	dropped.loadRecords([]persistedChallenge{{FQDN: key.FQDN, Key: key.Key, CreatedAt: time.Now().Add(-challengeStateMaxAge - time.Hour)}}, time.Now())
+	if len(dropped.snapshot().Records) != 0 {
+		t.Error("a record older than the maximum age was loaded")
+	}
+
+	if err := (&ddDNSProviderSolver{client: fake.NewSimpleClientset()}).loadChallengeState("cert-manager/missing"); err != nil {
+		t.Errorf("loading a missing ConfigMap failed: %v", err)
+	}
+}
+
+func TestDeleteCreated(t *testing.T) {
+	provider := &fakeProvider{zones: map[string][]TXTRecord{
+		"example.com": {
+			{ID: "1", Name: "_acme-challenge.example.com", Value: "challenge-key"},
+			{ID: "2", Name: "_acme-challenge.example.com", Value: "challenge-key"},
+		},
+	}}
+	ctx := context.Background()
+
+	result, exact, err := deleteCreated(ctx, provider, "example.com", "_acme-challenge.example.com.", map[string]time.Time{"1": time.Now(), "9": time.Now()})
+	if err != nil || !exact || len(result.Deleted) != 1 || result.Deleted[0] != "1" {
+		t.Errorf("deleteCreated() = %+v, %v, %v, want 1 deleted", result, exact, err)
+	}
+	if len(provider.zones["example.com"]) != 1 {
+		t.Errorf("records left = %+v, want the one not created for the challenge", provider.zones["example.com"])
+	}
+
+	for _, created := range []map[string]time.Time{nil, {"1": time.Now()}} {
+		if _, exact, err := deleteCreated(ctx, provider, "example.com", "_acme-challenge.example.com.", created); err != nil || exact {
+			t.Errorf("deleteCreated(%v) = %v, %v, want a fallback to the search", created, exact, err)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/component-base/logs"
+	"k8s.io/klog/v2"
+
+	cmdutil "github.com/cert-manager/cert-manager/cmd/util"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/cmd/server"
+
+	"github.com/baarde/cert-manager-webhook-dd/registry"
+)
+
+// envPrefix is the prefix of the environment variables from which the flags
+// not set on the command line are read, e.g. WEBHOOK_RATE_LIMIT for
+// --rate-limit.
+const envPrefix = "WEBHOOK_"
+
+// selfTestTimeout bounds the DonDominio API call of the self test.
+const selfTestTimeout = 10 * time.Second
+
+// subcommands are the names of the subcommands of the webhook. Any other
+// command line is the one of the serve subcommand, as before the subcommands
+// were introduced.
+var subcommands = []string{"serve", "version", "selftest", "dev", ddctlName, "help", "completion"}
+
+// newRootCommand returns the command of the webhook, with its serve, version,
+// selftest, dev and ddctl subcommands.
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:           "webhook",
+		Short:         "cert-manager ACME DNS01 webhook for DonDominio",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		// Printed by --version, as by the version subcommand
+		Version: currentBuild().String(),
+	}
+	root.SetVersionTemplate("{{.Version}}\n")
+
+	root.AddCommand(&cobra.Command{
+		Use:   "serve",
+		Short: "Serve the solvers to cert-manager",
+		// The flags are parsed by the command of the webhook server, which
+		// adds those of the API server.
+		DisableFlagParsing: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			return runServe(args)
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "version",
+		Short: "Print the version of the webhook",
+		Args:  cobra.NoArgs,
+		Run: func(c *cobra.Command, args []string) {
+			fmt.Fprintln(c.OutOrStdout(), currentBuild())
+		},
+	})
+
+	selftest := &cobra.Command{
+		Use:   "selftest",
+		Short: "Check the flags, the solvers and the ambient DonDominio credentials without serving",
+		Args:  cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return applyEnvDefaults(c.Flags())
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			return runSelfTest(c.Context(), c.OutOrStdout())
+		},
+	}
+	selftest.Flags().AddGoFlagSet(flag.CommandLine)
+	root.AddCommand(selftest)
+
+	root.AddCommand(newDevCommand(), newDdctlCommand())
+
+	return root
+}
+
+// compatArgs returns the arguments of the root command for the command line
+// args, the serve subcommand being implied when args start with a flag or are
+// empty, so that the deployments passing only flags keep working.
+func compatArgs(args []string) []string {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return args
+	}
+	if len(args) == 1 && (args[0] == "-h" || args[0] == "--help" || args[0] == "--version") {
+		return args
+	}
+	return append([]string{"serve"}, args...)
+}
+
+// envName returns the environment variable from which the flag name is read
+// when it is not set on the command line.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// applyEnvDefaults sets the flags of fs not set on the command line from
+// their environment variable, if any.
+func applyEnvDefaults(fs *pflag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed {
+			return
+		}
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid %s: %w", envName(f.Name), setErr)
+		}
+	})
+	return err
+}
+
+// preparsedFlag returns the value of the flag name in args, or else of its
+// environment variable. It is used for the flags needed before the command
+// of the webhook server is created.
+func preparsedFlag(args []string, name string) string {
+	value, found := "", false
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		trimmed := strings.TrimLeft(arg, "-")
+		if len(arg)-len(trimmed) < 1 || len(arg)-len(trimmed) > 2 {
+			continue
+		}
+		if strings.HasPrefix(trimmed, name+"=") {
+			value, found = strings.TrimPrefix(trimmed, name+"="), true
+		} else if trimmed == name && i+1 < len(args) {
+			value, found = args[i+1], true
+		}
+	}
+	if !found {
+		value = os.Getenv(envName(name))
+	}
+	return value
+}
+
+// Files of the serving certificate in --tls-cert-dir, as in a
+// kubernetes.io/tls secret.
+const (
+	tlsCertFile = "tls.crt"
+	tlsKeyFile  = "tls.key"
+)
+
+// applyServeFlags applies the flags of the webhook standing for flags of the
+// webhook server library, or of klog, to fs once parsed.
+func applyServeFlags(fs *pflag.FlagSet) error {
+	if *tlsCertDir != "" {
+		for _, f := range []struct{ name, file string }{
+			{"tls-cert-file", tlsCertFile},
+			{"tls-private-key-file", tlsKeyFile},
+		} {
+			if fs.Changed(f.name) {
+				return fmt.Errorf("--tls-cert-dir cannot be set with --%s", f.name)
+			}
+			if err := fs.Set(f.name, filepath.Join(*tlsCertDir, f.file)); err != nil {
+				return err
+			}
+		}
+	}
+	// Checked by validateFlags
+	if level, _ := parseLogLevel(*logLevel); level >= 0 {
+		return klogFlags.Set("v", strconv.Itoa(int(level)))
+	}
+	return nil
+}
+
+// runServe runs the webhook server with the command line args, like
+// cmd.RunWebhookServer does with the whole command line.
+func runServe(args []string) error {
+	if group := preparsedFlag(args, "group-name"); group != "" {
+		GroupName = group
+	}
+	if GroupName == "" {
+		group, err := discoverGroupName()
+		if err != nil {
+			return fmt.Errorf("--group-name is not set and could not be discovered from the APIService registration: %w", err)
+		}
+		klog.InfoS("Group name discovered from the APIService registration", "groupName", group)
+		GroupName = group
+	}
+	setMaxProcs()
+
+	// This will register the DNS providers of the registry, the DonDominio
+	// one and those of the providers package, with the webhook serving
+	// library, making them available as an API under the provided GroupName.
+	// The Name() method of each solver is used to disambiguate between the
+	// different implementations.
+	solvers, err := registry.Solvers(enabledSolvers(args))
+	if err != nil {
+		return fmt.Errorf("invalid --solvers flag, registered solvers are %s: %w", strings.Join(registry.Names(), ", "), err)
+	}
+	servedSolvers = solverNames(solvers)
+
+	stopCh, exit := cmdutil.SetupExitHandler(cmdutil.GracefulShutdown)
+	defer exit() // This function might call os.Exit, so defer last
+
+	logs.InitLogs()
+	defer logs.FlushLogs()
+
+	serve := server.NewCommandStartWebhookServer(os.Stdout, os.Stderr, stopCh, GroupName, solvers...)
+	serve.Use = "serve"
+	serve.Flags().AddGoFlagSet(flag.CommandLine)
+	serve.PreRunE = func(c *cobra.Command, args []string) error {
+		if err := applyEnvDefaults(c.Flags()); err != nil {
+			return err
+		}
+		if err := validateFlags(); err != nil {
+			return err
+		}
+		return applyServeFlags(c.Flags())
+	}
+	serve.SetArgs(args)
+	if err := serve.Execute(); err != nil {
+		klog.ErrorS(err, "Error executing command")
+		cmdutil.SetExitCode(err)
+	}
+	return nil
+}
+
+// selfTestCheck is a check run by the self test. It returns errSkipped if it
+// does not apply.
+type selfTestCheck struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+var errSkipped = errors.New("skipped")
+
+// selfTestChecks are the checks run by the self test.
+func selfTestChecks() []selfTestCheck {
+	return []selfTestCheck{
+		{
+			name: "solvers",
+			run: func(ctx context.Context) error {
+				_, err := registry.Solvers(solverList(*solversFlag))
+				return err
+			},
+		},
+		{
+			name: "flags",
+			run:  func(ctx context.Context) error { return validateFlags() },
+		},
+		{
+			name: "dondominio",
+			run: func(ctx context.Context) error {
+				ambient := loadAmbientConfig("")
+				if ambient.APIUser == "" || ambient.APIPassword == "" {
+					return fmt.Errorf("%w: no ambient credentials", errSkipped)
+				}
+				if settings := transportFlags(); settings.validate() == nil {
+					settings.tune(sharedTransport)
+				}
+				ddClient, err := NewClient(ambient.Endpoint, ambient.APIUser, ambient.APIPassword)
+				if err != nil {
+					return err
+				}
+				ddClient.UserAgent = currentBuild().userAgent()
+				ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+				defer cancel()
+				return ddClient.PingWithContext(ctx)
+			},
+		},
+	}
+}
+
+// runSelfTest runs the self test checks and writes their results to out. It
+// fails if any check failed.
+func runSelfTest(ctx context.Context, out io.Writer) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	failed := 0
+	for _, check := range selfTestChecks() {
+		err := check.run(ctx)
+		switch {
+		case err == nil:
+			fmt.Fprintf(out, "ok    %s\n", check.name)
+		case errors.Is(err, errSkipped):
+			fmt.Fprintf(out, "skip  %s: %v\n", check.name, err)
+		default:
+			failed++
+			fmt.Fprintf(out, "FAIL  %s: %v\n", check.name, err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d self test checks failed", failed)
+	}
+	return nil
+}
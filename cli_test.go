@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestCompatArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"serve"}},
+		{[]string{"--secure-port=8443", "--tls-cert-file=/tls/tls.crt"}, []string{"serve", "--secure-port=8443", "--tls-cert-file=/tls/tls.crt"}},
+		{[]string{"serve", "--secure-port=8443"}, []string{"serve", "--secure-port=8443"}},
+		{[]string{"version"}, []string{"version"}},
+		{[]string{"--help"}, []string{"--help"}},
+		{[]string{"--version"}, []string{"--version"}},
+	}
+	for _, tt := range tests {
+		if got := compatArgs(tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("compatArgs(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestApplyEnvDefaults(t *testing.T) {
+	goFlags := flag.NewFlagSet("test", flag.ContinueOnError)
+	rate := goFlags.String("rate-limit", "", "")
+	burst := goFlags.Int("rate-limit-burst", 1, "")
+	timeout := goFlags.String("request-timeout", "2m", "")
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.AddGoFlagSet(goFlags)
+
+	t.Setenv("WEBHOOK_RATE_LIMIT", "10/s")
+	t.Setenv("WEBHOOK_RATE_LIMIT_BURST", "5")
+	if err := fs.Parse([]string{"--rate-limit-burst=3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyEnvDefaults(fs); err != nil {
+		t.Fatal(err)
+	}
+	if *rate != "10/s" {
+		t.Errorf("--rate-limit = %q, want the value of the environment", *rate)
+	}
+	if *burst != 3 {
+		t.Errorf("--rate-limit-burst = %d, want the value of the command line", *burst)
+	}
+	if *timeout != "2m" {
+		t.Errorf("--request-timeout = %q, want the default", *timeout)
+	}
+
+	t.Setenv("WEBHOOK_REQUEST_TIMEOUT", "")
+	fs.Lookup("rate-limit-burst").Changed = false
+	t.Setenv("WEBHOOK_RATE_LIMIT_BURST", "many")
+	if err := applyEnvDefaults(fs); err == nil || !strings.Contains(err.Error(), "WEBHOOK_RATE_LIMIT_BURST") {
+		t.Errorf("applyEnvDefaults() = %v, want an error naming the variable", err)
+	}
+}
+
+func TestPreparsedFlag(t *testing.T) {
+	t.Setenv("WEBHOOK_GROUP_NAME", "acme.env.example.com")
+	if got := preparsedFlag([]string{"--group-name=acme.example.com"}, "group-name"); got != "acme.example.com" {
+		t.Errorf("preparsedFlag() = %q, want the value of the command line", got)
+	}
+	if got := preparsedFlag([]string{"--secure-port", "8443"}, "group-name"); got != "acme.env.example.com" {
+		t.Errorf("preparsedFlag() = %q, want the value of the environment", got)
+	}
+}
+
+func TestVersionCommand(t *testing.T) {
+	var out bytes.Buffer
+	root := newRootCommand()
+	root.SetOut(&out)
+	root.SetArgs([]string{"version"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "cert-manager-webhook-dd "+version+" ") {
+		t.Errorf("version printed %q", out.String())
+	}
+}
+
+func TestVersionFlag(t *testing.T) {
+	var out bytes.Buffer
+	root := newRootCommand()
+	root.SetOut(&out)
+	root.SetArgs(compatArgs([]string{"--version"}))
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if want := currentBuild().String() + "\n"; out.String() != want {
+		t.Errorf("--version printed %q, want %q", out.String(), want)
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for level, want := range map[string]int{"": -1, "info": 0, "Debug": logDebug, "trace": 6, "2": 2} {
+		if got, err := parseLogLevel(level); err != nil || int(got) != want {
+			t.Errorf("parseLogLevel(%q) = %v, %v, want %d", level, got, err, want)
+		}
+	}
+	for _, level := range []string{"verbose", "-1"} {
+		if _, err := parseLogLevel(level); err == nil {
+			t.Errorf("parseLogLevel(%q) succeeded", level)
+		}
+	}
+}
+
+func TestApplyServeFlags(t *testing.T) {
+	defer func(dir string) { *tlsCertDir = dir }(*tlsCertDir)
+	*tlsCertDir = "/tls"
+	newFlagSet := func() *pflag.FlagSet {
+		fs := pflag.NewFlagSet("serve", pflag.ContinueOnError)
+		fs.String("tls-cert-file", "", "")
+		fs.String("tls-private-key-file", "", "")
+		return fs
+	}
+
+	fs := newFlagSet()
+	if err := applyServeFlags(fs); err != nil {
+		t.Fatal(err)
+	}
+	if cert, _ := fs.GetString("tls-cert-file"); cert != "/tls/tls.crt" {
+		t.Errorf("--tls-cert-file = %q", cert)
+	}
+	if key, _ := fs.GetString("tls-private-key-file"); key != "/tls/tls.key" {
+		t.Errorf("--tls-private-key-file = %q", key)
+	}
+
+	fs = newFlagSet()
+	fs.Set("tls-cert-file", "/other/tls.crt")
+	if err := applyServeFlags(fs); err == nil {
+		t.Error("applyServeFlags() succeeded with both --tls-cert-dir and --tls-cert-file")
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/sha256"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// clientCacheTTL is the time after which a cached client is replaced, so
+// that changes to the configuration files and environment variables it was
+// loaded from are eventually picked up.
+const clientCacheTTL = 10 * time.Minute
+
+// clientCacheKey identifies the clients sharing the same endpoint,
+// credentials, authentication scheme, retry policy and extra parameters. The secret is only kept
+// hashed.
+type clientCacheKey struct {
+	endpoint    string
+	appKey      string
+	secretHash  [sha256.Size]byte
+	authScheme  string
+	retryPolicy RetryPolicy
+	params      string
+}
+
+type cachedClient struct {
+	client    *Client
+	createdAt time.Time
+}
+
+// clientCache reuses the DonDominio clients across the Present and CleanUp
+// calls of the same issuer, which saves loading their configuration and the
+// time offset of the API for every challenge. Its zero value is ready to use.
+type clientCache struct {
+	mu      sync.Mutex
+	clients map[clientCacheKey]cachedClient
+}
+
+// get returns the cached client for the endpoint, credentials,
+// authentication scheme, retry policy and extra parameters, calling newClient
+// to create it if there is none or it has expired.
+func (c *clientCache) get(endpoint, appKey, appSecret, authScheme string, policy RetryPolicy, params url.Values, newClient func() (*Client, error)) (*Client, error) {
+	key := clientCacheKey{
+		endpoint:    endpoint,
+		appKey:      appKey,
+		secretHash:  sha256.Sum256([]byte(appSecret)),
+		authScheme:  authScheme,
+		retryPolicy: policy,
+		params:      params.Encode(),
+	}
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, cached := range c.clients {
+		if now.Sub(cached.createdAt) >= clientCacheTTL || cached.client.replayed.Load() {
+			delete(c.clients, k)
+		}
+	}
+	if cached, ok := c.clients[key]; ok {
+		return cached.client, nil
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+	if c.clients == nil {
+		c.clients = make(map[clientCacheKey]cachedClient)
+	}
+	c.clients[key] = cachedClient{client: client, createdAt: now}
+	return client, nil
+}
+
+// purge drops all the cached clients.
+func (c *clientCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clients = nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestClientCache(t *testing.T) {
+	var cache clientCache
+	created := 0
+	newClient := func() (*Client, error) {
+		created++
+		return &Client{}, nil
+	}
+
+	first, _ := cache.get("https://simple-api.dondominio.net", "apiuser", "apipasswd", "", DefaultRetryPolicy, nil, newClient)
+	again, _ := cache.get("https://simple-api.dondominio.net", "apiuser", "apipasswd", "", DefaultRetryPolicy, nil, newClient)
+	if again != first || created != 1 {
+		t.Errorf("the client was not reused, %d clients created", created)
+	}
+
+	rotated, _ := cache.get("https://simple-api.dondominio.net", "apiuser", "rotated", "", DefaultRetryPolicy, nil, newClient)
+	if rotated == first || created != 2 {
+		t.Errorf("the client was reused with another secret, %d clients created", created)
+	}
+
+	patient := DefaultRetryPolicy
+	patient.MaxAttempts = 10
+	if retried, _ := cache.get("https://simple-api.dondominio.net", "apiuser", "rotated", "", patient, nil, newClient); retried == rotated || created != 3 {
+		t.Errorf("the client was reused with another retry policy, %d clients created", created)
+	}
+
+	subUser := url.Values{"subuser": {"customer"}}
+	if impersonating, _ := cache.get("https://simple-api.dondominio.net", "apiuser", "rotated", "", DefaultRetryPolicy, subUser, newClient); impersonating == rotated || created != 4 {
+		t.Errorf("the client was reused with other parameters, %d clients created", created)
+	}
+
+	failing := func() (*Client, error) { return nil, errors.New("invalid configuration") }
+	if _, err := cache.get("https://other.example.com", "apiuser", "apipasswd", "", DefaultRetryPolicy, nil, failing); err == nil {
+		t.Error("get() did not return the error of newClient")
+	}
+	if _, err := cache.get("https://other.example.com", "apiuser", "apipasswd", "", DefaultRetryPolicy, nil, newClient); err != nil || created != 5 {
+		t.Errorf("a failed creation was cached, %d clients created", created)
+	}
+
+	for key, cached := range cache.clients {
+		cached.createdAt = cached.createdAt.Add(-clientCacheTTL)
+		cache.clients[key] = cached
+	}
+	if renewed, _ := cache.get("https://simple-api.dondominio.net", "apiuser", "apipasswd", "", DefaultRetryPolicy, nil, newClient); renewed == first {
+		t.Error("an expired client was reused")
+	}
+	if len(cache.clients) != 1 {
+		t.Errorf("%d clients cached, want only the renewed one", len(cache.clients))
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var apiClockSkew = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: metricsNamespace,
+	Name:      "api_clock_skew_seconds",
+	Help:      "Offset of the local clock from the one of the DonDominio API, positive when the local clock is ahead, by endpoint.",
+}, []string{"endpoint"})
+
+func init() {
+	metricsRegistry.MustRegister(apiClockSkew)
+}
+
+// DefaultTimeDeltaTTL is the default age after which the offset of the clock
+// of the API is measured again.
+const DefaultTimeDeltaTTL = 30 * time.Minute
+
+// timeSample is an offset of the clock of the API and when it was measured.
+type timeSample struct {
+	delta time.Duration
+	at    time.Time
+}
+
+// getTimeDelta returns the offset of the clock of the API, measured again
+// with /auth/time if the last one is older than TimeDeltaTTL.
+func (c *Client) getTimeDelta(ctx context.Context) (time.Duration, error) {
+	sample, ok := c.timeDelta.Load().(timeSample)
+	if ok && (c.TimeDeltaTTL == 0 || time.Since(sample.at) < c.TimeDeltaTTL) {
+		return sample.delta, nil
+	}
+
+	var timestamp int64
+	start := time.Now()
+	if err := c.GetWithContext(ctx, "/auth/time", &timestamp); err != nil {
+		return 0, err
+	}
+	delta := offsetFrom(time.Unix(timestamp, 0), start, time.Now())
+	c.storeTimeDelta(delta)
+	return delta, nil
+}
+
+// observeServerDate refreshes the offset of the clock of the API from the
+// Date header of a response to a request sent at start and answered at end,
+// so that it follows the drift of the clocks over long uptimes without extra
+// calls. The responses without a valid Date are ignored.
+func (c *Client) observeServerDate(date string, start, end time.Time) {
+	if date == "" {
+		return
+	}
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return
+	}
+	c.storeTimeDelta(offsetFrom(serverTime, start, end))
+}
+
+// offsetFrom returns the offset of the local clock from a server time with a
+// second precision, read by a request sent at start and answered at end.
+// The server time is compared to the middle of the request, rounded down to
+// the second like the server time, so that a clock in sync has no offset.
+func offsetFrom(serverTime, start, end time.Time) time.Duration {
+	middle := start.Add(end.Sub(start) / 2).Truncate(time.Second)
+	return middle.Sub(serverTime)
+}
+
+func (c *Client) storeTimeDelta(delta time.Duration) {
+	c.timeDelta.Store(timeSample{delta: delta, at: time.Now()})
+	apiClockSkew.WithLabelValues(c.endpoint).Set(delta.Seconds())
+}
+
+// serverNow returns the current time of the API if its offset has been
+// measured, and else the local time, without calling the API.
+func (c *Client) serverNow() time.Time {
+	if sample, ok := c.timeDelta.Load().(timeSample); ok {
+		return time.Now().Add(-sample.delta)
+	}
+	return time.Now()
+}
+
+// ServerTime returns the current time of the API, i.e. the local time
+// corrected by the offset of its clock, for the operations that must be
+// timestamped with the clock of the API.
+func (c *Client) ServerTime(ctx context.Context) (time.Time, error) {
+	delta, err := c.getTimeDelta(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(-delta), nil
+}
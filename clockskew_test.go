@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTimeDelta(t *testing.T) {
+	// The clock of the API is an hour behind
+	var skew, calls int64 = int64(time.Hour), 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverTime := time.Now().Add(-time.Duration(atomic.LoadInt64(&skew)))
+		w.Header().Set("Date", serverTime.UTC().Format(http.TimeFormat))
+		if r.URL.Path == "/auth/time" {
+			atomic.AddInt64(&calls, 1)
+			json.NewEncoder(w).Encode(serverTime.Unix())
+			return
+		}
+		w.Write([]byte(`{"success": true, "responseData": {"dns": []}}`))
+	}))
+	defer server.Close()
+
+	ddClient, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	delta, err := ddClient.TimeDelta()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delta < time.Hour-time.Second || delta > time.Hour+time.Second {
+		t.Errorf("TimeDelta() = %v, want 1h", delta)
+	}
+
+	// The responses refresh the delta, which follows the drift of the clocks
+	atomic.StoreInt64(&skew, int64(2*time.Hour))
+	ddClient.TimeDeltaTTL = time.Nanosecond
+	if _, err := ddClient.ListTXT(context.Background(), "example.com", "_acme-challenge.example.com", ""); err != nil {
+		t.Fatal(err)
+	}
+	ddClient.TimeDeltaTTL = time.Hour
+	serverTime, err := ddClient.ServerTime(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset := time.Since(serverTime); offset < 2*time.Hour-time.Second || offset > 2*time.Hour+time.Second {
+		t.Errorf("ServerTime() is %v behind, want 2h", offset)
+	}
+	if calls := atomic.LoadInt64(&calls); calls != 1 {
+		t.Errorf("/auth/time called %d times, want once", calls)
+	}
+}
+
+func TestOffsetFrom(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 900*int(time.Millisecond), time.UTC)
+	end := start.Add(200 * time.Millisecond)
+	if got := offsetFrom(time.Date(2024, 1, 1, 12, 0, 1, 0, time.UTC), start, end); got != 0 {
+		t.Errorf("offsetFrom() of a clock in sync = %v, want 0", got)
+	}
+	if got := offsetFrom(time.Date(2024, 1, 1, 11, 59, 31, 0, time.UTC), start, end); got != 30*time.Second {
+		t.Errorf("offsetFrom() = %v, want 30s", got)
+	}
+}
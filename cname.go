@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+	"github.com/miekg/dns"
+	"k8s.io/klog/v2"
+)
+
+// followCNAMEs follows the chain of CNAME records starting at fqdn and
+// returns the last name of the chain, which is the one the TXT record must be
+// created for. It fails if the chain contains a loop.
+func followCNAMEs(fqdn string, nameservers []string) (string, error) {
+	seen := map[string]bool{}
+	for {
+		if seen[fqdn] {
+			return "", fmt.Errorf("CNAME loop on %s", fqdn)
+		}
+		seen[fqdn] = true
+
+		r, err := util.DNSQuery(fqdn, dns.TypeCNAME, nameservers, true)
+		if err != nil {
+			return "", fmt.Errorf("failed to look up the CNAME of %s: %w", fqdn, err)
+		}
+		if r.Rcode != dns.RcodeSuccess {
+			return fqdn, nil
+		}
+
+		target := ""
+		for _, rr := range r.Answer {
+			if cn, ok := rr.(*dns.CNAME); ok && cn.Hdr.Name == fqdn {
+				target = cn.Target
+				break
+			}
+		}
+		if target == "" {
+			return fqdn, nil
+		}
+		klog.V(logDebug).InfoS("Following CNAME", "name", fqdn, "target", target)
+		fqdn = target
+	}
+}
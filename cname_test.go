@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+	"github.com/miekg/dns"
+)
+
+// serveCNAMEs starts a DNS server answering CNAME queries from the given
+// records and returns its address.
+func serveCNAMEs(t *testing.T, records map[string]string) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &dns.Server{
+		PacketConn: conn,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetReply(req)
+			q := req.Question[0]
+			if target, ok := records[q.Name]; ok && q.Qtype == dns.TypeCNAME {
+				m.Answer = append(m.Answer, &dns.CNAME{
+					Hdr:    dns.RR_Header{Name: q.Name, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 60},
+					Target: target,
+				})
+			}
+			w.WriteMsg(m)
+		}),
+	}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+	return conn.LocalAddr().String()
+}
+
+func TestFollowCNAMEs(t *testing.T) {
+	ns := serveCNAMEs(t, map[string]string{
+		"_acme-challenge.example.com.":      "_acme-challenge.example.net.",
+		"_acme-challenge.example.net.":      "_acme-challenge.zone.example.org.",
+		"_acme-challenge.loop.example.com.": "_acme-challenge.loop.example.net.",
+		"_acme-challenge.loop.example.net.": "_acme-challenge.loop.example.com.",
+	})
+
+	tests := []struct {
+		fqdn    string
+		want    string
+		wantErr bool
+	}{
+		{fqdn: "_acme-challenge.example.com.", want: "_acme-challenge.zone.example.org."},
+		{fqdn: "_acme-challenge.www.example.com.", want: "_acme-challenge.www.example.com."},
+		{fqdn: "_acme-challenge.loop.example.com.", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := followCNAMEs(tt.fqdn, []string{ns})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("followCNAMEs(%q) error = %v, wantErr %v", tt.fqdn, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("followCNAMEs(%q) = %q, want %q", tt.fqdn, got, tt.want)
+		}
+	}
+}
+
+func TestChallengeFQDNFollowsMixedCaseCNAMEs(t *testing.T) {
+	ns := serveCNAMEs(t, map[string]string{
+		"_acme-challenge.www.example.com.": "_ACME-Challenge.Zone.Example.ORG.",
+	})
+	defer func(nameservers []string) { util.RecursiveNameservers = nameservers }(util.RecursiveNameservers)
+	util.RecursiveNameservers = []string{ns}
+
+	cfg := &ddDNSProviderConfig{FollowCNAME: true}
+	got, err := cfg.challengeFQDN(&v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.WWW.Example.com."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "_acme-challenge.zone.example.org."; got != want {
+		t.Errorf("challengeFQDN() = %q, want %q", got, want)
+	}
+}
+
+func TestChallengeFQDNApexCNAMEs(t *testing.T) {
+	ns := serveCNAMEs(t, map[string]string{
+		"_acme-challenge.example.com.":     "example-challenges.net.",
+		"_acme-challenge.www.example.com.": "www.example-challenges.net.",
+	})
+	defer func(nameservers []string) { util.RecursiveNameservers = nameservers }(util.RecursiveNameservers)
+	util.RecursiveNameservers = []string{ns}
+
+	tests := []struct {
+		resolvedFQDN string
+		fqdn         string
+		record       string
+	}{
+		// The target is the apex of a zone dedicated to the challenges
+		{"_acme-challenge.example.com.", "example-challenges.net.", "example-challenges.net"},
+		// The bare apex requested stands for its challenge record
+		{"example.com.", "example-challenges.net.", "example-challenges.net"},
+		{"_acme-challenge.www.example.com.", "www.example-challenges.net.", "www.example-challenges.net"},
+	}
+	cfg := &ddDNSProviderConfig{FollowCNAME: true}
+	for _, tt := range tests {
+		fqdn, err := cfg.challengeFQDN(&v1alpha1.ChallengeRequest{ResolvedFQDN: tt.resolvedFQDN})
+		if err != nil {
+			t.Errorf("challengeFQDN(%q) error: %v", tt.resolvedFQDN, err)
+			continue
+		}
+		if fqdn != tt.fqdn {
+			t.Errorf("challengeFQDN(%q) = %q, want %q", tt.resolvedFQDN, fqdn, tt.fqdn)
+		}
+		zone := getDomain(fqdn)
+		if record := recordName(zone, getSubDomain(zone, fqdn)); record != tt.record {
+			t.Errorf("record of %q = %q, want %q", tt.resolvedFQDN, record, tt.record)
+		}
+	}
+}
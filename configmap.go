@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// splitConfigMap splits a "namespace/name" ConfigMap reference.
+func splitConfigMap(configMap string) (namespace, name string, err error) {
+	namespace, name, ok := strings.Cut(configMap, "/")
+	if !ok || namespace == "" || name == "" {
+		return "", "", fmt.Errorf("expected namespace/name, got %q", configMap)
+	}
+	return namespace, name, nil
+}
+
+// readConfigMapKey returns the value of key in the "namespace/name"
+// ConfigMap. The second return value is false if the ConfigMap or the key
+// does not exist.
+func (s *ddDNSProviderSolver) readConfigMapKey(ctx context.Context, configMap, key string) (string, bool, error) {
+	namespace, name, err := splitConfigMap(configMap)
+	if err != nil {
+		return "", false, err
+	}
+
+	cm, err := s.client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := cm.Data[key]
+	return value, ok, nil
+}
+
+// writeConfigMapKey sets key to data in the "namespace/name" ConfigMap,
+// creating the ConfigMap if it does not exist.
+func (s *ddDNSProviderSolver) writeConfigMapKey(ctx context.Context, configMap, key string, data []byte) error {
+	namespace, name, err := splitConfigMap(configMap)
+	if err != nil {
+		return err
+	}
+
+	configMaps := s.client.CoreV1().ConfigMaps(namespace)
+	cm, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		cm.Data = map[string]string{key: string(data)}
+		_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[key] = string(data)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
@@ -6,6 +6,8 @@ import (
 	"os/user"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/ini.v1"
 )
@@ -43,20 +45,36 @@ func appendConfigurationFile(cfg *ini.File, path string) {
 	}
 }
 
-// loadConfig loads client configuration from params, environments or configuration
-// files (by order of decreasing precedence).
+// ambientConfig is the configuration found in the environment and the
+// configuration files, used by the issuers allowed ambient credentials and by
+// the clients not tied to an issuer.
+type ambientConfig struct {
+	Endpoint    string
+	APIUser     string
+	APIPassword string
+}
+
+// loadAmbientConfig loads the configuration from the environment and the
+// configuration files (by order of decreasing precedence).
 //
-// loadConfig will check DD_CONSUMER_KEY, DD_APPLICATION_KEY, DD_APPLICATION_SECRET
-// and DD_ENDPOINT environment variables. If any is present, it will take precedence
-// over any configuration from file.
+// loadAmbientConfig will check the DD_API_USER, DD_API_PASSWORD and
+// DD_ENDPOINT environment variables, and the older DD_APPLICATION_KEY and
+// DD_APPLICATION_SECRET. If any is present, it will take precedence over any
+// configuration from file.
 //
 // Configuration files are ini files. If any wrapper is configured, all
-// can re-use the same configuration. loadConfig will check for configuration in:
+// can re-use the same configuration. loadAmbientConfig will check for
+// configuration in:
 //
+// - the file set by --ambient-credentials-file, e.g. a mounted secret
 // - ./dondominio.conf
 // - $HOME/.dondominio.conf
 // - /etc/dondominio.conf
-func (c *Client) loadConfig(endpointName string) error {
+//
+// The credentials are read from the section named after the endpoint, then
+// from the default section, with the api_user and api_password keys or the
+// older application_key and application_secret.
+func loadAmbientConfig(endpointName string) ambientConfig {
 	// Load configuration files by order of increasing priority. All configuration
 	// files are optional. Only load file from user home if home could be resolve
 	cfg := ini.Empty()
@@ -66,25 +84,89 @@ func (c *Client) loadConfig(endpointName string) error {
 		appendConfigurationFile(cfg, userConfigFullPath)
 	}
 	appendConfigurationFile(cfg, localConfigPath)
+	if *ambientCredentialsFile != "" {
+		appendConfigurationFile(cfg, *ambientCredentialsFile)
+	}
 
 	// Canonicalize configuration
 	if endpointName == "" {
 		endpointName = getConfigValue(cfg, "default", "endpoint", "")
 	}
+	sections := []string{endpointName, "default"}
 
-	if c.AppKey == "" {
-		c.AppKey = getConfigValue(cfg, endpointName, "application_key", "")
-	}
-
-	if c.AppSecret == "" {
-		c.AppSecret = getConfigValue(cfg, endpointName, "application_secret", "")
-	}
+	var ambient ambientConfig
+	ambient.APIUser = getConfigValues(cfg, sections, "api_user", "application_key")
+	ambient.APIPassword = getConfigValues(cfg, sections, "api_password", "application_secret")
 
 	// Load real endpoint URL by name. If endpoint contains a '/', consider it as a URL
 	if strings.Contains(endpointName, "/") {
-		c.endpoint = endpointName
+		ambient.Endpoint = endpointName
 	} else {
-		c.endpoint = Endpoint
+		ambient.Endpoint = Endpoint
+	}
+	return ambient
+}
+
+// ambientConfigTTL is the time for which the ambient configuration is cached
+// when the credentials file is not watched.
+const ambientConfigTTL = time.Minute
+
+// ambientConfigs caches loadAmbientConfig, so that the challenges of the
+// issuers allowed ambient credentials do not read the configuration files
+// every time.
+var ambientConfigs ambientConfigCache
+
+// ambientConfigCache caches the ambient configuration by endpoint name. It
+// is cleared when the watched credentials file changes, and expires after
+// ambientConfigTTL otherwise. Its zero value is ready to use.
+type ambientConfigCache struct {
+	mu       sync.Mutex
+	watched  bool
+	loadedAt time.Time
+	configs  map[string]ambientConfig
+}
+
+// get returns the ambient configuration of endpointName.
+func (c *ambientConfigCache) get(endpointName string) ambientConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.watched && time.Since(c.loadedAt) >= ambientConfigTTL {
+		c.configs = nil
+	}
+	if ambient, ok := c.configs[endpointName]; ok {
+		return ambient
+	}
+	if c.configs == nil {
+		c.configs = make(map[string]ambientConfig)
+		c.loadedAt = time.Now()
+	}
+	ambient := loadAmbientConfig(endpointName)
+	c.configs[endpointName] = ambient
+	return ambient
+}
+
+// clear drops the cached configurations. Once it has been called with
+// watched set, the configurations no longer expire but are only reloaded on
+// the next clear.
+func (c *ambientConfigCache) clear(watched bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.watched = watched
+	c.configs = nil
+}
+
+// loadConfig completes the client configuration from the environment and the
+// configuration files, see loadAmbientConfig. The values already set take
+// precedence.
+func (c *Client) loadConfig(endpointName string) error {
+	ambient := loadAmbientConfig(endpointName)
+	c.endpoint = ambient.Endpoint
+	if c.AppKey == "" {
+		c.AppKey = ambient.APIUser
+	}
+	if c.AppSecret == "" {
+		c.AppSecret = ambient.APIPassword
 	}
 
 	// If we still have no valid endpoint, AppKey or AppSecret, return an error
@@ -101,6 +183,19 @@ func (c *Client) loadConfig(endpointName string) error {
 	return nil
 }
 
+// getConfigValues returns the first value found of the names, each read with
+// getConfigValue from the sections in order, or "".
+func getConfigValues(cfg *ini.File, sections []string, names ...string) string {
+	for _, name := range names {
+		for _, section := range sections {
+			if value := getConfigValue(cfg, section, name, ""); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
 // getConfigValue returns the value of DD_<NAME> or “name“ value from “section“. If
 // the value could not be read from either env or any configuration files, return 'def'
 func getConfigValue(cfg *ini.File, section, name, def string) string {
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// isolateAmbientConfig makes loadAmbientConfig ignore the configuration files
+// of the host, sets the file of --ambient-credentials-file to content and
+// clears ambientConfigs.
+func isolateAmbientConfig(t *testing.T, content string) {
+	dir := t.TempDir()
+	for _, path := range []*string{&systemConfigPath, &userConfigPath, &localConfigPath} {
+		saved := *path
+		*path = filepath.Join(dir, "missing.conf")
+		t.Cleanup(func() { *path = saved })
+	}
+	for _, name := range []string{"DD_API_USER", "DD_API_PASSWORD", "DD_ENDPOINT", "DD_APPLICATION_KEY", "DD_APPLICATION_SECRET"} {
+		t.Setenv(name, "")
+	}
+
+	file := filepath.Join(dir, "dondominio.conf")
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	saved := *ambientCredentialsFile
+	*ambientCredentialsFile = file
+	ambientConfigs.clear(false)
+	t.Cleanup(func() {
+		*ambientCredentialsFile = saved
+		ambientConfigs.clear(false)
+	})
+}
+
+func TestLoadAmbientConfig(t *testing.T) {
+	isolateAmbientConfig(t, `
+[default]
+endpoint = http://file.dondominio.invalid
+api_user = fileuser
+api_password = filepasswd
+`)
+
+	ambient := loadAmbientConfig("")
+	want := ambientConfig{Endpoint: "http://file.dondominio.invalid", APIUser: "fileuser", APIPassword: "filepasswd"}
+	if ambient != want {
+		t.Errorf("loadAmbientConfig() = %+v, want %+v", ambient, want)
+	}
+
+	t.Setenv("DD_API_USER", "envuser")
+	t.Setenv("DD_ENDPOINT", "http://env.dondominio.invalid")
+	ambient = loadAmbientConfig("")
+	want = ambientConfig{Endpoint: "http://env.dondominio.invalid", APIUser: "envuser", APIPassword: "filepasswd"}
+	if ambient != want {
+		t.Errorf("loadAmbientConfig() = %+v, want %+v", ambient, want)
+	}
+}
+
+func TestAmbientCredentials(t *testing.T) {
+	isolateAmbientConfig(t, "")
+	t.Setenv("DD_API_USER", "envuser")
+	t.Setenv("DD_API_PASSWORD", "envpasswd")
+
+	solver := &ddDNSProviderSolver{}
+	cfg := &ddDNSProviderConfig{Endpoint: "http://dondominio.invalid"}
+	if err := solver.validate(cfg, true); err != nil {
+		t.Fatalf("validate() = %v", err)
+	}
+	cfg.allowAmbientCredentials = true
+	ddClient, err := solver.ddClient(context.Background(), cfg, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ddClient.AppKey != "envuser" || ddClient.AppSecret != "envpasswd" || ddClient.endpoint != "http://dondominio.invalid" {
+		t.Errorf("client = %q/%q at %q, want the ambient credentials", ddClient.AppKey, ddClient.AppSecret, ddClient.endpoint)
+	}
+
+	// Credentials left empty are never read from the environment without
+	// ambient credentials.
+	cfg.allowAmbientCredentials = false
+	if _, err := solver.ddClient(context.Background(), cfg, "default"); err == nil {
+		t.Error("ddClient() used the ambient credentials of the webhook")
+	}
+}
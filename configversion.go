@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// The apiVersion and kind of the current version of the issuer config. The
+// configs without apiVersion are in the legacy unversioned shape and are
+// converted to it when they are loaded, so that the existing issuers keep
+// working when the config evolves.
+const (
+	configAPIVersion = "dondominio.acme/" + configSchemaVersion
+	configKind       = "DonDominioConfig"
+)
+
+// legacyConfigFields are the fields of the legacy shape that the current
+// version no longer has. They were ignored, and are dropped on conversion
+// rather than rejected as unknown fields.
+var legacyConfigFields = []string{
+	// Inherited from the OVH webhook, DonDominio has no consumer key
+	"consumerKey",
+}
+
+// convertConfig returns the raw issuer config in the shape of the current
+// version, converting it if it is a legacy config.
+func convertConfig(raw []byte) ([]byte, error) {
+	var typeMeta struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+	}
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, err
+	}
+	switch typeMeta.APIVersion {
+	case "":
+		return convertLegacyConfig(raw)
+	case configAPIVersion:
+		if typeMeta.Kind != configKind {
+			return nil, fmt.Errorf("unsupported kind %q, must be %s", typeMeta.Kind, configKind)
+		}
+		return raw, nil
+	default:
+		return nil, fmt.Errorf("unsupported apiVersion %q, must be %s or none for the legacy config", typeMeta.APIVersion, configAPIVersion)
+	}
+}
+
+// convertLegacyConfig converts a legacy unversioned config to the current
+// version.
+func convertLegacyConfig(raw []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		// null
+		fields = make(map[string]json.RawMessage)
+	}
+	if _, ok := fields["kind"]; ok {
+		return nil, fmt.Errorf("kind without apiVersion, must be %s", configAPIVersion)
+	}
+	for _, name := range legacyConfigFields {
+		delete(fields, name)
+	}
+	fields["apiVersion"], _ = json.Marshal(configAPIVersion)
+	fields["kind"], _ = json.Marshal(configKind)
+	return json.Marshal(fields)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestLoadConfigVersions(t *testing.T) {
+	legacy := `{"endpoint": "https://simple-api.dondominio.net", "applicationKey": "apiuser", "consumerKey": "ignored"}`
+	v1 := `{"apiVersion": "dondominio.acme/v1", "kind": "DonDominioConfig", "endpoint": "https://simple-api.dondominio.net", "applicationKey": "apiuser"}`
+	for name, raw := range map[string]string{"legacy": legacy, "v1": v1} {
+		cfg, unknownFields, err := loadConfig(&extapi.JSON{Raw: []byte(raw)})
+		if err != nil {
+			t.Fatalf("%s: loadConfig() = %v", name, err)
+		}
+		if len(unknownFields) != 0 {
+			t.Errorf("%s: unknown fields %v", name, unknownFields)
+		}
+		if cfg.APIVersion != configAPIVersion || cfg.Kind != configKind || cfg.ApplicationKey != "apiuser" {
+			t.Errorf("%s: loadConfig() = %+v", name, cfg)
+		}
+	}
+
+	// consumerKey only belongs to the legacy shape
+	_, unknownFields, err := loadConfig(&extapi.JSON{Raw: []byte(strings.Replace(v1, `"applicationKey"`, `"consumerKey": "x", "applicationKey"`, 1))})
+	if err != nil || len(unknownFields) != 1 || unknownFields[0].Field != "consumerKey" {
+		t.Errorf("loadConfig() = %v, %v, want consumerKey unknown", unknownFields, err)
+	}
+}
+
+func TestLoadConfigUnsupportedVersion(t *testing.T) {
+	for _, raw := range []string{
+		`{"apiVersion": "dondominio.acme/v2", "kind": "DonDominioConfig"}`,
+		`{"apiVersion": "dondominio.acme/v1", "kind": "OVHConfig"}`,
+		`{"apiVersion": "dondominio.acme/v1"}`,
+		`{"kind": "DonDominioConfig"}`,
+	} {
+		if _, _, err := loadConfig(&extapi.JSON{Raw: []byte(raw)}); err == nil {
+			t.Errorf("loadConfig(%s) accepted", raw)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddTXTRecordConflictPolicy(t *testing.T) {
+	const name = "_acme-challenge.example.com"
+	stale := TXTRecord{ID: "1", Name: name, Value: "stale-key"}
+	pending := TXTRecord{ID: "2", Name: name, Value: "pending-key"}
+	other := TXTRecord{ID: "3", Name: "_acme-challenge.www.example.com", Value: "other-key"}
+	created := TXTRecord{ID: "11", Name: name, Value: "challenge-key"}
+
+	tests := []struct {
+		policy  string
+		records []TXTRecord
+		err     string
+		want    []TXTRecord
+	}{
+		{"", []TXTRecord{stale, pending, other}, "", []TXTRecord{stale, pending, other, created}},
+		{conflictPolicyAppend, []TXTRecord{stale, pending, other}, "", []TXTRecord{stale, pending, other, created}},
+		{conflictPolicyReplace, []TXTRecord{stale, pending, other}, "", []TXTRecord{pending, other, created}},
+		{conflictPolicyUpdate, []TXTRecord{stale, pending, other}, "", []TXTRecord{{ID: "1", Name: name, Value: "challenge-key"}, pending, other}},
+		{conflictPolicyFail, []TXTRecord{stale, pending, other}, "already exists with a different value", []TXTRecord{stale, pending, other}},
+		// The pending values of concurrent challenges never conflict
+		{conflictPolicyFail, []TXTRecord{pending, other}, "", []TXTRecord{pending, other, created}},
+	}
+	for _, tt := range tests {
+		provider := &fakeProvider{zones: map[string][]TXTRecord{"example.com": append([]TXTRecord(nil), tt.records...)}, nextID: 10}
+		_, err := addTXTRecord(context.Background(), provider, "example.com", "_acme-challenge", "challenge-key", 0, tt.policy,
+			map[string]bool{"pending-key": true})
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.policy, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%q: error = %v, want %q", tt.policy, err, tt.err)
+		}
+		if got := provider.zones["example.com"]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: records = %+v, want %+v", tt.policy, got, tt.want)
+		}
+	}
+}
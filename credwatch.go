@@ -0,0 +1,70 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/klog/v2"
+)
+
+// watchCredentialsFile calls onChange whenever the file at path changes,
+// until stopCh is closed.
+//
+// Secret volumes are updated by atomically swapping the ..data symlink of
+// their directory, which the files are links into, so the directory is
+// watched rather than the file.
+func watchCredentialsFile(path string, onChange func(), stopCh <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				if filepath.Clean(event.Name) == filepath.Clean(path) || filepath.Base(event.Name) == "..data" {
+					onChange()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				klog.ErrorS(err, "Failed to watch the credentials file", "file", path)
+			}
+		}
+	}()
+	return nil
+}
+
+// reloadAmbientCredentials drops the ambient configuration and the clients
+// that may have been created from it, so that rotated credentials are used
+// by the next challenges without restarting the webhook. The clients of the
+// other issuers are dropped as well, and simply created again.
+func (s *ddDNSProviderSolver) reloadAmbientCredentials() {
+	ambientConfigs.clear(true)
+	s.clients.purge()
+	klog.InfoS("Credentials file changed, ambient credentials reloaded", "file", *ambientCredentialsFile)
+}
+
+// ambientClient returns a client using the ambient credentials, for the
+// checks not tied to an issuer. It goes through the client cache so that a
+// reload of the credentials gets a new client.
+func (s *ddDNSProviderSolver) ambientClient() (*Client, error) {
+	ambient := ambientConfigs.get("")
+	return s.cachedClient(ambient.Endpoint, ambient.APIUser, ambient.APIPassword, "", retryPolicy(), nil)
+}
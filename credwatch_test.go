@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchCredentialsFile(t *testing.T) {
+	// Lay the directory out like a Secret volume: the file is a link into
+	// the ..data link to a timestamped directory.
+	dir := t.TempDir()
+	writeVersion := func(name, content string) {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, "dondominio.conf"), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(name, filepath.Join(dir, "..data_tmp")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeVersion("..2022_03_01_00_00_00", "[default]\napi_user = before\n")
+	path := filepath.Join(dir, "dondominio.conf")
+	if err := os.Symlink(filepath.Join("..data", "dondominio.conf"), path); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := make(chan struct{}, 16)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := watchCredentialsFile(path, func() { changed <- struct{}{} }, stopCh); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unrelated files do not trigger a reload
+	if err := os.WriteFile(filepath.Join(dir, "other"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changed:
+		t.Fatal("reloaded on a change of another file")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	writeVersion("..2022_03_02_00_00_00", "[default]\napi_user = after\n")
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("not reloaded after the rotation of the secret")
+	}
+}
+
+func TestAmbientConfigCacheWatched(t *testing.T) {
+	isolateAmbientConfig(t, "[default]\napi_user = before\n")
+	ambientConfigs.clear(true)
+
+	if got := ambientConfigs.get("").APIUser; got != "before" {
+		t.Fatalf("APIUser = %q, want before", got)
+	}
+	if err := os.WriteFile(*ambientCredentialsFile, []byte("[default]\napi_user = after\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := ambientConfigs.get("").APIUser; got != "before" {
+		t.Errorf("APIUser = %q before the reload, want the cached one", got)
+	}
+
+	solver := &ddDNSProviderSolver{}
+	solver.reloadAmbientCredentials()
+	if got := ambientConfigs.get("").APIUser; got != "after" {
+		t.Errorf("APIUser = %q after the reload, want after", got)
+	}
+}
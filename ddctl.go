@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ddctlName is the name of the companion CLI. The webhook binary runs it when
+// invoked under that name, e.g. through the /usr/local/bin/ddctl link of the
+// image, as well as through its ddctl subcommand.
+const ddctlName = "ddctl"
+
+// isDdctl returns whether the binary was invoked as the companion CLI. Both
+// separators are accepted whatever the platform, so that a Windows path is
+// recognized when the binary runs under a compatibility layer.
+func isDdctl(arg0 string) bool {
+	name := arg0[strings.LastIndexAny(arg0, `/\`)+1:]
+	return strings.TrimSuffix(name, ".exe") == ddctlName
+}
+
+// ddctlOptions are the flags common to the ddctl commands.
+type ddctlOptions struct {
+	endpoint   string
+	secret     string
+	kubeconfig string
+	timeout    time.Duration
+}
+
+// newDdctlCommand returns the command of the companion CLI, managing the
+// DonDominio records by hand with the client of the webhook, to reproduce the
+// failures of the webhook and clean up the challenge records it left behind.
+func newDdctlCommand() *cobra.Command {
+	opts := &ddctlOptions{}
+	root := &cobra.Command{
+		Use:           ddctlName,
+		Short:         "Manage the DonDominio challenge records by hand",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Version:       currentBuild().String(),
+	}
+	root.SetVersionTemplate("{{.Version}}\n")
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.endpoint, "endpoint", "",
+		"DonDominio API endpoint, as a name of the configuration files or an URL. Empty uses the one of the credentials.")
+	flags.StringVar(&opts.secret, "secret", "",
+		"Secret, as namespace/name, with the apiUser, apiPassword and optional endpoint keys of a credentialsSecretRef. Empty reads the credentials from the DD_API_USER and DD_API_PASSWORD environment variables or the configuration files.")
+	flags.StringVar(&opts.kubeconfig, "kubeconfig", "",
+		"Kubeconfig file from which --secret is read. Empty uses $KUBECONFIG, ~/.kube/config or the in-cluster config.")
+	flags.DurationVar(&opts.timeout, "timeout", 30*time.Second,
+		"Maximum duration of the command.")
+
+	root.AddCommand(&cobra.Command{
+		Use:   "ping",
+		Short: "Check that the DonDominio API answers and accepts the credentials",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			return opts.run(c, func(ctx context.Context, ddClient *Client) error {
+				if err := ddClient.PingWithContext(ctx); err != nil {
+					return err
+				}
+				fmt.Fprintln(c.OutOrStdout(), "ok")
+				return nil
+			})
+		},
+	})
+
+	root.AddCommand(newDdctlTXTCommand(opts), newDdctlZoneCommand(opts))
+	return root
+}
+
+func newDdctlTXTCommand(opts *ddctlOptions) *cobra.Command {
+	txt := &cobra.Command{
+		Use:   "txt",
+		Short: "Manage the TXT records",
+	}
+
+	txt.AddCommand(&cobra.Command{
+		Use:   "list NAME [VALUE]",
+		Short: "List the TXT records of a name, and value if given",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(c *cobra.Command, args []string) error {
+			zone, name, err := ddctlRecordName(args[0])
+			if err != nil {
+				return err
+			}
+			value := ""
+			if len(args) > 1 {
+				value = args[1]
+			}
+			return opts.run(c, func(ctx context.Context, ddClient *Client) error {
+				records, err := ddClient.ListTXT(ctx, zone, name, value)
+				if err != nil {
+					return err
+				}
+				return printTXTRecords(c.OutOrStdout(), records)
+			})
+		},
+	})
+
+	var ttl time.Duration
+	create := &cobra.Command{
+		Use:   "create NAME VALUE",
+		Short: "Create a TXT record",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(c *cobra.Command, args []string) error {
+			zone, name, err := ddctlRecordName(args[0])
+			if err != nil {
+				return err
+			}
+			return opts.run(c, func(ctx context.Context, ddClient *Client) error {
+				record, err := ddClient.CreateTXT(ctx, zone, name, args[1], ttl)
+				if err != nil {
+					return err
+				}
+				return printTXTRecords(c.OutOrStdout(), []TXTRecord{record})
+			})
+		},
+	}
+	create.Flags().DurationVar(&ttl, "ttl", 0, "TTL of the record. Zero uses the default TTL of the zone.")
+	txt.AddCommand(create)
+
+	var id string
+	del := &cobra.Command{
+		Use:   "delete NAME [VALUE]",
+		Short: "Delete the TXT records of a name and value, or the record of --id, if still there",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(c *cobra.Command, args []string) error {
+			if (len(args) == 2) == (id != "") {
+				return fmt.Errorf("either a value or --id is required")
+			}
+			fqdn, err := ddctlFQDN(args[0])
+			if err != nil {
+				return err
+			}
+			zone := getDomain(fqdn)
+			return opts.run(c, func(ctx context.Context, ddClient *Client) error {
+				if id != "" {
+					err := ddClient.DeleteTXT(ctx, zone, id)
+					if errors.Is(err, ErrRecordNotFound) {
+						fmt.Fprintf(c.OutOrStdout(), "%s already absent\n", id)
+						return nil
+					}
+					if err != nil {
+						return err
+					}
+					fmt.Fprintf(c.OutOrStdout(), "deleted %s\n", id)
+					return nil
+				}
+				result, err := EnsureAbsent(ctx, ddClient, zone, fqdn, args[1])
+				for _, deleted := range result.Deleted {
+					fmt.Fprintf(c.OutOrStdout(), "deleted %s\n", deleted)
+				}
+				if err != nil {
+					return err
+				}
+				if len(result.Deleted) == 0 {
+					fmt.Fprintln(c.OutOrStdout(), "already absent")
+				}
+				return nil
+			})
+		},
+	}
+	del.Flags().StringVar(&id, "id", "", "ID of the record to delete, as listed by txt list.")
+	txt.AddCommand(del)
+
+	return txt
+}
+
+func newDdctlZoneCommand(opts *ddctlOptions) *cobra.Command {
+	zone := &cobra.Command{
+		Use:   "zone",
+		Short: "Inspect, export and import the zones",
+	}
+
+	zone.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the services of the account, whose DNS zones the credentials manage",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			return opts.run(c, func(ctx context.Context, ddClient *Client) error {
+				services, err := ddClient.ListServices(ctx)
+				if err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(c.OutOrStdout(), 0, 8, 2, ' ', 0)
+				fmt.Fprintln(w, "NAME\tTYPE\tSTATUS")
+				for _, service := range services {
+					fmt.Fprintf(w, "%s\t%s\t%s\n", service.Name, service.Type, service.Status)
+				}
+				return w.Flush()
+			})
+		},
+	})
+
+	zone.AddCommand(&cobra.Command{
+		Use:   "status ZONE",
+		Short: "Check that the zone is an active DonDominio service and list its challenge records",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			domain, err := ddctlZone(args[0])
+			if err != nil {
+				return err
+			}
+			return opts.run(c, func(ctx context.Context, ddClient *Client) error {
+				if err := ddClient.ValidateZone(ctx, domain); err != nil {
+					return err
+				}
+				fmt.Fprintf(c.OutOrStdout(), "zone %s is active\n", domain)
+				records, err := challengeRecords(ctx, ddClient, domain)
+				if err != nil {
+					return err
+				}
+				return printTXTRecords(c.OutOrStdout(), records)
+			})
+		},
+	})
+
+	var output string
+	export := &cobra.Command{
+		Use:   "export ZONE",
+		Short: "Write all the records of the zone as a BIND zone file, to snapshot it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			domain, err := ddctlZone(args[0])
+			if err != nil {
+				return err
+			}
+			return opts.run(c, func(ctx context.Context, ddClient *Client) error {
+				records, err := ddClient.ExportZone(ctx, domain)
+				if err != nil {
+					return err
+				}
+				if output == "" || output == "-" {
+					return WriteZoneFile(c.OutOrStdout(), domain, records)
+				}
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				if err := WriteZoneFile(f, domain, records); err != nil {
+					f.Close()
+					return err
+				}
+				return f.Close()
+			})
+		},
+	}
+	export.Flags().StringVarP(&output, "output", "o", "", "File to write. Empty or - writes to the standard output.")
+	zone.AddCommand(export)
+
+	zone.AddCommand(&cobra.Command{
+		Use:   "import ZONE FILE",
+		Short: "Create the records of a zone file missing from the zone, to restore its export",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(c *cobra.Command, args []string) error {
+			domain, err := ddctlZone(args[0])
+			if err != nil {
+				return err
+			}
+			in := c.InOrStdin()
+			if args[1] != "-" {
+				f, err := os.Open(args[1])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+			records, err := ParseZoneFile(in, domain)
+			if err != nil {
+				return fmt.Errorf("invalid zone file %s: %w", args[1], err)
+			}
+			return opts.run(c, func(ctx context.Context, ddClient *Client) error {
+				created, err := ddClient.ImportZone(ctx, domain, records)
+				for _, record := range created {
+					fmt.Fprintf(c.OutOrStdout(), "created %s %s %s\n", record.Name, record.Type, record.Value)
+				}
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(c.OutOrStdout(), "%d created, %d already present\n", len(created), len(records)-len(created))
+				return nil
+			})
+		},
+	})
+
+	return zone
+}
+
+// ddctlFQDN returns the name of the challenge record of a name given on the
+// command line, with or without the trailing dot, normalized like the ones of
+// the webhook.
+func ddctlFQDN(name string) (string, error) {
+	fqdn, err := normalizeFQDN(name)
+	if err != nil {
+		return "", err
+	}
+	return challengeName(fqdn), nil
+}
+
+// ddctlRecordName returns the zone and the record name of the DonDominio API
+// of a name given on the command line.
+func ddctlRecordName(name string) (zone, record string, err error) {
+	fqdn, err := ddctlFQDN(name)
+	if err != nil {
+		return "", "", err
+	}
+	zone = getDomain(fqdn)
+	return zone, recordName(zone, getSubDomain(zone, fqdn)), nil
+}
+
+// ddctlZone returns the zone of a name given on the command line.
+func ddctlZone(name string) (string, error) {
+	fqdn, err := normalizeFQDN(name)
+	if err != nil {
+		return "", err
+	}
+	return getDomain(fqdn), nil
+}
+
+// printTXTRecords writes the records as a table.
+func printTXTRecords(out io.Writer, records []TXTRecord) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tVALUE")
+	for _, record := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", record.ID, record.Name, record.Value)
+	}
+	return w.Flush()
+}
+
+// run calls f with the client of the options, bounded by their timeout.
+func (opts *ddctlOptions) run(c *cobra.Command, f func(ctx context.Context, ddClient *Client) error) error {
+	ctx := c.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	ddClient, err := opts.client(ctx)
+	if err != nil {
+		return err
+	}
+	return redactError(withHint(f(ctx, ddClient)))
+}
+
+// client returns the DonDominio client of the credentials of --secret, or
+// else of the environment and the configuration files.
+func (opts *ddctlOptions) client(ctx context.Context) (*Client, error) {
+	endpoint, apiUser, apiPassword := opts.endpoint, "", ""
+	if opts.secret != "" {
+		namespace, name, err := splitConfigMap(opts.secret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --secret: %w", err)
+		}
+		rules := clientcmd.NewDefaultClientConfigLoadingRules()
+		rules.ExplicitPath = opts.kubeconfig
+		config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		client, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		s := &ddDNSProviderSolver{client: client}
+		creds, err := s.credentialsSecret(ctx, name, namespace)
+		if err != nil {
+			return nil, err
+		}
+		apiUser, apiPassword = creds.APIUser, creds.APIPassword
+		if endpoint == "" {
+			endpoint = creds.Endpoint
+		}
+	}
+
+	ddClient, err := NewClient(endpoint, apiUser, apiPassword)
+	if err != nil {
+		return nil, err
+	}
+	ddClient.UserAgent = currentBuild().userAgent()
+	return ddClient, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsDdctl(t *testing.T) {
+	for arg0, want := range map[string]bool{
+		"/usr/local/bin/ddctl":   true,
+		"ddctl":                  true,
+		`C:\bin\ddctl.exe`:       true,
+		"ddctl.exe":              true,
+		"/usr/local/bin/webhook": false,
+	} {
+		if got := isDdctl(arg0); got != want {
+			t.Errorf("isDdctl(%q) = %v, want %v", arg0, got, want)
+		}
+	}
+}
+
+func TestDdctlRecordName(t *testing.T) {
+	for name, want := range map[string][2]string{
+		"_acme-challenge.www.example.com.": {"example.com", "_acme-challenge.www.example.com"},
+		"_acme-challenge.example.com":      {"example.com", "_acme-challenge.example.com"},
+		"example.com":                      {"example.com", "_acme-challenge.example.com"},
+		"_acme-challenge.WWW.España.es":    {"xn--espaa-rta.es", "_acme-challenge.www.xn--espaa-rta.es"},
+	} {
+		zone, record, err := ddctlRecordName(name)
+		if err != nil || zone != want[0] || record != want[1] {
+			t.Errorf("ddctlRecordName(%q) = %q, %q, %v, want %q", name, zone, record, err, want)
+		}
+	}
+}
+
+func TestDdctl(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, []Dns{
+		{Name: "_acme-challenge.example.com", Type: "TXT", Value: "stranded-key"},
+		{Name: "www.example.com", Type: "A", Value: "192.0.2.1"},
+	})
+	server := httptest.NewServer(api)
+	defer server.Close()
+	t.Setenv("DD_API_USER", "apiuser")
+	t.Setenv("DD_API_PASSWORD", "apipasswd")
+
+	ddctl := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		root := newDdctlCommand()
+		root.SetOut(&out)
+		root.SetArgs(append(args, "--endpoint", server.URL))
+		err := root.Execute()
+		return out.String(), err
+	}
+
+	if out, err := ddctl("ping"); err != nil || out != "ok\n" {
+		t.Errorf("ping = %q, %v", out, err)
+	}
+	if out, err := ddctl("txt", "create", "_acme-challenge.www.example.com.", "manual-key"); err != nil || !strings.Contains(out, "manual-key") {
+		t.Errorf("txt create = %q, %v", out, err)
+	}
+	if out, err := ddctl("zone", "list"); err != nil || !strings.Contains(out, "example.com") || !strings.Contains(out, "active") {
+		t.Errorf("zone list = %q, %v", out, err)
+	}
+	out, err := ddctl("zone", "status", "example.com")
+	if err != nil || !strings.Contains(out, "stranded-key") || !strings.Contains(out, "manual-key") || strings.Contains(out, "192.0.2.1") {
+		t.Errorf("zone status = %q, %v", out, err)
+	}
+	if out, err := ddctl("txt", "delete", "_acme-challenge.example.com", "stranded-key"); err != nil || !strings.HasPrefix(out, "deleted ") {
+		t.Errorf("txt delete = %q, %v", out, err)
+	}
+	// Deleting again succeeds
+	if out, err := ddctl("txt", "delete", "_acme-challenge.example.com", "stranded-key"); err != nil || out != "already absent\n" {
+		t.Errorf("txt delete of a deleted record = %q, %v", out, err)
+	}
+	if out, err := ddctl("txt", "delete", "_acme-challenge.example.com", "--id", "1"); err != nil || out != "1 already absent\n" {
+		t.Errorf("txt delete --id of a deleted record = %q, %v", out, err)
+	}
+	if _, err := ddctl("txt", "delete", "_acme-challenge.example.com"); err == nil {
+		t.Error("txt delete without a value or --id succeeded")
+	}
+	out, err = ddctl("txt", "list", "_acme-challenge.example.com")
+	if err != nil || strings.Contains(out, "stranded-key") {
+		t.Errorf("txt list = %q, %v", out, err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runtimepprof "runtime/pprof"
+
+	"k8s.io/klog/v2"
+)
+
+func init() {
+	expvar.Publish("build", expvar.Func(func() interface{} { return currentBuild() }))
+	expvar.Publish("goroutines", expvar.Func(func() interface{} { return runtime.NumGoroutine() }))
+}
+
+// validateDebugAddr checks that the debug server, if enabled, listens on a
+// loopback address only, as it exposes the internals of the process without
+// authentication.
+func validateDebugAddr(addr string) error {
+	if addr == "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid debug address %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("invalid debug address %q, must be a loopback address, e.g. localhost:6060", addr)
+	}
+	return nil
+}
+
+// debugHandler returns the handler of the debug server: the pprof profiles
+// on /debug/pprof/, the expvar variables on /debug/vars and the stacks of all
+// the goroutines on /debug/goroutines.
+func debugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/goroutines", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		runtimepprof.Lookup("goroutine").WriteTo(w, 2)
+	})
+	return mux
+}
+
+// serveDebug serves the debug handler on addr until stopCh is closed.
+func serveDebug(addr string, stopCh <-chan struct{}) {
+	server := &http.Server{Addr: addr, Handler: debugHandler()}
+
+	go func() {
+		<-stopCh
+		server.Close()
+	}()
+
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		klog.ErrorS(err, "Debug server failed", "addr", addr)
+	}
+}
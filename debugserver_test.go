@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateDebugAddr(t *testing.T) {
+	for _, addr := range []string{"", "localhost:6060", "127.0.0.1:6060", "[::1]:6060"} {
+		if err := validateDebugAddr(addr); err != nil {
+			t.Errorf("validateDebugAddr(%q) = %v", addr, err)
+		}
+	}
+	for _, addr := range []string{":6060", "0.0.0.0:6060", "10.0.0.1:6060", "example.com:6060", "localhost"} {
+		if err := validateDebugAddr(addr); err == nil {
+			t.Errorf("validateDebugAddr(%q) succeeded", addr)
+		}
+	}
+}
+
+func TestDebugHandler(t *testing.T) {
+	handler := debugHandler()
+	for path, want := range map[string]string{
+		"/debug/goroutines":    "goroutine ",
+		"/debug/vars":          `"build"`,
+		"/debug/pprof/":        "heap",
+		"/debug/pprof/cmdline": "",
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != 200 || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("GET %s = %d %q", path, rec.Code, rec.Body.String())
+		}
+	}
+}
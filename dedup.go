@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"k8s.io/klog/v2"
+)
+
+// callGroup shares the result of a call with the identical calls made while
+// it is in flight, e.g. a ChallengeRequest re-sent by cert-manager before
+// the first one returned, so that they do not race to mutate the zone. Its
+// zero value is ready to use.
+type callGroup struct {
+	mu    sync.Mutex
+	calls map[string]*batchCall
+}
+
+// do calls f unless a call with the same key is in flight, whose result is
+// then returned, or the error of ctx if it is done first. shared is true if
+// the result is not the one of f.
+func (g *callGroup) do(ctx context.Context, key string, f func() error) (shared bool, err error) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+			return true, call.err
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+	}
+	call := &batchCall{done: make(chan struct{})}
+	if g.calls == nil {
+		g.calls = make(map[string]*batchCall)
+	}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.err = f()
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+	return false, call.err
+}
+
+// challengeCallKey identifies the identical calls of an action for a
+// challenge: same record name and key.
+func challengeCallKey(action string, ch *v1alpha1.ChallengeRequest) string {
+	return action + "\x00" + ch.ResolvedFQDN + "\x00" + ch.Key
+}
+
+// dedupChallenge calls f for the challenge, unless an identical call of the
+// action is in flight, whose result is then returned.
+func (s *ddDNSProviderSolver) dedupChallenge(ctx context.Context, action string, ch *v1alpha1.ChallengeRequest, f func(ctx context.Context, ch *v1alpha1.ChallengeRequest) error) error {
+	shared, err := s.inFlightCalls.do(ctx, challengeCallKey(action, ch), func() error {
+		return f(ctx, ch)
+	})
+	if shared {
+		klog.FromContext(ctx).V(1).Info("Identical call in flight, its result is shared", "action", action)
+	}
+	return err
+}
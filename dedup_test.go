@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallGroup(t *testing.T) {
+	var g callGroup
+	var calls, shared int64
+	release := make(chan struct{})
+	errFailed := errors.New("failed")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			isShared, err := g.do(context.Background(), "present\x00_acme-challenge.example.com.\x00key", func() error {
+				atomic.AddInt64(&calls, 1)
+				<-release
+				return errFailed
+			})
+			if isShared {
+				atomic.AddInt64(&shared, 1)
+			}
+			if err != errFailed {
+				t.Errorf("do() = %v, want the error of the shared call", err)
+			}
+		}()
+	}
+	// Let the identical calls join the first one
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 || shared != 4 {
+		t.Errorf("%d calls made and %d shared, want 1 and 4", calls, shared)
+	}
+
+	// The calls are not memoized once they returned
+	if isShared, err := g.do(context.Background(), "present\x00_acme-challenge.example.com.\x00key", func() error { return nil }); isShared || err != nil {
+		t.Errorf("do() after the call returned = %v, %v, want a new call", isShared, err)
+	}
+}
+
+func TestCallGroupContext(t *testing.T) {
+	var g callGroup
+	release := make(chan struct{})
+	defer close(release)
+	go g.do(context.Background(), "cleanup\x00_acme-challenge.example.com.\x00key", func() error {
+		<-release
+		return nil
+	})
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	shared, err := g.do(ctx, "cleanup\x00_acme-challenge.example.com.\x00key", func() error {
+		t.Error("identical call made while the first one is in flight")
+		return nil
+	})
+	if !shared || err != context.DeadlineExceeded {
+		t.Errorf("do() of a waiter past its deadline = %v, %v, want the deadline of its context", shared, err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Defaults of the issuer config. The command line flags of the same name
+// default to them, and replace them for all the issuers.
+const (
+	// DefaultEndpoint is the endpoint of the issuers that set none and are
+	// not allowed ambient credentials, which may set it otherwise.
+	DefaultEndpoint = Endpoint
+	// DefaultRequestTimeout bounds the time spent calling DonDominio for a
+	// single Present or CleanUp.
+	DefaultRequestTimeout = 2 * time.Minute
+	// DefaultPropagationInterval is the interval between two DNS queries
+	// when waiting for propagation.
+	DefaultPropagationInterval = 10 * time.Second
+	// DefaultPropagationTimeout is the maximum time to wait for propagation
+	// when the issuer sets no record TTL.
+	DefaultPropagationTimeout = 2 * time.Minute
+	// DefaultPropagationTTLMargin is added to the record TTL to get the
+	// propagation timeout when the issuer sets one.
+	DefaultPropagationTTLMargin = time.Minute
+)
+
+// retryPolicyConfig overrides the retry policy of the --retry-* flags for
+// the DonDominio API requests of an issuer.
+type retryPolicyConfig struct {
+	MaxAttempts *int             `json:"maxAttempts,omitempty"`
+	BaseDelay   *metav1.Duration `json:"baseDelay,omitempty"`
+	MaxDelay    *metav1.Duration `json:"maxDelay,omitempty"`
+}
+
+// setDefaults fills the fields left unset by the issuer with the values of
+// the command line flags, so that the rest of the webhook sees the complete
+// settings of the challenge. It must be called once allowAmbientCredentials
+// is set.
+func (cfg *ddDNSProviderConfig) setDefaults() {
+	if cfg.Endpoint == "" && !cfg.allowAmbientCredentials {
+		cfg.Endpoint = DefaultEndpoint
+	}
+	if cfg.RequestTimeout == nil {
+		cfg.RequestTimeout = &metav1.Duration{Duration: *requestTimeout}
+	}
+	if cfg.RecordTTL == nil && *recordTTLFlag > 0 {
+		cfg.RecordTTL = &metav1.Duration{Duration: *recordTTLFlag}
+	}
+
+	if cfg.WaitForPropagation == nil {
+		wait := *waitForPropagationFlag
+		cfg.WaitForPropagation = &wait
+	}
+	if cfg.PropagationInterval == nil {
+		cfg.PropagationInterval = &metav1.Duration{Duration: *propagationInterval}
+	}
+	if cfg.PropagationTimeout == nil {
+		timeout := *propagationTimeout
+		if cfg.RecordTTL != nil {
+			// Resolvers may keep serving the records of a previous
+			// challenge for up to their TTL
+			timeout = cfg.RecordTTL.Duration + *propagationTTLMargin
+		}
+		cfg.PropagationTimeout = &metav1.Duration{Duration: timeout}
+	}
+	if len(cfg.PropagationResolvers) == 0 {
+		cfg.PropagationResolvers = splitResolvers(*propagationResolvers)
+	}
+
+	if cfg.RetryPolicy == nil {
+		cfg.RetryPolicy = &retryPolicyConfig{}
+	}
+	if cfg.RetryPolicy.MaxAttempts == nil {
+		maxAttempts := *retryMaxAttempts
+		cfg.RetryPolicy.MaxAttempts = &maxAttempts
+	}
+	if cfg.RetryPolicy.BaseDelay == nil {
+		cfg.RetryPolicy.BaseDelay = &metav1.Duration{Duration: *retryBaseDelay}
+	}
+	if cfg.RetryPolicy.MaxDelay == nil {
+		cfg.RetryPolicy.MaxDelay = &metav1.Duration{Duration: *retryMaxDelay}
+	}
+}
+
+// retryPolicy returns the retry policy of the DonDominio clients of the
+// issuer.
+func (cfg *ddDNSProviderConfig) retryPolicy() RetryPolicy {
+	policy := retryPolicy()
+	if cfg.RetryPolicy == nil {
+		return policy
+	}
+	if cfg.RetryPolicy.MaxAttempts != nil {
+		policy.MaxAttempts = *cfg.RetryPolicy.MaxAttempts
+	}
+	if cfg.RetryPolicy.BaseDelay != nil {
+		policy.BaseDelay = cfg.RetryPolicy.BaseDelay.Duration
+	}
+	if cfg.RetryPolicy.MaxDelay != nil {
+		policy.MaxDelay = cfg.RetryPolicy.MaxDelay.Duration
+	}
+	return policy
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetDefaults(t *testing.T) {
+	cfg := &ddDNSProviderConfig{
+		ApplicationKey: "apiuser",
+		ApplicationSecretRef: corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "dd-credentials"},
+		},
+	}
+	cfg.setDefaults()
+	if cfg.Endpoint != DefaultEndpoint {
+		t.Errorf("endpoint = %q, want %q", cfg.Endpoint, DefaultEndpoint)
+	}
+	if got := cfg.requestTimeout(); got != DefaultRequestTimeout {
+		t.Errorf("requestTimeout() = %s, want %s", got, DefaultRequestTimeout)
+	}
+	settings := cfg.propagationSettings()
+	if settings.Interval != DefaultPropagationInterval || settings.Timeout != DefaultPropagationTimeout {
+		t.Errorf("propagationSettings() = %+v", settings)
+	}
+	if cfg.RecordTTL != nil {
+		t.Errorf("recordTTL = %s, want the default TTL of the zone", cfg.RecordTTL.Duration)
+	}
+	if got := cfg.retryPolicy(); got != DefaultRetryPolicy {
+		t.Errorf("retryPolicy() = %+v, want %+v", got, DefaultRetryPolicy)
+	}
+	if errs := validateConfig(cfg, false); len(errs) != 0 {
+		t.Errorf("validateConfig() = %v", errs)
+	}
+}
+
+func TestSetDefaultsKeepsOverrides(t *testing.T) {
+	maxAttempts := 1
+	cfg := &ddDNSProviderConfig{
+		Endpoint:       "https://dondominio.example",
+		RequestTimeout: &metav1.Duration{Duration: time.Minute},
+		RecordTTL:      &metav1.Duration{Duration: 5 * time.Minute},
+		RetryPolicy:    &retryPolicyConfig{MaxAttempts: &maxAttempts},
+	}
+	cfg.setDefaults()
+	if cfg.Endpoint != "https://dondominio.example" || cfg.requestTimeout() != time.Minute {
+		t.Errorf("overridden fields replaced: endpoint %q, requestTimeout %s", cfg.Endpoint, cfg.requestTimeout())
+	}
+	if got, want := cfg.propagationSettings().Timeout, 5*time.Minute+DefaultPropagationTTLMargin; got != want {
+		t.Errorf("propagation timeout = %s, want %s", got, want)
+	}
+	policy := cfg.retryPolicy()
+	if policy.MaxAttempts != 1 || policy.BaseDelay != DefaultRetryPolicy.BaseDelay {
+		t.Errorf("retryPolicy() = %+v", policy)
+	}
+}
+
+func TestSetDefaultsAmbientEndpoint(t *testing.T) {
+	cfg := &ddDNSProviderConfig{allowAmbientCredentials: true}
+	cfg.setDefaults()
+	if cfg.Endpoint != "" {
+		t.Errorf("endpoint = %q, want it left to the ambient credentials", cfg.Endpoint)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+)
+
+var apiDeprecationWarnings = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "api_deprecation_warnings_total",
+	Help:      "Number of DonDominio API responses carrying a deprecation header or messages, by path and source (header or message).",
+}, []string{"path", "source"})
+
+func init() {
+	metricsRegistry.MustRegister(apiDeprecationWarnings)
+}
+
+// deprecationHeaders are the response headers announcing that an endpoint is
+// deprecated or will be removed (RFC 8594 and the Deprecation header draft),
+// or carrying a warning (RFC 7234).
+var deprecationHeaders = []string{"Deprecation", "Sunset", "Warning"}
+
+// loggedWarnings holds the warnings already logged, by path, so that each
+// one is only logged once per process while the metric counts all of them.
+var loggedWarnings sync.Map
+
+// reportDeprecations logs and counts the deprecation headers of the response
+// to path, and counts the messages of its envelope, handled according to
+// --api-messages.
+func reportDeprecations(ctx context.Context, path string, header http.Header, resType interface{}) {
+	for _, name := range deprecationHeaders {
+		for _, value := range header.Values(name) {
+			apiDeprecationWarnings.WithLabelValues(path, "header").Inc()
+			if _, logged := loggedWarnings.LoadOrStore(path+"\x00"+name+"\x00"+value, true); !logged {
+				klog.FromContext(ctx).Info("DonDominio API deprecation header, the webhook may need an update", "path", path, "header", name, "value", value)
+			}
+		}
+	}
+
+	r, ok := resType.(interface{ response() *ddResponse })
+	if !ok {
+		return
+	}
+	for _, message := range r.response().Messages {
+		apiDeprecationWarnings.WithLabelValues(path, "message").Inc()
+		apiMessages.handle(ctx, path, message)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDeprecationWarnings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Sunset", "Wed, 01 Jan 2031 00:00:00 GMT")
+		w.Write([]byte(`{"success": true, "errorCode": 0, "messages": ["filterName will be removed, use filter"]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const path = "/test/deprecated"
+	// The counters are global, e.g. with -count=2
+	headersBefore := testutil.ToFloat64(apiDeprecationWarnings.WithLabelValues(path, "header"))
+	messagesBefore := testutil.ToFloat64(apiDeprecationWarnings.WithLabelValues(path, "message"))
+	for i := 0; i < 2; i++ {
+		if err := client.PostWithContext(context.Background(), path, nil, &ddResponse{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := testutil.ToFloat64(apiDeprecationWarnings.WithLabelValues(path, "header")) - headersBefore; got != 4 {
+		t.Errorf("deprecation headers = %v, want 4", got)
+	}
+	if got := testutil.ToFloat64(apiDeprecationWarnings.WithLabelValues(path, "message")) - messagesBefore; got != 2 {
+		t.Errorf("deprecation messages = %v, want 2", got)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+
+	"github.com/baarde/cert-manager-webhook-dd/registry"
+)
+
+// readLocalSecret reads the secret namespace/name from dir, each regular file
+// of dir/namespace/name being a key of the secret, as in a secret volume.
+// The files starting with a dot, like the ..data link of the volumes, are
+// skipped.
+func readLocalSecret(dir, namespace, name string) (*corev1.Secret, error) {
+	secretDir := filepath.Join(dir, namespace, name)
+	entries, err := os.ReadDir(secretDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, apierrors.NewNotFound(corev1.Resource("secrets"), name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       make(map[string][]byte, len(entries)),
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		// Stat follows the links of the secret volumes
+		path := filepath.Join(secretDir, entry.Name())
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		secret.Data[entry.Name()] = value
+	}
+	return secret, nil
+}
+
+// devOptions are the flags of the dev command.
+type devOptions struct {
+	kubeconfig string
+	listen     string
+}
+
+// newDevCommand returns the dev command, running the solvers outside of the
+// cluster for debugging. The webhook server library only supports the
+// in-cluster config, so the solvers are not served to cert-manager: the
+// challenges are sent to the local endpoints of the command instead, e.g.
+// with curl.
+func newDevCommand() *cobra.Command {
+	opts := &devOptions{}
+	dev := &cobra.Command{
+		Use:   "dev",
+		Short: "Run the solvers locally against the cluster of a kubeconfig, for debugging",
+		Long: `Run the solvers locally against the cluster of a kubeconfig, for debugging.
+
+The challenges are sent as ChallengeRequest JSON objects to the
+POST /<solver>/present and POST /<solver>/cleanup endpoints of --listen.
+With --secrets-dir, the secrets are read from local files and no cluster is
+needed.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			if err := applyEnvDefaults(c.Flags()); err != nil {
+				return err
+			}
+			return validateFlags()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			return opts.run()
+		},
+	}
+	dev.Flags().StringVar(&opts.kubeconfig, "kubeconfig", "",
+		"Kubeconfig file of the cluster. Empty uses $KUBECONFIG or ~/.kube/config.")
+	dev.Flags().StringVar(&opts.listen, "listen", "localhost:8089",
+		"Address of the endpoints receiving the challenges.")
+	dev.Flags().AddGoFlagSet(flag.CommandLine)
+	return dev
+}
+
+// restConfig returns the config of the cluster of the kubeconfig. Without a
+// kubeconfig, an empty config is returned if the secrets are read from
+// --secrets-dir, the solvers not needing the cluster then.
+func (opts *devOptions) restConfig() (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = opts.kubeconfig
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		if *secretsDir != "" && opts.kubeconfig == "" && clientcmd.IsEmptyConfig(err) {
+			klog.Info("No kubeconfig found, the features needing the cluster will fail")
+			return &rest.Config{}, nil
+		}
+		return nil, fmt.Errorf("failed to load the kubeconfig: %w", err)
+	}
+	return config, nil
+}
+
+// run initializes the solvers of --solvers with the kubeconfig and serves
+// their endpoints until the process is interrupted.
+func (opts *devOptions) run() error {
+	solvers, err := registry.Solvers(solverList(*solversFlag))
+	if err != nil {
+		return fmt.Errorf("invalid --solvers flag, registered solvers are %s: %w", strings.Join(registry.Names(), ", "), err)
+	}
+	servedSolvers = solverNames(solvers)
+	config, err := opts.restConfig()
+	if err != nil {
+		return err
+	}
+
+	stopCh := make(chan struct{})
+	for _, solver := range solvers {
+		if err := solver.Initialize(config, stopCh); err != nil {
+			close(stopCh)
+			return fmt.Errorf("failed to initialize the %s solver: %w", solver.Name(), err)
+		}
+	}
+
+	server := &http.Server{Addr: opts.listen, Handler: devHandler(solvers)}
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		close(stopCh)
+		server.Close()
+	}()
+	klog.InfoS("Serving the solvers locally", "addr", opts.listen, "solvers", servedSolvers)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// devHandler serves the POST /<solver>/present and POST /<solver>/cleanup
+// endpoints of the dev command, calling the solver with the ChallengeRequest
+// of the body.
+func devHandler(solvers []webhook.Solver) http.Handler {
+	mux := http.NewServeMux()
+	for _, solver := range solvers {
+		solver := solver
+		for action, call := range map[string]func(*v1alpha1.ChallengeRequest) error{
+			"present": solver.Present,
+			"cleanup": solver.CleanUp,
+		} {
+			call := call
+			mux.HandleFunc("/"+solver.Name()+"/"+action, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					w.Header().Set("Allow", http.MethodPost)
+					http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				var ch v1alpha1.ChallengeRequest
+				if err := json.NewDecoder(r.Body).Decode(&ch); err != nil {
+					http.Error(w, fmt.Sprintf("invalid ChallengeRequest: %v", err), http.StatusBadRequest)
+					return
+				}
+				if err := call(&ch); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				fmt.Fprintln(w, "ok")
+			})
+		}
+	}
+	return mux
+}
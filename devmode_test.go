@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func TestReadLocalSecret(t *testing.T) {
+	dir := t.TempDir()
+	secretDir := filepath.Join(dir, "cert-manager", "dd-credentials")
+	if err := os.MkdirAll(filepath.Join(secretDir, "..2022_10_01"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range map[string]string{
+		"apiUser":                   "apiuser",
+		"..2022_10_01/apiPassword":  "apipasswd",
+		"..2022_10_01/unreferenced": "other",
+	} {
+		if err := os.WriteFile(filepath.Join(secretDir, name), []byte(value), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// As in a secret volume, the keys may be links into a hidden directory
+	if err := os.Symlink(filepath.Join("..2022_10_01", "apiPassword"), filepath.Join(secretDir, "apiPassword")); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &ddDNSProviderSolver{secretsDir: dir}
+	creds, err := s.credentialsSecret(context.Background(), "dd-credentials", "cert-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.APIUser != "apiuser" || creds.APIPassword != "apipasswd" {
+		t.Errorf("credentials = %+v", creds)
+	}
+
+	secret, err := readLocalSecret(dir, "cert-manager", "dd-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret.Data) != 2 {
+		t.Errorf("keys = %v, want apiUser and apiPassword", secret.Data)
+	}
+
+	if _, err := readLocalSecret(dir, "default", "dd-credentials"); !apierrors.IsNotFound(err) {
+		t.Errorf("missing secret error = %v, want not found", err)
+	}
+}
+
+// devTestSolver records the challenges it is called with.
+type devTestSolver struct {
+	calls []string
+}
+
+func (s *devTestSolver) Name() string { return "test" }
+
+func (s *devTestSolver) Present(ch *v1alpha1.ChallengeRequest) error {
+	s.calls = append(s.calls, "present "+ch.ResolvedFQDN)
+	return nil
+}
+
+func (s *devTestSolver) CleanUp(ch *v1alpha1.ChallengeRequest) error {
+	s.calls = append(s.calls, "cleanup "+ch.ResolvedFQDN)
+	return errors.New("cleanup failed")
+}
+
+func (s *devTestSolver) Initialize(*rest.Config, <-chan struct{}) error { return nil }
+
+func TestDevHandler(t *testing.T) {
+	solver := &devTestSolver{}
+	server := httptest.NewServer(devHandler([]webhook.Solver{solver}))
+	defer server.Close()
+
+	post := func(path, body string) int {
+		t.Helper()
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	challenge := `{"resolvedFQDN": "_acme-challenge.example.com."}`
+	if code := post("/test/present", challenge); code != http.StatusOK {
+		t.Errorf("present status = %d", code)
+	}
+	if code := post("/test/cleanup", challenge); code != http.StatusInternalServerError {
+		t.Errorf("failed cleanup status = %d", code)
+	}
+	if code := post("/test/present", "{"); code != http.StatusBadRequest {
+		t.Errorf("invalid challenge status = %d", code)
+	}
+	if code := post("/other/present", challenge); code != http.StatusNotFound {
+		t.Errorf("unknown solver status = %d", code)
+	}
+
+	want := []string{"present _acme-challenge.example.com.", "cleanup _acme-challenge.example.com."}
+	if strings.Join(solver.calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls = %q, want %q", solver.calls, want)
+	}
+
+	resp, err := http.Get(server.URL + "/test/present")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d", resp.StatusCode)
+	}
+}
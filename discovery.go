@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook"
+)
+
+// configSchemaVersion is the version of the issuer config, bumped when a
+// field is removed or changes meaning. Adding a field does not change it.
+// It is the version of the configAPIVersion of the configs.
+const configSchemaVersion = "v1"
+
+// servedSolvers are the names of the solvers served by the webhook, set by
+// main before the server starts.
+var servedSolvers []string
+
+// solverNames returns the names of the solvers.
+func solverNames(solvers []webhook.Solver) []string {
+	names := make([]string, len(solvers))
+	for i, solver := range solvers {
+		names[i] = solver.Name()
+	}
+	return names
+}
+
+// discovery describes how to reference the webhook from an Issuer, for the
+// tools generating Issuers and dashboards.
+type discovery struct {
+	GroupName           string   `json:"groupName"`
+	Solvers             []string `json:"solvers"`
+	ConfigSchemaVersion string   `json:"configSchemaVersion"`
+	// ConfigFields are the fields of the config of the don-dominio solver
+	ConfigFields []string `json:"configFields"`
+}
+
+// configFields returns the JSON names of the fields of the issuer config.
+func configFields() []string {
+	t := reflect.TypeOf(ddDNSProviderConfig{})
+	fields := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// serveDiscovery serves the discovery document as JSON.
+func serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(discovery{
+		GroupName:           GroupName,
+		Solvers:             servedSolvers,
+		ConfigSchemaVersion: configSchemaVersion,
+		ConfigFields:        configFields(),
+	})
+}
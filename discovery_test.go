@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestServeDiscovery(t *testing.T) {
+	defer func(group string, solvers []string) { GroupName, servedSolvers = group, solvers }(GroupName, servedSolvers)
+	GroupName = "acme.example.com"
+	servedSolvers = []string{"don-dominio"}
+
+	rec := httptest.NewRecorder()
+	serveDiscovery(rec, httptest.NewRequest("GET", "/debug/discovery", nil))
+
+	var got discovery
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.GroupName != "acme.example.com" || !reflect.DeepEqual(got.Solvers, []string{"don-dominio"}) || got.ConfigSchemaVersion != configSchemaVersion {
+		t.Errorf("discovery = %+v", got)
+	}
+	fields := map[string]bool{}
+	for _, field := range got.ConfigFields {
+		fields[field] = true
+	}
+	for _, field := range []string{"endpoint", "applicationKey", "applicationSecretRef", "conflictPolicy"} {
+		if !fields[field] {
+			t.Errorf("config field %q missing from %q", field, got.ConfigFields)
+		}
+	}
+}
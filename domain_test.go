@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestRecordNaming(t *testing.T) {
+	tests := []struct {
+		fqdn      string
+		domain    string
+		subDomain string
+		name      string
+	}{
+		{"_acme-challenge.example.com.", "example.com", "_acme-challenge", "_acme-challenge.example.com"},
+		{"_acme-challenge.www.example.com.", "example.com", "_acme-challenge.www", "_acme-challenge.www.example.com"},
+		{"_acme-challenge.example.com", "example.com", "_acme-challenge", "_acme-challenge.example.com"},
+		// The apex itself, e.g. a CNAME target
+		{"example.com.", "example.com", "", "example.com"},
+		{"example.com", "example.com", "", "example.com"},
+	}
+	for _, tt := range tests {
+		domain := getDomain(tt.fqdn)
+		if domain != tt.domain {
+			t.Errorf("getDomain(%q) = %q, want %q", tt.fqdn, domain, tt.domain)
+		}
+		subDomain := getSubDomain(domain, tt.fqdn)
+		if subDomain != tt.subDomain {
+			t.Errorf("getSubDomain(%q, %q) = %q, want %q", domain, tt.fqdn, subDomain, tt.subDomain)
+		}
+		if name := recordName(domain, subDomain); name != tt.name {
+			t.Errorf("recordName(%q, %q) = %q, want %q", domain, subDomain, name, tt.name)
+		}
+	}
+
+	for fqdn, want := range map[string]string{
+		"example.com.":                 "_acme-challenge.example.com.",
+		"example.com":                  "_acme-challenge.example.com.",
+		"_acme-challenge.example.com.": "_acme-challenge.example.com.",
+		"www.example.com.":             "www.example.com.",
+	} {
+		if got := challengeName(fqdn); got != want {
+			t.Errorf("challengeName(%q) = %q, want %q", fqdn, got, want)
+		}
+	}
+}
+
+func TestNormalizeFQDN(t *testing.T) {
+	tests := []struct {
+		fqdn string
+		want string
+	}{
+		{"_acme-challenge.example.com.", "_acme-challenge.example.com."},
+		{"_acme-challenge.WWW.Example.COM.", "_acme-challenge.www.example.com."},
+		{"_acme-challenge.españa.es.", "_acme-challenge.xn--espaa-rta.es."},
+		{"_acme-challenge.ESPAÑA.es", "_acme-challenge.xn--espaa-rta.es."},
+		{"_acme-challenge.www.pingüino.cat.", "_acme-challenge.www.xn--pingino-q2a.cat."},
+	}
+	for _, tt := range tests {
+		got, err := normalizeFQDN(tt.fqdn)
+		if err != nil {
+			t.Errorf("normalizeFQDN(%q) error: %v", tt.fqdn, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeFQDN(%q) = %q, want %q", tt.fqdn, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// domainCredentials are the credentials of the names under a domain of the
+// domainCredentials config field. Either CredentialsSecretRef, or
+// ApplicationKey (or ApplicationKeyRef) and ApplicationSecretRef must be set,
+// with the same meaning as the fields of the issuer config.
+type domainCredentials struct {
+	ApplicationKey       string                       `json:"applicationKey,omitempty"`
+	ApplicationKeyRef    *corev1.SecretKeySelector    `json:"applicationKeyRef,omitempty"`
+	ApplicationSecretRef corev1.SecretKeySelector     `json:"applicationSecretRef,omitempty"`
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+}
+
+func (c *domainCredentials) validate(path *field.Path, domain string) field.ErrorList {
+	var allErrs field.ErrorList
+	if normalizeDomain(domain) == "" {
+		allErrs = append(allErrs, field.Invalid(path, domain, "must be a domain"))
+	}
+	if c.CredentialsSecretRef != nil {
+		if c.CredentialsSecretRef.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Child("credentialsSecretRef", "name"), ""))
+		}
+		if c.ApplicationKey != "" || c.ApplicationKeyRef != nil || c.ApplicationSecretRef.Name != "" {
+			allErrs = append(allErrs, field.Forbidden(path.Child("credentialsSecretRef"), "cannot be combined with applicationKey, applicationKeyRef or applicationSecretRef"))
+		}
+		return allErrs
+	}
+	if c.ApplicationKeyRef != nil {
+		if c.ApplicationKeyRef.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Child("applicationKeyRef", "name"), ""))
+		}
+	} else if c.ApplicationKey == "" {
+		allErrs = append(allErrs, field.Required(path.Child("applicationKey"), "or applicationKeyRef or credentialsSecretRef"))
+	}
+	if c.ApplicationSecretRef.Name == "" {
+		allErrs = append(allErrs, field.Required(path.Child("applicationSecretRef", "name"), ""))
+	}
+	return allErrs
+}
+
+// normalizeDomain returns domain without its leading and trailing dots, in
+// the form of normalizeFQDN, so that the domains of the config match the
+// punycode names of the challenges, or "" if it is not a valid domain.
+func normalizeDomain(domain string) string {
+	name, err := normalizeFQDN(strings.Trim(domain, "."))
+	if err != nil {
+		return ""
+	}
+	return util.UnFqdn(name)
+}
+
+// forName returns the config of the challenges of fqdn: the credentials are
+// those of the domainCredentials entry of the longest domain fqdn is in, if
+// any, or else the ones of the issuer.
+func (cfg *ddDNSProviderConfig) forName(fqdn string) *ddDNSProviderConfig {
+	name := normalizeDomain(fqdn)
+	var match string
+	var creds domainCredentials
+	for domain, c := range cfg.DomainCredentials {
+		domain = normalizeDomain(domain)
+		if name != domain && !strings.HasSuffix(name, "."+domain) {
+			continue
+		}
+		if len(domain) > len(match) {
+			match, creds = domain, c
+		}
+	}
+	if match == "" {
+		return cfg
+	}
+
+	domainCfg := *cfg
+	domainCfg.ApplicationKey = creds.ApplicationKey
+	domainCfg.ApplicationKeyRef = creds.ApplicationKeyRef
+	domainCfg.ApplicationSecretRef = creds.ApplicationSecretRef
+	domainCfg.CredentialsSecretRef = creds.CredentialsSecretRef
+	domainCfg.CredentialsBroker = nil
+	// The credentials of the entry are complete
+	domainCfg.allowAmbientCredentials = false
+	return &domainCfg
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDomainCredentials(t *testing.T) {
+	solver := &ddDNSProviderSolver{client: fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "default-credentials", Namespace: "default"},
+			Data:       map[string][]byte{"password": []byte("defaultpasswd")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "tenant-credentials", Namespace: "default"},
+			Data:       map[string][]byte{"apiUser": []byte("tenant"), "apiPassword": []byte("tenantpasswd")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop-credentials", Namespace: "default"},
+			Data:       map[string][]byte{"password": []byte("shoppasswd")},
+		},
+	)}
+	cfg := &ddDNSProviderConfig{
+		Endpoint:       "http://dondominio.invalid",
+		ApplicationKey: "default",
+		ApplicationSecretRef: corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "default-credentials"},
+		},
+		DomainCredentials: map[string]domainCredentials{
+			"tenant.example": {
+				CredentialsSecretRef: &corev1.LocalObjectReference{Name: "tenant-credentials"},
+			},
+			"Shop.Tenant.Example.": {
+				ApplicationKey: "shop",
+				ApplicationSecretRef: corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "shop-credentials"},
+				},
+			},
+			"España.example": {
+				ApplicationKey: "shop",
+				ApplicationSecretRef: corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "shop-credentials"},
+				},
+			},
+		},
+	}
+	if err := solver.validate(cfg, false); err != nil {
+		t.Fatalf("validate() = %v", err)
+	}
+
+	tests := []struct {
+		fqdn      string
+		appKey    string
+		appSecret string
+	}{
+		{"_acme-challenge.example.com.", "default", "defaultpasswd"},
+		// Only whole labels match
+		{"_acme-challenge.othertenant.example.", "default", "defaultpasswd"},
+		{"_acme-challenge.tenant.example.", "tenant", "tenantpasswd"},
+		{"_acme-challenge.www.tenant.example.", "tenant", "tenantpasswd"},
+		// The longest domain wins
+		{"_acme-challenge.shop.tenant.example.", "shop", "shoppasswd"},
+		// The domains match the punycode names
+		{"_acme-challenge.www.xn--espaa-rta.example.", "shop", "shoppasswd"},
+	}
+	for _, tt := range tests {
+		ddClient, err := solver.ddClient(context.Background(), cfg.forName(tt.fqdn), "default")
+		if err != nil {
+			t.Errorf("%s: %v", tt.fqdn, err)
+			continue
+		}
+		if ddClient.AppKey != tt.appKey || ddClient.AppSecret != tt.appSecret {
+			t.Errorf("%s: credentials = %q/%q, want %q/%q", tt.fqdn, ddClient.AppKey, ddClient.AppSecret, tt.appKey, tt.appSecret)
+		}
+	}
+
+	cfg.DomainCredentials["invalid.example"] = domainCredentials{ApplicationKey: "invalid"}
+	if err := solver.validate(cfg, false); err == nil {
+		t.Error("validate() accepted domain credentials without secret")
+	}
+}
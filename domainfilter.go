@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errDomainNotAllowed is returned for the challenges of the names excluded by
+// the allowedDomains and deniedDomains of the issuer.
+var errDomainNotAllowed = errors.New("domain not allowed by the DonDominio config")
+
+// validateDomainPattern checks a pattern of allowedDomains or deniedDomains:
+// a domain, optionally prefixed with a "*." wildcard label.
+func validateDomainPattern(pattern string) error {
+	domain := strings.TrimPrefix(pattern, "*.")
+	if normalizeDomain(domain) == "" || strings.Contains(domain, "*") {
+		return errors.New("must be a domain optionally prefixed with *.")
+	}
+	return nil
+}
+
+// matchDomain reports whether name matches pattern: a pattern matches the
+// name it is equal to, and a "*." pattern any name under its domain.
+func matchDomain(pattern, name string) bool {
+	name = normalizeDomain(name)
+	domain := normalizeDomain(strings.TrimPrefix(pattern, "*."))
+	if name == "" || domain == "" {
+		return false
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(name, "."+domain)
+	}
+	return name == domain
+}
+
+// checkDomain fails with errDomainNotAllowed if the name of the challenge
+// record fqdn, without its _acme-challenge label, matches one of
+// deniedDomains, or none of allowedDomains if it is not empty.
+func (cfg *ddDNSProviderConfig) checkDomain(fqdn string) error {
+	name := strings.TrimPrefix(normalizeDomain(fqdn), acmeChallengeLabel+".")
+	for _, pattern := range cfg.DeniedDomains {
+		if matchDomain(pattern, name) {
+			return fmt.Errorf("%w: %s is denied by %q", errDomainNotAllowed, name, pattern)
+		}
+	}
+	if len(cfg.AllowedDomains) == 0 {
+		return nil
+	}
+	for _, pattern := range cfg.AllowedDomains {
+		if matchDomain(pattern, name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not in the allowed domains", errDomainNotAllowed, name)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckDomain(t *testing.T) {
+	cfg := &ddDNSProviderConfig{
+		AllowedDomains: []string{"example.com", "*.example.com", "example.org"},
+		DeniedDomains:  []string{"*.internal.example.com"},
+	}
+	tests := []struct {
+		fqdn    string
+		allowed bool
+	}{
+		{"_acme-challenge.example.com.", true},
+		{"_acme-challenge.WWW.Example.com.", true},
+		{"_acme-challenge.example.org.", true},
+		// Without wildcard, only the domain itself
+		{"_acme-challenge.www.example.org.", false},
+		{"_acme-challenge.example.net.", false},
+		{"_acme-challenge.notexample.com.", false},
+		{"_acme-challenge.internal.example.com.", true},
+		{"_acme-challenge.db.internal.example.com.", false},
+	}
+	for _, tt := range tests {
+		err := cfg.checkDomain(tt.fqdn)
+		if tt.allowed && err != nil {
+			t.Errorf("checkDomain(%s) = %v, want allowed", tt.fqdn, err)
+		}
+		if !tt.allowed && !errors.Is(err, errDomainNotAllowed) {
+			t.Errorf("checkDomain(%s) = %v, want %v", tt.fqdn, err, errDomainNotAllowed)
+		}
+	}
+
+	// The patterns match the punycode names of the challenges
+	cfg = &ddDNSProviderConfig{AllowedDomains: []string{"España.es", "*.bücher.example"}}
+	for _, fqdn := range []string{"_acme-challenge.xn--espaa-rta.es.", "_acme-challenge.www.xn--bcher-kva.example."} {
+		if err := cfg.checkDomain(fqdn); err != nil {
+			t.Errorf("checkDomain(%s) = %v, want allowed", fqdn, err)
+		}
+	}
+	if err := cfg.checkDomain("_acme-challenge.xn--bcher-kva.example."); !errors.Is(err, errDomainNotAllowed) {
+		t.Errorf("checkDomain(_acme-challenge.xn--bcher-kva.example.) = %v, want %v", err, errDomainNotAllowed)
+	}
+
+	for _, pattern := range []string{"", "*", "*.", "www.*.example.com"} {
+		if validateDomainPattern(pattern) == nil {
+			t.Errorf("validateDomainPattern(%q) accepted", pattern)
+		}
+	}
+}
+
+func TestSolverDeniedDomain(t *testing.T) {
+	provider := &fakeProvider{zones: map[string][]TXTRecord{"example.com": nil}}
+	solver := testSolver()
+	solver.provider = provider
+
+	ch := testChallenge(t, "http://dondominio.invalid", "_acme-challenge.example.com.", "example.com.", "challenge-key", map[string]interface{}{
+		"allowedDomains": []string{"example.org"},
+	})
+	if err := solver.Present(ch); !errors.Is(err, errDomainNotAllowed) {
+		t.Fatalf("Present() = %v, want %v", err, errDomainNotAllowed)
+	}
+	if provider.created != 0 || len(provider.zones["example.com"]) != 0 {
+		t.Errorf("records created in a domain not allowed: %+v", provider.zones)
+	}
+}
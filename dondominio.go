@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"os"
 	"strings"
@@ -14,6 +16,10 @@ import (
 	"time"
 
 	"github.com/gorilla/schema"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+	"k8s.io/klog/v2"
 )
 
 // DefaultTimeout api requests after 180s
@@ -24,6 +30,13 @@ const Endpoint = "https://simple-api.dondominio.net"
 
 var encoder = schema.NewEncoder()
 
+// mutatingPaths lists the API calls that modify the zones.
+var mutatingPaths = map[string]bool{
+	"/service/dnscreate": true,
+	"/service/dnsupdate": true,
+	"/service/dnsdelete": true,
+}
+
 // Client represents a client to call the DD API
 type Client struct {
 	// AppKey holds the Application key
@@ -32,6 +45,10 @@ type Client struct {
 	// AppSecret holds the Application secret key
 	AppSecret string
 
+	// Auth adds the credentials to the requests. Nil sends AppKey and
+	// AppSecret as form parameters.
+	Auth Authenticator
+
 	// API endpoint
 	endpoint string
 
@@ -41,42 +58,89 @@ type Client struct {
 	// Logger is used to log HTTP requests and responses.
 	Logger Logger
 
-	// Ensures that the timeDelta function is only ran once
-	// sync.Once would consider init done, even in case of error
-	// hence a good old flag
+	// timeDelta holds the last timeSample of the offset of the clock of
+	// the API
 	timeDelta atomic.Value
 
-	// Timeout configures the maximum duration to wait for an API requests to complete
+	// TimeDeltaTTL is the age after which TimeDelta measures the offset of
+	// the clock of the API again, if no response refreshed it meanwhile.
+	// Zero measures it only once.
+	TimeDeltaTTL time.Duration
+
+	// Timeout configures the maximum duration to wait for an API requests to complete.
+	// It is applied to the context of each request, never to the shared http.Client.
 	Timeout time.Duration
 
 	// UserAgent configures the user-agent indication that will be sent in the requests to DDcloud API
 	UserAgent string
+
+	// RetryPolicy configures how requests failing with a transient error are retried
+	RetryPolicy RetryPolicy
+
+	// ReadOnly disables the API calls that modify the zones, which are only logged
+	ReadOnly bool
+
+	// AdaptiveTimeout, if set, derives the timeout of each request from the
+	// latency observed on its path. Timeout still applies.
+	AdaptiveTimeout *AdaptiveTimeout
+
+	// Limiter, if set, bounds the number of requests in flight. The time
+	// spent waiting for it counts in Timeout but not in AdaptiveTimeout.
+	Limiter *RequestLimiter
+
+	// RateLimiter, if set, is the token bucket of the account, which every
+	// request waits for. It may be shared by the clients of the account.
+	RateLimiter *rate.Limiter
+	// Quota, if set, checks the API quota of the account before the requests
+	// and slows down its RateLimiter when it runs low. It may be shared by the
+	// clients of the account.
+	Quota *accountQuota
+
+	// StrictDecoding makes the responses with unexpected or missing fields
+	// fail with ErrResponseDrift instead of only logging a warning, e.g. to
+	// catch the API changes in CI.
+	StrictDecoding bool
+
+	// ExtraParams are added to the parameters of every request, e.g. the
+	// impersonation parameters of a reseller. They cannot override the
+	// credentials.
+	ExtraParams url.Values
+
+	// MaxResponseSize is the maximum size of a response body, once
+	// decompressed. Zero disables the limit.
+	MaxResponseSize int64
+
+	// ServiceCacheTTL is the time for which a zone found active by
+	// ValidateZone is not checked again. Zero disables the cache.
+	ServiceCacheTTL time.Duration
+
+	// services caches the zones found active
+	services serviceCache
+
+	// replayed is set once a replayed response has been detected, so that
+	// the client is no longer reused
+	replayed atomic.Bool
 }
 
 // NewClient represents a new client to call the API
 func NewClient(endpoint, appKey, appSecret string) (*Client, error) {
-	var httpClient http.Client
-	if proxyUrl, err := url.Parse(os.Getenv("PROXY")); err == nil {
-		//fmt.Printf("PROXY: %s\n", proxyUrl)
-		httpClient = http.Client{
-			Transport: &http.Transport{
-				Proxy: http.ProxyURL(proxyUrl),
-			},
-		}
-	} else {
-		httpClient = http.Client{}
-	}
+	httpClient := http.Client{Transport: sharedTransport}
 	client := Client{
-		AppKey:    appKey,
-		AppSecret: appSecret,
-		Client:    &httpClient,
-		Timeout:   time.Duration(DefaultTimeout),
+		AppKey:          appKey,
+		AppSecret:       appSecret,
+		Client:          &httpClient,
+		Logger:          requestLogger{Bodies: *debugHTTP || os.Getenv("DEBUG") != ""},
+		Timeout:         time.Duration(DefaultTimeout),
+		RetryPolicy:     DefaultRetryPolicy,
+		MaxResponseSize: DefaultMaxResponseSize,
+		TimeDeltaTTL:    DefaultTimeDeltaTTL,
 	}
 
 	// Get and check the configuration
 	if err := client.loadConfig(endpoint); err != nil {
 		return nil, err
 	}
+	registerSecret(client.AppSecret)
 	return &client, nil
 }
 
@@ -104,10 +168,17 @@ func (c *Client) Ping() error {
 	return err
 }
 
+// PingWithContext is Ping with a context.
+func (c *Client) PingWithContext(ctx context.Context) error {
+	var timestamp int64
+	return c.GetWithContext(ctx, "/auth/time", &timestamp)
+}
+
 // TimeDelta represents the delay between the machine that runs the code and the
-// DD API. The delay shouldn't change, let's do it only once.
+// DD API, positive when the local clock is ahead. It is refreshed by the
+// responses of the API and measured again once older than TimeDeltaTTL.
 func (c *Client) TimeDelta() (time.Duration, error) {
-	return c.getTimeDelta()
+	return c.getTimeDelta(context.Background())
 }
 
 // Time returns time from the DD API, by asking GET /auth/time.
@@ -159,24 +230,6 @@ func (c *Client) DeleteWithContext(ctx context.Context, url string, resType inte
 	return c.CallAPIWithContext(ctx, "DELETE", url, nil, resType)
 }
 
-// timeDelta returns the time delta between the host and the remote API
-func (c *Client) getTimeDelta() (time.Duration, error) {
-	d, ok := c.timeDelta.Load().(time.Duration)
-	if ok {
-		return d, nil
-	}
-
-	ddTime, err := c.getTime()
-	if err != nil {
-		return 0, err
-	}
-
-	d = time.Since(*ddTime)
-	c.timeDelta.Store(d)
-
-	return d, nil
-}
-
 // getTime t returns time from for a given api client endpoint
 func (c *Client) getTime() (*time.Time, error) {
 	var timestamp int64
@@ -202,23 +255,32 @@ func (c *Client) NewRequest(method, path string, reqBody interface{}) (*http.Req
 		}
 	}
 
-	body.Set("apiuser", c.AppKey)
-	body.Set("apipasswd", c.AppSecret)
-
-	fmt.Fprintf(os.Stdout, "url: %s, body %s\n", path, body.Encode())
+	for name, values := range c.ExtraParams {
+		body[name] = values
+	}
 
 	target := fmt.Sprintf("%s%s", c.endpoint, path)
+	auth := c.Auth
+	if auth == nil {
+		auth = FormAuthenticator{APIUser: c.AppKey, APIPassword: c.AppSecret}
+	}
+	authHeader, err := auth.Authenticate(method, target, body, c.serverNow())
+	if err != nil {
+		return nil, err
+	}
 	req, err := http.NewRequest(method, target, strings.NewReader(body.Encode()))
 	if err != nil {
 		return nil, err
 	}
+	for name, values := range authHeader {
+		req.Header[name] = values
+	}
 
 	// Inject headers
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded;charset=utf-8")
 	req.Header.Add("Accept", "application/json")
-
-	// Send the request with requested timeout
-	c.Client.Timeout = c.Timeout
+	// Decompressed by Do
+	req.Header.Set("Accept-Encoding", "gzip")
 
 	if c.UserAgent != "" {
 		req.Header.Set("User-Agent", "github.com/galgus/go-dd ("+c.UserAgent+")")
@@ -238,22 +300,18 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := decodeResponse(resp, c.MaxResponseSize); err != nil {
+		return nil, err
+	}
 	if c.Logger != nil {
 		c.Logger.LogResponse(resp)
 	}
 	return resp, nil
 }
 
-// CallAPI is the lowest level call helper. If needAuth is true,
-// inject authentication headers and sign the request.
-//
-// Request signature is a sha1 hash on following fields, joined by '+':
-// - applicationSecret (from Client instance)
-// - consumerKey (from Client instance)
-// - capitalized method (from arguments)
-// - full request url, including any query string argument
-// - full serialized request body
-// - server current time (takes time delta into account)
+// CallAPI is the lowest level call helper. The credentials are added to the
+// request by the Auth of the client: sent as form parameters by default, or
+// an HMAC signature of the request with HMACAuthenticator.
 //
 // Call will automatically assemble the target url from the endpoint
 // configured in the client instance and the path argument. If the reqBody
@@ -266,16 +324,9 @@ func (c *Client) CallAPI(method, path string, reqBody, resType interface{}) erro
 	return c.CallAPIWithContext(context.Background(), method, path, reqBody, resType)
 }
 
-// CallAPIWithContext is the lowest level call helper. If needAuth is true,
-// inject authentication headers and sign the request.
-//
-// Request signature is a sha1 hash on following fields, joined by '+':
-// - applicationSecret (from Client instance)
-// - consumerKey (from Client instance)
-// - capitalized method (from arguments)
-// - full request url, including any query string argument
-// - full serialized request body
-// - server current time (takes time delta into account)
+// CallAPIWithContext is the lowest level call helper. The credentials are
+// added to the request by the Auth of the client: sent as form parameters by
+// default, or an HMAC signature of the request with HMACAuthenticator.
 //
 // # Context is used by http.Client to handle context cancelation
 //
@@ -284,19 +335,120 @@ func (c *Client) CallAPI(method, path string, reqBody, resType interface{}) erro
 // argument is not nil, it will also serialize it as json and inject
 // the required Content-Type header.
 //
+// Requests failing with a transient error are retried according to the
+// RetryPolicy, except for the non idempotent ones. In ReadOnly mode, requests
+// modifying the zones are logged and not sent.
+//
 // If everything went fine, unmarshall response into resType and return nil
 // otherwise, return the error
-func (c *Client) CallAPIWithContext(ctx context.Context, method, path string, reqBody, resType interface{}) error {
+func (c *Client) CallAPIWithContext(ctx context.Context, method, path string, reqBody, resType interface{}) (err error) {
+	ctx, span := tracer.Start(ctx, "DonDominio "+path,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("dondominio.path", path)))
+	defer func() { endSpan(span, err) }()
+
+	if c.ReadOnly && mutatingPaths[path] {
+		params := url.Values{}
+		if reqBody != nil {
+			if err := encoder.Encode(reqBody, params); err != nil {
+				return err
+			}
+		}
+		klog.FromContext(ctx).Info("Read-only mode, skipping DonDominio API call", "method", method, "path", path, "params", params.Encode())
+		span.SetAttributes(attribute.Bool("dondominio.read_only", true))
+		return nil
+	}
+
+	if nonIdempotentPaths[path] {
+		return c.callAPI(ctx, method, path, reqBody, resType)
+	}
+	return c.Retry(ctx, func(attempt int) error {
+		span.SetAttributes(attribute.Int("dondominio.retries", attempt))
+		return c.callAPI(ctx, method, path, reqBody, resType)
+	})
+}
+
+// callAPI performs a single attempt of CallAPIWithContext.
+func (c *Client) callAPI(ctx context.Context, method, path string, reqBody, resType interface{}) error {
 	req, err := c.NewRequest(method, path, reqBody)
 	if err != nil {
 		return err
 	}
-	req = req.WithContext(ctx)
+	// The timeouts are applied to the context rather than to the shared
+	// http.Client, since a Client may be used concurrently.
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	if c.Quota != nil && path != accountInfoPath {
+		c.Quota.check(ctx, c)
+	}
+	if c.RateLimiter != nil {
+		if err := throttle(ctx, c.RateLimiter); err != nil {
+			return err
+		}
+	}
+	if c.Limiter != nil {
+		release, err := c.Limiter.acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+	if c.AdaptiveTimeout != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.AdaptiveTimeout.timeout(path))
+		defer cancel()
+	}
+	req = req.WithContext(httptrace.WithClientTrace(ctx, connectionTrace(path)))
+
+	start := time.Now()
 	response, err := c.Do(req)
+	if err == nil {
+		c.observeServerDate(response.Header.Get("Date"), start, time.Now())
+	}
+	if err == nil && mutatingPaths[path] {
+		err = c.checkReplay(ctx, method, path, reqBody, response)
+	}
+	if err == nil {
+		err = c.UnmarshalResponse(response, resType)
+		reportDeprecations(ctx, path, response.Header, resType)
+	}
+
+	latency := time.Since(start)
+	apiRequestDuration.WithLabelValues(path).Observe(latency.Seconds())
+	apiRequestsByCredential.WithLabelValues(c.CredentialFingerprint()).Inc()
+	if c.AdaptiveTimeout != nil && !errors.Is(ctx.Err(), context.Canceled) {
+		// Requests cancelled by the caller say nothing about the latency
+		c.AdaptiveTimeout.observe(path, latency)
+	}
+	return err
+}
+
+// checkReplay fails with ErrReplayedResponse if the query ID of the response
+// to a mutating call already answered another request, in which case the
+// response says nothing about the outcome of this one. The idle connections
+// are closed and the client stops being reused, so that the calls that
+// follow don't go through the same proxy connection.
+func (c *Client) checkReplay(ctx context.Context, method, path string, reqBody interface{}, response *http.Response) error {
+	fingerprint, err := requestFingerprint(method, path, reqBody)
 	if err != nil {
+		response.Body.Close()
 		return err
 	}
-	return c.UnmarshalResponse(response, resType)
+	queryID := response.Header.Get("X-Dd-QueryID")
+	previous, replayed := queryIDs.observe(queryID, fingerprint)
+	if !replayed {
+		return nil
+	}
+
+	response.Body.Close()
+	replayedResponses.WithLabelValues(path).Inc()
+	klog.FromContext(ctx).Info("Replayed DonDominio API response discarded, a proxy may be caching API calls", "queryID", queryID, "request", fingerprint, "answered", previous)
+	c.replayed.Store(true)
+	c.Client.CloseIdleConnections()
+	return fmt.Errorf("%w: query ID %s already answered %q", ErrReplayedResponse, queryID, previous)
 }
 
 // UnmarshalResponse checks the response and unmarshals it into the response
@@ -309,13 +461,26 @@ func (c *Client) UnmarshalResponse(response *http.Response, resType interface{})
 		return err
 	}
 
+	path := ""
+	if response.Request != nil && response.Request.URL != nil {
+		path = response.Request.URL.Path
+	}
+
 	// < 200 && >= 300 : API error
 	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
 		apiError := &APIError{Code: response.StatusCode}
 		if err = json.Unmarshal(body, apiError); err != nil {
 			apiError.Message = string(body)
+		} else if apiError.Message == "" {
+			// The DonDominio envelope, e.g. of the rate limited calls
+			var envelope ddResponse
+			if json.Unmarshal(body, &envelope) == nil {
+				apiError.Message = envelope.ErrorCodeMsg
+			}
 		}
+		apiError.Path = path
 		apiError.QueryID = response.Header.Get("X-Dd-QueryID")
+		apiError.RetryAfter = parseRetryAfter(response.Header.Get("Retry-After"), time.Now())
 
 		return apiError
 	}
@@ -325,9 +490,36 @@ func (c *Client) UnmarshalResponse(response *http.Response, resType interface{})
 		return nil
 	}
 
-	fmt.Printf("body: %s\n", string(body))
-
+	ctx := context.Background()
+	if response.Request != nil {
+		ctx = response.Request.Context()
+	}
 	d := json.NewDecoder(bytes.NewReader(body))
 	d.UseNumber()
-	return d.Decode(&resType)
+	if c.StrictDecoding {
+		d.DisallowUnknownFields()
+	}
+	if err = d.Decode(&resType); err != nil {
+		// encoding/json has no error type for the unknown fields
+		if c.StrictDecoding && strings.HasPrefix(err.Error(), "json: unknown field") {
+			return fmt.Errorf("%w from %s: %v", ErrResponseDrift, path, err)
+		}
+		return err
+	}
+
+	// DonDominio reports most errors with a 200 status and success=false
+	if r, ok := resType.(interface{ response() *ddResponse }); ok {
+		if envelope := r.response(); !envelope.Success {
+			return &APIError{
+				Code:       response.StatusCode,
+				ErrorCode:  envelope.ErrorCode,
+				Message:    envelope.ErrorCodeMsg,
+				Path:       path,
+				QueryID:    response.Header.Get("X-Dd-QueryID"),
+				RetryAfter: parseRetryAfter(response.Header.Get("Retry-After"), time.Now()),
+			}
+		}
+	}
+	// The error responses have no data to compare
+	return checkResponseDrift(ctx, path, body, resType, c.StrictDecoding)
 }
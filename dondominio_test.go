@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestClientTimeoutShared runs requests with different timeouts concurrently
+// on the same client: each one must get its own timeout, without touching
+// the shared http.Client (run with -race).
+func TestClientTimeoutShared(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("1700000000"))
+	}))
+	defer server.Close()
+
+	ddClient, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ddClient.RetryPolicy = RetryPolicy{MaxAttempts: 1}
+	ddClient.Timeout = time.Second
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := context.Background()
+			if i%2 == 1 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, 10*time.Millisecond)
+				defer cancel()
+			}
+			errs[i] = ddClient.PingWithContext(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if i%2 == 0 && err != nil {
+			t.Errorf("request %d failed: %v", i, err)
+		}
+		if i%2 == 1 && !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("request %d error = %v, want a deadline exceeded", i, err)
+		}
+	}
+	if ddClient.Client.Timeout != 0 {
+		t.Errorf("shared http.Client timeout set to %v", ddClient.Client.Timeout)
+	}
+}
+
+func TestModifyRecord(t *testing.T) {
+	var forms []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		forms = append(forms, r.PostForm)
+		if r.PostForm.Get("entityID") == "missing" {
+			w.Write([]byte(`{"success": false, "errorCode": 10002, "errorCodeMsg": "Entity not found"}`))
+			return
+		}
+		w.Write([]byte(`{"success": true, "responseData": {"dns": [{"entityID": "42", "name": "mail.example.com", "type": "MX", "ttl": "300", "priority": "5", "value": "mx.example.com"}]}}`))
+	}))
+	defer server.Close()
+
+	ddClient, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	priority := 5
+	record, err := ddClient.ModifyRecord(context.Background(), "example.com", "42", RecordUpdate{TTL: 5 * time.Minute, Priority: &priority})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.ID != "42" || record.Priority != 5 || record.TTL != 5*time.Minute || record.Value != "mx.example.com" {
+		t.Errorf("ModifyRecord() = %+v", record)
+	}
+	form := forms[0]
+	if form.Get("serviceName") != "example.com" || form.Get("ttl") != "300" || form.Get("priority") != "5" || form.Has("value") {
+		t.Errorf("dnsupdate params = %v, want the TTL and priority only", form)
+	}
+
+	_, err = ddClient.UpdateTXT(context.Background(), "example.com", "missing", "challenge-key", 0)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("UpdateTXT() of a missing record error = %v, want %v", err, ErrRecordNotFound)
+	}
+	if form := forms[len(forms)-1]; form.Get("value") != "challenge-key" || form.Has("ttl") || form.Has("priority") {
+		t.Errorf("dnsupdate params = %v, want the value only", form)
+	}
+}
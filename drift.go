@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+)
+
+var responseDrifts = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "response_drifts_total",
+	Help:      "Number of DonDominio API responses with unexpected or missing fields, by path.",
+}, []string{"path"})
+
+func init() {
+	metricsRegistry.MustRegister(responseDrifts)
+}
+
+// ErrResponseDrift is returned by the clients in StrictDecoding mode when a
+// response has fields the webhook does not know, or lacks fields it expects.
+var ErrResponseDrift = errors.New("unexpected response structure")
+
+// responseDrift compares the JSON body of a response to the type it is
+// decoded into, and returns the paths of the fields of the body the type
+// has no field for, and of the fields of the type, other than omitempty
+// ones, missing from the body. The types that are not structs have no
+// expected structure.
+func responseDrift(body []byte, resType interface{}) (unexpected, missing []string) {
+	t := reflect.TypeOf(resType)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil, nil
+	}
+	for _, err := range unknownFields(nil, value, t) {
+		unexpected = append(unexpected, err.Field)
+	}
+	return unexpected, missingFields("", value, t)
+}
+
+// missingFields returns the paths of the fields of t, other than omitempty
+// ones, missing from the decoded JSON value, at any depth.
+func missingFields(path string, value interface{}, t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return nil
+	}
+
+	var missing []string
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if t.Kind() != reflect.Struct {
+			break
+		}
+		keys := make(map[string]interface{}, len(value))
+		for key, v := range value {
+			keys[strings.ToLower(key)] = v
+		}
+		fields := jsonFields(t)
+		for _, name := range sortedFieldNames(fields) {
+			f := fields[name]
+			jsonName, options, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if jsonName == "" {
+				jsonName = f.Name
+			}
+			fieldPath := jsonName
+			if path != "" {
+				fieldPath = path + "." + jsonName
+			}
+			v, ok := keys[name]
+			if !ok {
+				if !strings.Contains(","+options+",", ",omitempty,") {
+					missing = append(missing, fieldPath)
+				}
+				continue
+			}
+			missing = append(missing, missingFields(fieldPath, v, f.Type)...)
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice {
+			break
+		}
+		for i, item := range value {
+			missing = append(missing, missingFields(fmt.Sprintf("%s[%d]", path, i), item, t.Elem())...)
+		}
+	}
+	return missing
+}
+
+func sortedFieldNames(fields map[string]reflect.StructField) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkResponseDrift fails with ErrResponseDrift if strict, and otherwise
+// warns once per path and drift, when the body of the response to path
+// does not match resType.
+func checkResponseDrift(ctx context.Context, path string, body []byte, resType interface{}, strict bool) error {
+	unexpected, missing := responseDrift(body, resType)
+	if len(unexpected) == 0 && len(missing) == 0 {
+		return nil
+	}
+	responseDrifts.WithLabelValues(path).Inc()
+	if strict {
+		return fmt.Errorf("%w from %s: unexpected fields %v, missing fields %v", ErrResponseDrift, path, unexpected, missing)
+	}
+	key := fmt.Sprintf("drift\x00%s\x00%v\x00%v", path, unexpected, missing)
+	if _, logged := loggedWarnings.LoadOrStore(key, true); !logged {
+		klog.FromContext(ctx).Info("DonDominio API response structure changed, the webhook may need an update",
+			"path", path, "unexpectedFields", unexpected, "missingFields", missing)
+	}
+	return nil
+}
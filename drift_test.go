@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const driftedServiceInfo = `{"success": true, "errorCode": 0, "errorCodeMsg": "", "action": "service/getinfo", "version": "1.0.20",
+	"responseData": {"name": "example.com", "type": "domain", "productkey": "dns", "status": "active", "tsExpir": "", "tsCreate": "", "renewable": true, "plan": "basic"}}`
+
+func TestResponseDrift(t *testing.T) {
+	unexpected, missing := responseDrift([]byte(driftedServiceInfo), &ddServiceInfo{})
+	if strings.Join(unexpected, " ") != "responseData.plan" {
+		t.Errorf("unexpected fields = %v, want [responseData.plan]", unexpected)
+	}
+	if strings.Join(missing, " ") != "responseData.renewalMode" {
+		t.Errorf("missing fields = %v, want [responseData.renewalMode]", missing)
+	}
+
+	// The calls ignoring the data don't report it
+	if unexpected, missing := responseDrift([]byte(driftedServiceInfo), &ddResponse{}); len(unexpected) != 0 || len(missing) != 0 {
+		t.Errorf("responseDrift() = %v, %v for ddResponse", unexpected, missing)
+	}
+}
+
+func TestStrictDecoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(driftedServiceInfo))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info ddServiceInfo
+	if err := client.PostWithContext(context.Background(), "/test/drift", nil, &info); err != nil {
+		t.Fatalf("PostWithContext() = %v, want only a warning", err)
+	}
+	if info.ResponseData.Status != "active" {
+		t.Errorf("responseData = %+v", info.ResponseData)
+	}
+
+	client.StrictDecoding = true
+	if err := client.PostWithContext(context.Background(), "/test/drift", nil, &ddServiceInfo{}); !errors.Is(err, ErrResponseDrift) {
+		t.Errorf("PostWithContext() = %v, want %v", err, ErrResponseDrift)
+	}
+}
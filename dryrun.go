@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// dryRunProvider performs the lookups of its provider but only logs the
+// record creations, updates and deletions, so that a new issuer config can
+// be validated without modifying its zones.
+type dryRunProvider struct {
+	DNSProvider
+}
+
+// CreateTXT implements DNSProvider. The record returned has no ID.
+func (p dryRunProvider) CreateTXT(ctx context.Context, zone, name, value string, ttl time.Duration) (TXTRecord, error) {
+	klog.FromContext(ctx).Info("Dry run, not creating TXT record", "zone", zone, "record", name, "value", value, "ttl", ttl)
+	return TXTRecord{Name: name, Value: value}, nil
+}
+
+// UpdateTXT implements DNSProvider.
+func (p dryRunProvider) UpdateTXT(ctx context.Context, zone, id, value string, ttl time.Duration) (TXTRecord, error) {
+	klog.FromContext(ctx).Info("Dry run, not updating TXT record", "zone", zone, "entityID", id, "value", value, "ttl", ttl)
+	return TXTRecord{ID: id, Value: value}, nil
+}
+
+// DeleteTXT implements DNSProvider.
+func (p dryRunProvider) DeleteTXT(ctx context.Context, zone, id string) error {
+	klog.FromContext(ctx).Info("Dry run, not deleting TXT record", "zone", zone, "entityID", id)
+	return nil
+}
+
+// ListRecords implements recordManager, if the provider does.
+func (p dryRunProvider) ListRecords(ctx context.Context, zone string, filter RecordFilter) ([]Record, error) {
+	manager, ok := p.DNSProvider.(recordManager)
+	if !ok {
+		return nil, errRecordTypesNotSupported
+	}
+	return manager.ListRecords(ctx, zone, filter)
+}
+
+// CreateRecord implements recordManager.
+func (p dryRunProvider) CreateRecord(ctx context.Context, zone string, params RecordParams) (Record, error) {
+	klog.FromContext(ctx).Info("Dry run, not creating record", "zone", zone, "record", params.Name, "type", params.Type, "value", params.Value)
+	return Record{Name: params.Name, Type: params.Type, Value: params.Value}, nil
+}
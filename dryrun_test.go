@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, []Dns{
+		{Name: "_acme-challenge.example.com", Type: "TXT", Value: "stale-key"},
+	})
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	solver := testSolver()
+	config := map[string]interface{}{"dryRun": true, "waitForPropagation": true}
+	ch := testChallenge(t, server.URL, "_acme-challenge.example.com.", "example.com.", "challenge-key", config)
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("Present() error: %v", err)
+	}
+	stale := testChallenge(t, server.URL, "_acme-challenge.example.com.", "example.com.", "stale-key", config)
+	if err := solver.CleanUp(stale); err != nil {
+		t.Fatalf("CleanUp() error: %v", err)
+	}
+
+	actions, records := api.result()
+	for _, action := range actions {
+		if action == "dnscreate" || action == "dnsdelete" {
+			t.Errorf("dry run called %s, actions = %q", action, actions)
+		}
+	}
+	if len(records) != 1 {
+		t.Errorf("dry run left %d records, want 1", len(records))
+	}
+	if len(solver.ledger.snapshot().Records) != 0 {
+		t.Error("dry run recorded a presented record")
+	}
+}
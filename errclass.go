@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Classes of the errors returned to cert-manager, which retries every failed
+// challenge with a backoff: the class tells from the status of the Challenge
+// whether waiting is enough or the issuer, the secret or the account must be
+// fixed. Errors can be matched against them with errors.Is.
+var (
+	// ErrTransient is matched by the failures expected to go away by
+	// themselves: network errors, server errors and rate limits.
+	ErrTransient = errors.New("transient failure, will be retried")
+	// ErrPermanent is matched by the failures that retrying won't fix:
+	// invalid credentials or config, zones not managed by the account.
+	ErrPermanent = errors.New("permanent failure, retrying won't help until it is fixed")
+)
+
+// errorClasses match the failures to their class. The permanent failures are
+// checked first, so that e.g. a rejected password is not mistaken for a
+// network error.
+var errorClasses = []struct {
+	class error
+	match func(err error) bool
+}{
+	{ErrPermanent, isPermanent},
+	{ErrTransient, isTransient},
+}
+
+// isPermanent reports whether err cannot be fixed by retrying.
+func isPermanent(err error) bool {
+	for _, target := range []error{
+		ErrAuthFailed, ErrServiceNotActive, ErrQuotaExceeded, ErrResponseDrift,
+		errInvalidConfig, errDomainNotAllowed, errSecretNamespaceNotAllowed, errRecordTypesNotSupported,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	// The secrets that are missing or cannot be read
+	return apierrors.IsNotFound(err) || apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err)
+}
+
+// isTransient reports whether err is expected to go away by itself. Unlike
+// isRetryable, it includes the failures that are not worth retrying in the
+// same call but are by cert-manager, e.g. a connection refused.
+func isTransient(err error) bool {
+	if isRetryable(err) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrResponseTooLarge) {
+		return true
+	}
+	if apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) || apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) || apierrors.IsInternalError(err) {
+		return true
+	}
+	var netError net.Error
+	return errors.As(err, &netError)
+}
+
+// classifiedError is an error prefixed with its class.
+type classifiedError struct {
+	err   error
+	class error
+}
+
+func (e *classifiedError) Error() string {
+	return e.class.Error() + ": " + e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+func (e *classifiedError) Is(target error) bool {
+	return target == e.class
+}
+
+// classify prefixes err with its class, if known. It is applied once, to the
+// errors returned to cert-manager.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, c := range errorClasses {
+		if c.match(err) {
+			return &classifiedError{err: err, class: c.class}
+		}
+	}
+	return err
+}
+
+// errorClass returns the name of the class of err, for the metrics and the
+// logs: transient, permanent or unknown.
+func errorClass(err error) string {
+	switch {
+	case errors.Is(err, ErrPermanent):
+		return "permanent"
+	case errors.Is(err, ErrTransient):
+		return "transient"
+	}
+	return "unknown"
+}
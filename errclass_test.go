@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err   error
+		class string
+	}{
+		{&APIError{Code: http.StatusOK, ErrorCode: ddErrLoginInvalid}, "permanent"},
+		{&APIError{Code: http.StatusForbidden}, "permanent"},
+		{fmt.Errorf("DonDominio service not deployed for domain example.com: %w", ErrServiceNotActive), "permanent"},
+		{configError(field.ErrorList{field.Required(field.NewPath("zone"), "")}), "permanent"},
+		{fmt.Errorf("failed to read the secret: %w", apierrors.NewNotFound(corev1.Resource("secrets"), "dd-credentials")), "permanent"},
+		{&APIError{Code: http.StatusBadGateway}, "transient"},
+		{&APIError{Code: http.StatusOK, ErrorCode: ddErrTooManyRequests}, "transient"},
+		{fmt.Errorf("request failed: %w", context.DeadlineExceeded), "transient"},
+		{fmt.Errorf("request failed: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}), "transient"},
+		{errors.New("TXT record already exists"), "unknown"},
+	}
+	for _, tt := range tests {
+		err := classify(withHint(tt.err))
+		if got := errorClass(err); got != tt.class {
+			t.Errorf("errorClass(%v) = %s, want %s", tt.err, got, tt.class)
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("classify(%v) does not wrap the error", tt.err)
+		}
+		if tt.class != "unknown" && !strings.HasPrefix(err.Error(), tt.class+" failure") {
+			t.Errorf("classify(%v) = %q, want the class first", tt.err, err)
+		}
+	}
+
+	if classify(nil) != nil {
+		t.Error("classify(nil) != nil")
+	}
+	if !errors.Is(classify(&APIError{Code: http.StatusUnauthorized}), ErrAuthFailed) {
+		t.Error("the failure class of a classified error is lost")
+	}
+}
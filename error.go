@@ -1,25 +1,145 @@
 package main
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Sentinel errors for the failure classes of the DonDominio API. Errors
+// returned by the client can be matched against them with errors.Is.
+var (
+	// ErrAuthFailed is returned when the API user or password are rejected or
+	// the account is not allowed to use the API.
+	ErrAuthFailed = errors.New("authentication failed")
+	// ErrServiceNotActive is returned when the domain is not a DonDominio
+	// service or the service is not active.
+	ErrServiceNotActive = errors.New("service not active")
+	// ErrRecordNotFound is returned when a DNS record does not exist.
+	ErrRecordNotFound = errors.New("record not found")
+	// ErrRateLimited is returned when too many requests have been sent. It is
+	// transient and the request may be retried later.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrQuotaExceeded is returned when the account or the service has reached
+	// one of its limits, e.g. the maximum number of DNS records.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrReplayedResponse is returned when the response to a call modifying
+	// a zone carries the query ID of another request, e.g. because of a buggy
+	// proxy. It is transient and the request may be retried.
+	ErrReplayedResponse = errors.New("replayed response")
+	// ErrResponseTooLarge is returned when the body of a response exceeds
+	// the MaxResponseSize of the client, e.g. because a proxy answered with
+	// something else than the API.
+	ErrResponseTooLarge = errors.New("response too large")
+)
+
+// DonDominio errorCode values, as documented in the API reference.
+const (
+	ddErrLoginRequired       = 1000
+	ddErrLoginInvalid        = 1001
+	ddErrSessionInvalid      = 1002
+	ddErrActionNotAllowed    = 1003
+	ddErrTooManyRequests     = 1004
+	ddErrAccountBlocked      = 2000
+	ddErrAccountBanned       = 2015
+	ddErrInsufficientBalance = 3001
+	ddErrServiceNotFound     = 10001
+	ddErrEntityNotFound      = 10002
+	ddErrEntityLimitReached  = 10003
+)
 
 // APIError represents an error that can occurred while calling the API.
 type APIError struct {
 	// Error class
 	Class string `json:"class,omitempty"`
-	// Error message.
+	// Error message, the errorCodeMsg of the DonDominio errors.
 	Message string `json:"message"`
 	// Error details
 	Details map[string]string `json:"details,omitempty"`
 	// HTTP code.
-	Code int
-	// ID of the request
-	QueryID string
+	Code int `json:"-"`
+	// DonDominio error code, zero if the error is not reported by the API.
+	ErrorCode int64 `json:"errorCode,omitempty"`
+	// Path of the API call, e.g. /service/dnscreate, empty if unknown.
+	Path string `json:"-"`
+	// ID of the request, from the X-Dd-QueryID header.
+	QueryID string `json:"-"`
+	// Delay requested by the Retry-After header, zero if it is missing.
+	RetryAfter time.Duration `json:"-"`
 }
 
 func (err *APIError) Error() string {
-	if err.Class == "" {
-		return fmt.Sprintf("HTTP Error %d: %q", err.Code, err.Message)
+	var msg string
+	switch {
+	case err.ErrorCode != 0:
+		msg = fmt.Sprintf("DonDominio Error %d: %q", err.ErrorCode, err.Message)
+	case err.Class == "":
+		msg = fmt.Sprintf("HTTP Error %d: %q", err.Code, err.Message)
+	default:
+		msg = fmt.Sprintf("HTTP Error %d: %s: %q", err.Code, err.Class, err.Message)
 	}
 
-	return fmt.Sprintf("HTTP Error %d: %s: %q (X-DD-Query-Id: %s)", err.Code, err.Class, err.Message, err.QueryID)
+	var context []string
+	if err.Path != "" {
+		context = append(context, err.Path)
+	}
+	if err.ErrorCode != 0 && err.Code != 0 {
+		context = append(context, fmt.Sprintf("HTTP %d", err.Code))
+	}
+	if err.QueryID != "" {
+		context = append(context, "X-Dd-QueryID: "+err.QueryID)
+	}
+	if len(context) > 0 {
+		msg += " (" + strings.Join(context, ", ") + ")"
+	}
+	return msg
+}
+
+// Unwrap returns the sentinel error of the class of the error, or nil if it
+// does not belong to any known class.
+func (err *APIError) Unwrap() error {
+	return err.sentinel()
+}
+
+// Is reports whether the error belongs to the class of the target sentinel
+// error, so that errors.Is(err, ErrAuthFailed) works on API errors. An
+// *APIError target matches the errors with its non-zero ErrorCode and Code,
+// e.g. errors.Is(err, &APIError{ErrorCode: 10002}).
+func (err *APIError) Is(target error) bool {
+	if t, ok := target.(*APIError); ok {
+		return (t.ErrorCode != 0 || t.Code != 0) &&
+			(t.ErrorCode == 0 || t.ErrorCode == err.ErrorCode) &&
+			(t.Code == 0 || t.Code == err.Code)
+	}
+	return target != nil && err.sentinel() == target
+}
+
+// sentinel maps the HTTP status and the DonDominio error code to one of the
+// sentinel errors, or nil if the error does not belong to any known class.
+func (err *APIError) sentinel() error {
+	switch err.ErrorCode {
+	case ddErrLoginRequired, ddErrLoginInvalid, ddErrSessionInvalid, ddErrActionNotAllowed:
+		return ErrAuthFailed
+	case ddErrTooManyRequests:
+		return ErrRateLimited
+	case ddErrInsufficientBalance, ddErrEntityLimitReached:
+		return ErrQuotaExceeded
+	case ddErrServiceNotFound:
+		return ErrServiceNotActive
+	case ddErrEntityNotFound:
+		return ErrRecordNotFound
+	}
+	if err.ErrorCode >= ddErrAccountBlocked && err.ErrorCode <= ddErrAccountBanned {
+		return ErrAuthFailed
+	}
+
+	switch err.Code {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAuthFailed
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	}
+	return nil
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	tests := []struct {
+		err    *APIError
+		target error
+	}{
+		{&APIError{Code: http.StatusOK, ErrorCode: ddErrLoginInvalid}, ErrAuthFailed},
+		{&APIError{Code: http.StatusOK, ErrorCode: 2007}, ErrAuthFailed},
+		{&APIError{Code: http.StatusForbidden}, ErrAuthFailed},
+		{&APIError{Code: http.StatusOK, ErrorCode: ddErrServiceNotFound}, ErrServiceNotActive},
+		{&APIError{Code: http.StatusOK, ErrorCode: ddErrEntityNotFound}, ErrRecordNotFound},
+		{&APIError{Code: http.StatusOK, ErrorCode: ddErrTooManyRequests}, ErrRateLimited},
+		{&APIError{Code: http.StatusTooManyRequests}, ErrRateLimited},
+		{&APIError{Code: http.StatusOK, ErrorCode: ddErrEntityLimitReached}, ErrQuotaExceeded},
+	}
+
+	for _, tt := range tests {
+		wrapped := fmt.Errorf("DonDominio API call failed: POST /service/dnscreate - %w", tt.err)
+		if !errors.Is(wrapped, tt.target) {
+			t.Errorf("errors.Is(%v, %v) = false, want true", wrapped, tt.target)
+		}
+		var apiError *APIError
+		if !errors.As(wrapped, &apiError) || apiError != tt.err {
+			t.Errorf("errors.As(%v) did not return the APIError", wrapped)
+		}
+	}
+
+	unknown := &APIError{Code: http.StatusOK, ErrorCode: 1}
+	for _, target := range []error{ErrAuthFailed, ErrServiceNotActive, ErrRecordNotFound, ErrRateLimited, ErrQuotaExceeded} {
+		if errors.Is(unknown, target) {
+			t.Errorf("errors.Is(%v, %v) = true, want false", unknown, target)
+		}
+	}
+}
+
+func TestAPIErrorDetails(t *testing.T) {
+	err := &APIError{Code: http.StatusOK, ErrorCode: ddErrEntityNotFound, Message: "Entity not found", Path: "/service/dnsdelete", QueryID: "q1"}
+	want := `DonDominio Error 10002: "Entity not found" (/service/dnsdelete, HTTP 200, X-Dd-QueryID: q1)`
+	if err.Error() != want {
+		t.Errorf("Error() = %s, want %s", err, want)
+	}
+	if errors.Unwrap(err) != ErrRecordNotFound {
+		t.Errorf("Unwrap() = %v, want %v", errors.Unwrap(err), ErrRecordNotFound)
+	}
+	if !errors.Is(err, &APIError{ErrorCode: ddErrEntityNotFound}) || errors.Is(err, &APIError{ErrorCode: ddErrServiceNotFound}) {
+		t.Error("an *APIError target is not matched by its error code")
+	}
+	if errors.Is(err, &APIError{}) {
+		t.Error("an empty *APIError target matches any error")
+	}
+
+	// The errors of the HTTP status have the envelope of DonDominio
+	response := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"X-Dd-Queryid": {"q2"}},
+		Body:       io.NopCloser(strings.NewReader(`{"success": false, "errorCode": 1004, "errorCodeMsg": "Too many requests"}`)),
+		Request:    httptest.NewRequest("POST", "https://simple-api.dondominio.net/service/dnscreate", nil),
+	}
+	var apiError *APIError
+	if !errors.As((&Client{}).UnmarshalResponse(response, nil), &apiError) {
+		t.Fatal("UnmarshalResponse() did not return an APIError")
+	}
+	if apiError.ErrorCode != ddErrTooManyRequests || apiError.Message != "Too many requests" || apiError.Path != "/service/dnscreate" || apiError.QueryID != "q2" {
+		t.Errorf("APIError = %+v", apiError)
+	}
+}
+
+func TestWithHint(t *testing.T) {
+	tests := []struct {
+		err  error
+		hint string
+	}{
+		{&APIError{Code: http.StatusOK, ErrorCode: ddErrActionNotAllowed}, "DNS management permissions"},
+		{&APIError{Code: http.StatusOK, ErrorCode: ddErrLoginInvalid}, "egress IP of the cluster"},
+		{fmt.Errorf("DonDominio service not deployed for domain example.com: %w", ErrServiceNotActive), "DNS service is active"},
+		{fmt.Errorf("request failed: %w", context.DeadlineExceeded), "requestTimeout"},
+	}
+	for _, tt := range tests {
+		err := withHint(tt.err)
+		if !strings.HasPrefix(err.Error(), tt.err.Error()+" (hint: ") || !strings.Contains(err.Error(), tt.hint) {
+			t.Errorf("withHint(%v) = %q, want a hint containing %q", tt.err, err, tt.hint)
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("withHint(%v) does not wrap the error", tt.err)
+		}
+	}
+
+	plain := errors.New("TXT record already exists")
+	if err := withHint(plain); err != plain {
+		t.Errorf("withHint(%v) = %q, want the error unchanged", plain, err)
+	}
+	if err := withHint(nil); err != nil {
+		t.Errorf("withHint(nil) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var apiRequestsByCredential = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "api_requests_by_credential_total",
+	Help:      "Number of DonDominio API requests, by fingerprint of the credentials they were made with.",
+}, []string{"credential"})
+
+func init() {
+	metricsRegistry.MustRegister(apiRequestsByCredential)
+}
+
+// credentialFingerprintLength is the number of hex digits of a credential
+// fingerprint: enough to tell the credentials of the issuers apart, too few
+// to help guessing the secret.
+const credentialFingerprintLength = 16
+
+// credentialFingerprint returns the fingerprint of the application key and
+// secret, a truncated SHA-256 of both. It is stable across restarts and
+// replicas, so it can be logged and used as a metric label in their place.
+func credentialFingerprint(appKey, appSecret string) string {
+	sum := sha256.Sum256([]byte(appKey + "\x00" + appSecret))
+	return hex.EncodeToString(sum[:])[:credentialFingerprintLength]
+}
+
+// CredentialFingerprint returns the fingerprint of the credentials of the
+// client, which identifies them in the logs and metrics without revealing
+// them.
+func (c *Client) CredentialFingerprint() string {
+	return credentialFingerprint(c.AppKey, c.AppSecret)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCredentialFingerprint(t *testing.T) {
+	ddClient, err := NewClient("https://simple-api.dondominio.net", "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := ddClient.CredentialFingerprint()
+	if len(fingerprint) != credentialFingerprintLength || strings.Contains(fingerprint, "apipasswd") {
+		t.Errorf("fingerprint = %q, want %d hex digits", fingerprint, credentialFingerprintLength)
+	}
+	if again := credentialFingerprint("apiuser", "apipasswd"); again != fingerprint {
+		t.Errorf("fingerprint = %q then %q, want it stable", fingerprint, again)
+	}
+	for _, other := range [][2]string{{"apiuser", "other"}, {"other", "apipasswd"}, {"apiuserapi", "passwd"}} {
+		if credentialFingerprint(other[0], other[1]) == fingerprint {
+			t.Errorf("credentials %q share the fingerprint of apiuser/apipasswd", other)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+)
+
+// fixture describes a Present or CleanUp scenario: the initial state of the
+// DonDominio account, the challenge request and the expected outcome.
+type fixture struct {
+	Name  string `json:"name"`
+	State struct {
+		// Services maps the domains of the account to their status
+		Services map[string]string `json:"services"`
+		Records  []Dns             `json:"records"`
+		// Failures maps the actions, e.g. dnslist, to the error code they
+		// fail with
+		Failures map[string]int64 `json:"failures"`
+		// PageLength is the number of records per dnslist page when the
+		// request does not set it, defaultPageLength if zero
+		PageLength int `json:"pageLength"`
+	} `json:"state"`
+	Request struct {
+		// Action is either present or cleanup
+		Action string                 `json:"action"`
+		FQDN   string                 `json:"fqdn"`
+		Zone   string                 `json:"zone"`
+		Key    string                 `json:"key"`
+		Config map[string]interface{} `json:"config"`
+	} `json:"request"`
+	Expect struct {
+		// Error is a substring of the expected error, empty for success
+		Error string `json:"error"`
+		// Actions are the API calls made, e.g. dnslist
+		Actions []string `json:"actions"`
+		// Records is the final state of the records, entity IDs are ignored
+		Records []Dns `json:"records"`
+	} `json:"expect"`
+}
+
+func TestFixtures(t *testing.T) {
+	paths, err := filepath.Glob("testdata/fixtures/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var f fixture
+		if err := yaml.UnmarshalStrict(data, &f); err != nil {
+			t.Fatalf("%s: %v", p, err)
+		}
+		t.Run(f.Name, func(t *testing.T) {
+			f.run(t)
+		})
+	}
+}
+
+func (f *fixture) run(t *testing.T) {
+	api := newFixtureAPI(f.State.Services, f.State.Records)
+	api.failures = f.State.Failures
+	if f.State.PageLength > 0 {
+		api.pageLength = f.State.PageLength
+	}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	solver := testSolver()
+	ch := testChallenge(t, server.URL, f.Request.FQDN, f.Request.Zone, f.Request.Key, f.Request.Config)
+
+	var err error
+	switch f.Request.Action {
+	case "present":
+		err = solver.Present(ch)
+	case "cleanup":
+		err = solver.CleanUp(ch)
+	default:
+		t.Fatalf("unknown action %q", f.Request.Action)
+	}
+
+	switch {
+	case f.Expect.Error == "" && err != nil:
+		t.Errorf("unexpected error: %v", err)
+	case f.Expect.Error != "" && err == nil:
+		t.Errorf("expected error %q, got none", f.Expect.Error)
+	case f.Expect.Error != "" && !strings.Contains(err.Error(), f.Expect.Error):
+		t.Errorf("expected error %q, got %v", f.Expect.Error, err)
+	}
+
+	actions, records := api.result()
+	if !reflect.DeepEqual(actions, f.Expect.Actions) {
+		t.Errorf("actions = %q, want %q", actions, f.Expect.Actions)
+	}
+	if !reflect.DeepEqual(records, f.Expect.Records) {
+		t.Errorf("records = %+v, want %+v", records, f.Expect.Records)
+	}
+}
+
+// testSolver returns a solver whose Kubernetes client holds the credentials
+// accepted by fixtureAPI.
+func testSolver(hooks ...Hooks) *ddDNSProviderSolver {
+	solver := newSolver(hooks...)
+	solver.client = fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "dd-credentials", Namespace: "default"},
+		Data:       map[string][]byte{"applicationSecret": []byte("apipasswd")},
+	})
+	return solver
+}
+
+// testChallenge returns a challenge request for the fixtureAPI served at
+// endpoint, with additional issuer config fields.
+func testChallenge(t *testing.T, endpoint, fqdn, zone, key string, extraConfig map[string]interface{}) *v1alpha1.ChallengeRequest {
+	t.Helper()
+
+	config := map[string]interface{}{
+		"endpoint":       endpoint,
+		"applicationKey": "apiuser",
+		"applicationSecretRef": map[string]string{
+			"name": "dd-credentials",
+			"key":  "applicationSecret",
+		},
+	}
+	for k, v := range extraConfig {
+		config[k] = v
+	}
+	raw, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:      fqdn,
+		ResolvedZone:      zone,
+		Key:               key,
+		ResourceNamespace: "default",
+		Config:            &extapi.JSON{Raw: raw},
+	}
+}
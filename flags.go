@@ -0,0 +1,286 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+)
+
+// Flags are registered on flag.CommandLine, which the webhook server command
+// merges into its own flag set before parsing the command line. Their values
+// are therefore only available once Initialize has been called.
+var (
+	// The solvers and group-name flags are registered for the command line
+	// to accept them, but the serve command reads them before the flags are
+	// parsed.
+	solversFlag = flag.String("solvers", "",
+		"Comma separated list of the solvers served, among the registered ones. Empty serves all of them.")
+	_ = flag.String("group-name", "",
+		"API group under which the solvers are served, which must match the groupName of the issuers. Defaults to the GROUP_NAME environment variable, or else to the group of the APIService registered for the webhook.")
+
+	requestTimeout = flag.Duration("request-timeout", DefaultRequestTimeout,
+		"Default maximum time spent calling DonDominio for a single Present or CleanUp, overridden by the requestTimeout field of the issuer config.")
+
+	recordTTLFlag = flag.Duration("record-ttl", 0,
+		"Default TTL of the challenge records, overridden by the recordTTL field of the issuer config. Zero uses the default TTL of the zone.")
+
+	waitForPropagationFlag = flag.Bool("wait-for-propagation", false,
+		"Wait for the challenge record to be visible on the authoritative nameservers before Present returns, overridden by the waitForPropagation field of the issuer config.")
+	propagationInterval = flag.Duration("propagation-interval", DefaultPropagationInterval,
+		"Default interval between two DNS queries when waiting for propagation, overridden by the propagationInterval field of the issuer config.")
+	propagationTimeout = flag.Duration("propagation-timeout", DefaultPropagationTimeout,
+		"Default maximum time to wait for propagation, overridden by the propagationTimeout field of the issuer config.")
+	propagationTTLMargin = flag.Duration("propagation-ttl-margin", DefaultPropagationTTLMargin,
+		"Margin added to the recordTTL of the issuer config to get the default propagation timeout of its challenges.")
+	propagationWorkers = flag.Int("propagation-workers", 8,
+		"Maximum number of propagation DNS checks run at once. A zone uses at most half of them.")
+	propagationResolvers = flag.String("propagation-resolvers", "",
+		"Default comma separated list of recursive resolvers also queried when waiting for propagation, overridden by the propagationResolvers field of the issuer config.")
+
+	adaptiveTimeout = flag.Bool("adaptive-timeout", false,
+		"Derive the timeout of each DonDominio API request from the latency observed on the same endpoint.")
+	adaptiveTimeoutMin = flag.Duration("adaptive-timeout-min", 5*time.Second,
+		"Lowest timeout of a DonDominio API request when --adaptive-timeout is set.")
+	adaptiveTimeoutMax = flag.Duration("adaptive-timeout-max", DefaultTimeout,
+		"Highest timeout of a DonDominio API request when --adaptive-timeout is set.")
+
+	serviceCacheTTL = flag.Duration("service-cache-ttl", 10*time.Minute,
+		"Time for which a zone found to be an active DonDominio service is not checked again. Zero checks it on every Present.")
+
+	batchWindow = flag.Duration("batch-window", 0,
+		"Time for which the first lookup of a zone waits for those of the other challenges of the zone, e.g. of a certificate with many DNS names, to make them with a single API call. Zero disables the batching.")
+
+	maxConcurrentRequests = flag.Int("max-concurrent-requests", 0,
+		"Maximum number of DonDominio API requests in flight, across all the issuers. The other requests wait for a free slot. Zero is unlimited.")
+
+	rateLimit = flag.String("rate-limit", "",
+		"Maximum rate of the DonDominio API requests of each account, e.g. 10/s or 300/m, overridden by --account-rate-limits. Empty is unlimited.")
+	accountRateLimitsFlag = flag.String("account-rate-limits", "",
+		"Comma separated list of apiuser=rate setting the maximum rate of the DonDominio API requests of specific accounts, e.g. reseller=300/m.")
+	rateLimitBurst = flag.Int("rate-limit-burst", 1,
+		"Number of DonDominio API requests of an account that may be sent at once when its rate limit allows it.")
+	quotaCheckInterval = flag.Duration("quota-check-interval", 0,
+		"Interval between two checks of the API quota of an account with /account/info, reported by the cert_manager_webhook_dd_api_quota_remaining metric. Zero disables the checks.")
+	quotaThreshold = flag.Float64("quota-threshold", 0.1,
+		"Fraction of the API quota of an account below which its requests are limited to --quota-rate-limit.")
+	quotaRateLimit = flag.String("quota-rate-limit", "10/m",
+		"Maximum rate of the DonDominio API requests of an account whose API quota runs low.")
+
+	httpMaxIdleConnsPerHost = flag.Int("http-max-idle-conns-per-host", 16,
+		"Number of idle connections to the DonDominio API kept for reuse, shared by all the issuers. Raise it for large renewal waves.")
+	httpIdleConnTimeout = flag.Duration("http-idle-conn-timeout", 90*time.Second,
+		"Time after which an idle connection to the DonDominio API is closed.")
+	httpDialTimeout = flag.Duration("http-dial-timeout", 30*time.Second,
+		"Maximum time to establish a TCP connection to the DonDominio API or the proxy.")
+	httpKeepAlive = flag.Duration("http-keep-alive", 30*time.Second,
+		"Period of the TCP keep-alive probes of the connections to the DonDominio API. A negative value disables them.")
+	httpTLSHandshakeTimeout = flag.Duration("http-tls-handshake-timeout", 10*time.Second,
+		"Maximum duration of the TLS handshake with the DonDominio API.")
+	strictResponses = flag.Bool("strict-responses", false,
+		"Fail the DonDominio API calls whose response has unexpected or missing fields, instead of only logging a warning. Meant for CI, to catch API changes.")
+	maxResponseSize = flag.String("max-response-size", "8Mi",
+		"Maximum size of a DonDominio API response body, once decompressed, above which the request fails.")
+	httpHTTP2 = flag.Bool("http2", true,
+		"Use HTTP/2 with the DonDominio API when it supports it, multiplexing the requests over fewer connections.")
+
+	proxyUsername = flag.String("proxy-username", "",
+		"Username sent to the HTTP_PROXY, HTTPS_PROXY or PROXY proxy when its URL has no credentials.")
+	proxyPassword = flag.String("proxy-password", "",
+		"Password of --proxy-username. Prefer the WEBHOOK_PROXY_PASSWORD environment variable, set from a secret, to keep it out of the command line.")
+	proxyCredentialsSecret = flag.String("proxy-credentials-secret", "",
+		"Secret, as namespace/name, holding the proxy credentials in its username and password keys, like a kubernetes.io/basic-auth secret. It replaces --proxy-username and --proxy-password, and is read on startup.")
+
+	secretNamespace = flag.String("secret-namespace", "",
+		"Comma separated list of the namespaces from which the secrets referenced by the issuers may be read, e.g. the cluster resource namespace of cert-manager. Empty allows any namespace.")
+
+	secretsDir = flag.String("secrets-dir", "",
+		"Directory from which the secrets referenced by the issuers are read instead of the Kubernetes API, each key of the secret namespace/name being the file <dir>/<namespace>/<name>/<key>. Meant for running the webhook locally.")
+
+	ambientCredentialsFile = flag.String("ambient-credentials-file", "",
+		"dondominio.conf file, e.g. mounted from a secret, from which the issuers allowed ambient credentials and the canary read the credentials, with precedence over the other dondominio.conf files.")
+
+	brokerTokenFile = flag.String("broker-token-file", "/var/run/secrets/dd-broker/token",
+		"Default file of the projected service account token sent to the credentials broker, overridden by the credentialsBroker.tokenPath field of the issuer config.")
+
+	dryRunFlag = flag.Bool("dry-run", false,
+		"Perform the lookups and validation of the challenges but only log the records they or the orphan collector would create or delete, and do not wait for their propagation. Implies --read-only.")
+
+	readOnly = flag.Bool("read-only", false,
+		"Log the DonDominio API calls that would create or delete records instead of sending them.")
+
+	logFormat = flag.String("log-format", "text",
+		"Log format, text or json. The verbosity is set with -v: 4 logs the DonDominio API calls.")
+	logLevel = flag.String("log-level", "",
+		"Verbosity of the logs, info, debug (logs the DonDominio API calls) or trace, or a -v level. Overrides -v when set.")
+
+	tlsCertDir = flag.String("tls-cert-dir", "",
+		"Directory holding the tls.crt and tls.key files of the serving certificate, e.g. a mounted kubernetes.io/tls secret. Replaces --tls-cert-file and --tls-private-key-file.")
+
+	apiMessagesMode = flag.String("api-messages", apiMessagesLog,
+		"Handling of the messages of the DonDominio API responses: ignore, log, or event to also record the warnings as events of the webhook pod.")
+
+	debugHTTP = flag.Bool("debug-http", false,
+		"Log the bodies of the DonDominio API requests and responses, with the credentials redacted. Also enabled by the DEBUG environment variable.")
+
+	otlpEndpoint = flag.String("otlp-endpoint", "",
+		"OTLP gRPC endpoint, as host:port, to which the traces of the challenges and DonDominio API calls are exported. Empty disables tracing.")
+	otlpInsecure = flag.Bool("otlp-insecure", false,
+		"Connect to the OTLP endpoint without TLS.")
+
+	metricsAddr = flag.String("metrics-addr", "",
+		"Address on which Prometheus metrics are served on /metrics, e.g. :9402. Empty disables the metrics server.")
+
+	probeAddr = flag.String("probe-addr", "",
+		"Address on which /healthz and /readyz are served over HTTP, e.g. :8080. Empty disables the probe server.")
+	probePing = flag.Bool("probe-ping", false,
+		"Make /readyz also check that the DonDominio API of the configured endpoint answers.")
+	probeCacheTTL = flag.Duration("probe-cache-ttl", 30*time.Second,
+		"Time for which the result of a /readyz check is reused.")
+	probeTimeout = flag.Duration("probe-timeout", 5*time.Second,
+		"Maximum duration of each /readyz check.")
+
+	debugAddr = flag.String("debug-addr", "",
+		"Loopback address on which pprof profiles, expvar variables and goroutine dumps are served, e.g. localhost:6060. Empty disables the debug server.")
+
+	shutdownGracePeriod = flag.Duration("shutdown-grace-period", 20*time.Second,
+		"Maximum time for which the challenges in progress are waited for on shutdown before their DonDominio calls are cancelled. Should be shorter than the terminationGracePeriodSeconds of the pod.")
+
+	shutdownReportConfigMap = flag.String("shutdown-report-configmap", "",
+		"ConfigMap, as namespace/name, to which the summary of pending operations is written on shutdown. Empty only logs it.")
+
+	issuanceStatsConfigMap = flag.String("issuance-stats-configmap", "",
+		"ConfigMap, as namespace/name, in which the per-zone issuance statistics are persisted across restarts. Empty keeps them in memory only.")
+	challengeStateConfigMap = flag.String("challenge-state-configmap", "",
+		"ConfigMap, as namespace/name, in which the IDs of the challenge records created are persisted across restarts, so that CleanUp deletes them without searching the zone. Empty keeps them in memory only.")
+
+	auditLogPath = flag.String("audit-log", "",
+		"File to which an audit entry of every creation, update and deletion of a DNS record is appended as a JSON line, or - for stdout. Empty disables the audit log.")
+
+	orphanGCInterval = flag.Duration("orphan-gc-interval", 0,
+		"Interval between two collections of the orphaned challenge records of the --orphan-gc-zones, with the ambient credentials. Zero disables the collector.")
+	orphanGCZones = flag.String("orphan-gc-zones", "",
+		"Comma separated list of the zones whose orphaned challenge records are collected, or * for all the active zones of the account.")
+	orphanGCUnowned = flag.Bool("orphan-gc-unowned", false,
+		"Also collect the challenge records not created by the webhook, whose value is not the key of a Challenge of this cluster. Only for zones whose challenges no other cluster or ACME client solves.")
+	orphanGCMinAge = flag.Duration("orphan-gc-min-age", 24*time.Hour,
+		"Minimum age of the challenge records, not used by any Challenge, deleted by the collector.")
+
+	schemaCanaryInterval = flag.Duration("schema-canary-interval", 0,
+		"Interval between checks of the DonDominio API response structure against a pinned schema. Zero disables the check.")
+
+	debugBundleDir = flag.String("debug-bundle-dir", "",
+		"Directory in which diagnostic files, such as the heap profiles of --heap-profile-threshold, are written.")
+	heapProfileThreshold = flag.String("heap-profile-threshold", "",
+		"Heap or RSS size, e.g. 512Mi, above which a heap profile is written to --debug-bundle-dir once it has lasted --heap-profile-after. Empty disables it.")
+	heapProfileAfter = flag.Duration("heap-profile-after", 10*time.Minute,
+		"Time the memory usage must stay above --heap-profile-threshold before a heap profile is written, and between two profiles.")
+
+	retryMaxAttempts = flag.Int("retry-max-attempts", DefaultRetryPolicy.MaxAttempts,
+		"Maximum number of attempts of a DonDominio API request failing with a transient error, overridden by the retryPolicy.maxAttempts field of the issuer config.")
+	retryBaseDelay = flag.Duration("retry-base-delay", DefaultRetryPolicy.BaseDelay,
+		"Delay before the first retry of a DonDominio API request, doubled on each subsequent retry, overridden by the retryPolicy.baseDelay field of the issuer config.")
+	retryMaxDelay = flag.Duration("retry-max-delay", DefaultRetryPolicy.MaxDelay,
+		"Maximum delay between two attempts of a DonDominio API request, overridden by the retryPolicy.maxDelay field of the issuer config.")
+	retryJitter = flag.Float64("retry-jitter", DefaultRetryPolicy.Jitter,
+		"Maximum fraction of the retry delay, between 0 and 1, that is randomly removed from it.")
+)
+
+// validateFlags checks the flags that are otherwise only checked when the
+// solvers are initialized.
+func validateFlags() error {
+	limits, err := newAccountRateLimits(*rateLimit, *accountRateLimitsFlag, *rateLimitBurst)
+	if err != nil {
+		return err
+	}
+	if err := limits.withQuota(*quotaCheckInterval, *quotaThreshold, *quotaRateLimit); err != nil {
+		return err
+	}
+	if _, err := heapProfileThresholdBytes(); err != nil {
+		return err
+	}
+	if _, err := maxResponseSizeBytes(); err != nil {
+		return err
+	}
+	if err := transportFlags().validate(); err != nil {
+		return err
+	}
+	if err := validateAPIMessagesMode(*apiMessagesMode); err != nil {
+		return err
+	}
+	if err := validateDebugAddr(*debugAddr); err != nil {
+		return err
+	}
+	orphanZones, err := orphanGCZoneList()
+	if err != nil {
+		return err
+	}
+	if *orphanGCInterval > 0 && len(orphanZones) == 0 {
+		return fmt.Errorf("--orphan-gc-interval requires --orphan-gc-zones")
+	}
+	if *orphanGCMinAge <= 0 {
+		return fmt.Errorf("invalid orphan GC minimum age %s, must be positive", *orphanGCMinAge)
+	}
+	if *shutdownGracePeriod < 0 {
+		return fmt.Errorf("invalid shutdown grace period %s, must not be negative", *shutdownGracePeriod)
+	}
+	if _, err := parseLogLevel(*logLevel); err != nil {
+		return err
+	}
+	if *tlsCertDir != "" {
+		for _, name := range []string{tlsCertFile, tlsKeyFile} {
+			if _, err := os.Stat(filepath.Join(*tlsCertDir, name)); err != nil {
+				return fmt.Errorf("invalid --tls-cert-dir: %w", err)
+			}
+		}
+	}
+	if *secretsDir != "" {
+		if info, err := os.Stat(*secretsDir); err != nil {
+			return fmt.Errorf("invalid --secrets-dir: %w", err)
+		} else if !info.IsDir() {
+			return fmt.Errorf("invalid --secrets-dir: %s is not a directory", *secretsDir)
+		}
+	}
+	switch *logFormat {
+	case "text", "json":
+	default:
+		return fmt.Errorf("invalid log format %q, must be text or json", *logFormat)
+	}
+	return nil
+}
+
+// orphanGCZoneList returns the zones of --orphan-gc-zones, normalized like
+// the names of the challenges.
+func orphanGCZoneList() ([]string, error) {
+	var zones []string
+	for _, zone := range strings.Split(*orphanGCZones, ",") {
+		zone = strings.TrimSpace(zone)
+		switch zone {
+		case "":
+			continue
+		case orphanGCAllZones:
+			zones = append(zones, zone)
+			continue
+		}
+		fqdn, err := normalizeFQDN(zone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --orphan-gc-zones: %w", err)
+		}
+		zones = append(zones, util.UnFqdn(fqdn))
+	}
+	return zones, nil
+}
+
+// retryPolicy returns the retry policy configured by the command line flags.
+func retryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: *retryMaxAttempts,
+		BaseDelay:   *retryBaseDelay,
+		MaxDelay:    *retryMaxDelay,
+		Jitter:      *retryJitter,
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// apiServiceResource is the resource of the APIService registrations. It is
+// accessed with the dynamic client, since the aggregator client is not a
+// dependency of the webhook.
+var apiServiceResource = schema.GroupVersionResource{
+	Group:    "apiregistration.k8s.io",
+	Version:  "v1",
+	Resource: "apiservices",
+}
+
+// serviceAccountNamespaceFile holds the namespace of the pod.
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// discoverGroupName returns the group of the APIService registered for the
+// webhook, used when GROUP_NAME is not set. The APIService must point to a
+// service in the namespace of the webhook and, if the SERVICE_NAME
+// environment variable is set, to the service of that name.
+func discoverGroupName() (string, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return "", err
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return "", err
+	}
+	namespace, err := podNamespace()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	list, err := client.Resource(apiServiceResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list the APIServices: %w", err)
+	}
+	return groupNameFromAPIServices(list.Items, namespace, os.Getenv("SERVICE_NAME"))
+}
+
+// podNamespace returns the namespace of the pod, from the POD_NAMESPACE
+// environment variable or else from the service account.
+func podNamespace() (string, error) {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace, nil
+	}
+	data, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the namespace of the pod: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// groupNameFromAPIServices returns the group of the only v1alpha1 APIService
+// whose service is in namespace and, if serviceName is not empty, has that
+// name.
+func groupNameFromAPIServices(apiServices []unstructured.Unstructured, namespace, serviceName string) (string, error) {
+	var groups []string
+	for _, apiService := range apiServices {
+		version, _, _ := unstructured.NestedString(apiService.Object, "spec", "version")
+		ns, _, _ := unstructured.NestedString(apiService.Object, "spec", "service", "namespace")
+		name, _, _ := unstructured.NestedString(apiService.Object, "spec", "service", "name")
+		if version != "v1alpha1" || ns != namespace || (serviceName != "" && name != serviceName) {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(apiService.Object, "spec", "group")
+		groups = append(groups, group)
+	}
+
+	switch len(groups) {
+	case 0:
+		return "", fmt.Errorf("no v1alpha1 APIService registered for a service in namespace %s", namespace)
+	case 1:
+		return groups[0], nil
+	default:
+		sort.Strings(groups)
+		return "", fmt.Errorf("several APIServices registered for a service in namespace %s (%s), set GROUP_NAME or SERVICE_NAME", namespace, strings.Join(groups, ", "))
+	}
+}
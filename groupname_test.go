@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func testAPIService(group, version, namespace, name string) unstructured.Unstructured {
+	spec := map[string]interface{}{"group": group, "version": version}
+	if namespace != "" {
+		spec["service"] = map[string]interface{}{"namespace": namespace, "name": name}
+	}
+	return unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+}
+
+func TestGroupNameFromAPIServices(t *testing.T) {
+	apiServices := []unstructured.Unstructured{
+		testAPIService("apps", "v1", "", ""),
+		testAPIService("metrics.k8s.io", "v1beta1", "kube-system", "metrics-server"),
+		testAPIService("acme.example.com", "v1alpha1", "cert-manager", "cert-manager-webhook-dd"),
+		testAPIService("acme.example.org", "v1alpha1", "cert-manager", "cert-manager-webhook-other"),
+		testAPIService("acme.example.net", "v1alpha1", "dd", "cert-manager-webhook-dd"),
+	}
+
+	tests := []struct {
+		name        string
+		namespace   string
+		serviceName string
+		want        string
+		wantErr     bool
+	}{
+		{name: "single", namespace: "dd", want: "acme.example.net"},
+		{name: "ambiguous", namespace: "cert-manager", wantErr: true},
+		{name: "by service", namespace: "cert-manager", serviceName: "cert-manager-webhook-dd", want: "acme.example.com"},
+		{name: "none", namespace: "default", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := groupNameFromAPIServices(apiServices, tt.namespace, tt.serviceName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("groupNameFromAPIServices() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("groupNameFromAPIServices() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// heapWatchInterval is the interval between two samples of the memory
+	// usage by the heap watchdog.
+	heapWatchInterval = 30 * time.Second
+
+	// maxHeapProfiles is the number of heap profiles kept in the debug
+	// bundle directory, the oldest ones are deleted.
+	maxHeapProfiles = 5
+)
+
+var heapProfiles = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "heap_profiles_total",
+	Help:      "Number of heap profiles written by the watchdog on sustained high memory usage.",
+})
+
+func init() {
+	metricsRegistry.MustRegister(heapProfiles)
+}
+
+// heapWatchdog writes a heap profile to dir when the heap or the RSS of the
+// process stays above threshold for the duration after, so that a leak
+// showing up after months in a large cluster can be diagnosed from the
+// profiles instead of being reproduced. While the usage stays high, a new
+// profile is written every after.
+type heapWatchdog struct {
+	dir       string
+	threshold uint64
+	after     time.Duration
+
+	// usage returns the heap in use and the RSS, zero if unknown.
+	usage func() (heap, rss uint64)
+
+	highSince time.Time
+}
+
+func newHeapWatchdog(dir string, threshold uint64, after time.Duration) *heapWatchdog {
+	return &heapWatchdog{dir: dir, threshold: threshold, after: after, usage: memoryUsage}
+}
+
+// run samples the memory usage every heapWatchInterval until stopCh is
+// closed.
+func (w *heapWatchdog) run(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(heapWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case now := <-ticker.C:
+			w.check(now)
+		}
+	}
+}
+
+// check samples the memory usage at now and writes a heap profile if it has
+// been above the threshold for long enough. It returns the file written, if
+// any.
+func (w *heapWatchdog) check(now time.Time) string {
+	heap, rss := w.usage()
+	if heap < w.threshold && rss < w.threshold {
+		w.highSince = time.Time{}
+		return ""
+	}
+	if w.highSince.IsZero() {
+		w.highSince = now
+	}
+	if now.Sub(w.highSince) < w.after {
+		return ""
+	}
+	w.highSince = now
+
+	file, err := writeHeapProfile(w.dir, now)
+	if err != nil {
+		klog.ErrorS(err, "Failed to write the heap profile", "dir", w.dir)
+		return ""
+	}
+	heapProfiles.Inc()
+	klog.InfoS("Sustained high memory usage, heap profile written", "heapBytes", heap, "rssBytes", rss, "thresholdBytes", w.threshold, "file", file)
+	return file
+}
+
+// writeHeapProfile writes the heap profile to a file of dir named after now,
+// and deletes the oldest profiles beyond maxHeapProfiles.
+func writeHeapProfile(dir string, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, "heap-"+now.UTC().Format("20060102T150405Z")+".pprof")
+	f, err := os.Create(file)
+	if err != nil {
+		return "", err
+	}
+	err = pprof.Lookup("heap").WriteTo(f, 0)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file)
+		return "", err
+	}
+
+	// The names sort by time
+	profiles, err := filepath.Glob(filepath.Join(dir, "heap-*.pprof"))
+	if err != nil {
+		return file, nil
+	}
+	sort.Strings(profiles)
+	for len(profiles) > maxHeapProfiles {
+		if err := os.Remove(profiles[0]); err != nil {
+			klog.ErrorS(err, "Failed to delete an old heap profile", "file", profiles[0])
+		}
+		profiles = profiles[1:]
+	}
+	return file, nil
+}
+
+// memoryUsage returns the heap in use and the RSS of the process. The RSS is
+// zero where /proc is not available.
+func memoryUsage() (heap, rss uint64) {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse, residentSetSize()
+}
+
+func residentSetSize() uint64 {
+	statm, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(statm))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * uint64(os.Getpagesize())
+}
+
+// setupHeapWatchdog starts the heap watchdog configured by the flags, if
+// any, until stopCh is closed.
+func setupHeapWatchdog(stopCh <-chan struct{}) error {
+	threshold, err := heapProfileThresholdBytes()
+	if err != nil || threshold == 0 {
+		return err
+	}
+	go newHeapWatchdog(*debugBundleDir, threshold, *heapProfileAfter).run(stopCh)
+	return nil
+}
+
+// heapProfileThresholdBytes returns the threshold set by
+// --heap-profile-threshold, or zero if the watchdog is disabled.
+func heapProfileThresholdBytes() (uint64, error) {
+	if *heapProfileThreshold == "" {
+		return 0, nil
+	}
+	if *debugBundleDir == "" {
+		return 0, errors.New("--heap-profile-threshold requires --debug-bundle-dir")
+	}
+	threshold, err := resource.ParseQuantity(*heapProfileThreshold)
+	if err != nil || threshold.Sign() <= 0 {
+		return 0, fmt.Errorf("invalid --heap-profile-threshold %q, must be a positive quantity like 512Mi", *heapProfileThreshold)
+	}
+	return uint64(threshold.Value()), nil
+}
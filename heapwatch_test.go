@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHeapWatchdog(t *testing.T) {
+	dir := t.TempDir()
+	var heap uint64
+	w := &heapWatchdog{
+		dir:       dir,
+		threshold: 100,
+		after:     10 * time.Minute,
+		usage:     func() (uint64, uint64) { return heap, 0 },
+	}
+	start := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	heap = 200
+	if file := w.check(start); file != "" {
+		t.Fatalf("check() wrote %s on the first high sample", file)
+	}
+	heap = 50
+	if file := w.check(start.Add(5 * time.Minute)); file != "" {
+		t.Fatalf("check() wrote %s below the threshold", file)
+	}
+	heap = 200
+	if file := w.check(start.Add(10 * time.Minute)); file != "" {
+		t.Fatalf("check() wrote %s after a drop below the threshold", file)
+	}
+	file := w.check(start.Add(20 * time.Minute))
+	if file == "" {
+		t.Fatal("check() wrote no profile after sustained high usage")
+	}
+	if info, err := os.Stat(file); err != nil || info.Size() == 0 {
+		t.Errorf("profile %s not written: %v", file, err)
+	}
+	if file := w.check(start.Add(21 * time.Minute)); file != "" {
+		t.Errorf("check() wrote %s again before the delay", file)
+	}
+}
+
+func TestWriteHeapProfilePrunes(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < maxHeapProfiles+2; i++ {
+		if _, err := writeHeapProfile(dir, start.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	profiles, err := filepath.Glob(filepath.Join(dir, "heap-*.pprof"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != maxHeapProfiles {
+		t.Fatalf("%d profiles kept, want %d", len(profiles), maxHeapProfiles)
+	}
+	if filepath.Base(profiles[0]) != "heap-20220301T020000Z.pprof" {
+		t.Errorf("oldest profile kept = %s, want the third one", profiles[0])
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// errorHints map the common failure classes to an action likely to fix them.
+// The first matching hint is used.
+var errorHints = []struct {
+	match func(err error) bool
+	hint  string
+}{
+	{
+		match: func(err error) bool {
+			var apiError *APIError
+			return errors.As(err, &apiError) && apiError.ErrorCode == ddErrActionNotAllowed
+		},
+		hint: "check that the API user has the DNS management permissions in the DonDominio panel",
+	},
+	{
+		match: func(err error) bool { return errors.Is(err, ErrAuthFailed) },
+		hint:  "check the applicationKey and the secret, and that the egress IP of the cluster is allowed in the API settings of the DonDominio panel",
+	},
+	{
+		match: func(err error) bool { return errors.Is(err, ErrServiceNotActive) },
+		hint:  "check that the domain belongs to this DonDominio account and that its DNS service is active",
+	},
+	{
+		match: func(err error) bool { return errors.Is(err, ErrQuotaExceeded) },
+		hint:  "check the balance of the DonDominio account and the number of DNS records of the zone",
+	},
+	{
+		match: func(err error) bool { return errors.Is(err, ErrRateLimited) },
+		hint:  "too many challenges are solved at once, spread the renewals or raise --retry-max-attempts",
+	},
+	{
+		match: func(err error) bool { return errors.Is(err, ErrReplayedResponse) },
+		hint:  "a proxy between the webhook and DonDominio replays responses, make sure it does not cache the POST requests to the API",
+	},
+	{
+		match: func(err error) bool { return errors.Is(err, ErrResponseDrift) },
+		hint:  "the DonDominio API changed, update the webhook or run it without --strict-responses",
+	},
+	{
+		match: func(err error) bool { return errors.Is(err, ErrResponseTooLarge) },
+		hint:  "check that no proxy between the webhook and DonDominio answers instead of the API, or raise --max-response-size for very large zones",
+	},
+	{
+		match: func(err error) bool { return errors.Is(err, errSecretNamespaceNotAllowed) },
+		hint:  "the secrets can only be read from the namespaces of --secret-namespace, use a ClusterIssuer, whose secrets are read from the cluster resource namespace of cert-manager, or add the namespace of the Issuer to the ddApplicationSecret.namespaces chart value",
+	},
+	{
+		match: func(err error) bool { return errors.Is(err, errDomainNotAllowed) },
+		hint:  "the issuer must not be used for this domain, check the dnsZones selector of its solver or its allowedDomains and deniedDomains",
+	},
+	{
+		match: apierrors.IsNotFound,
+		hint:  "the applicationSecretRef secret must be in the namespace of the Certificate, or in the cluster resource namespace of cert-manager for a ClusterIssuer",
+	},
+	{
+		match: apierrors.IsForbidden,
+		hint:  "the service account of the webhook must be allowed to get the applicationSecretRef secret, see the ddApplicationSecret.secretName chart value",
+	},
+	{
+		match: func(err error) bool { return errors.Is(err, context.DeadlineExceeded) },
+		hint:  "DonDominio is slow to answer, raise the requestTimeout of the issuer",
+	},
+	{
+		match: func(err error) bool {
+			var netError net.Error
+			return errors.As(err, &netError)
+		},
+		hint: "check that the cluster can reach the DonDominio endpoint, through the proxy set by the HTTPS_PROXY or PROXY environment variables if needed",
+	},
+}
+
+// hintedError is an error with a troubleshooting hint appended to its
+// message.
+type hintedError struct {
+	err  error
+	hint string
+}
+
+func (e *hintedError) Error() string {
+	return e.err.Error() + " (hint: " + e.hint + ")"
+}
+
+func (e *hintedError) Unwrap() error {
+	return e.err
+}
+
+// withHint appends to err the hint of its failure class, if any. It is
+// applied once, to the errors returned to cert-manager, which shows them in
+// the status of the Challenge.
+func withHint(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, h := range errorHints {
+		if h.match(err) {
+			return &hintedError{err: err, hint: h.hint}
+		}
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+// Hooks lets extensions run custom logic, e.g. opening a ticket or updating a
+// CMDB, around the challenges handled by the solver. They are registered with
+// newSolver and called in registration order.
+type Hooks interface {
+	// BeforePresent is called before the challenge record is created. An
+	// error aborts Present.
+	BeforePresent(ctx context.Context, ch *v1alpha1.ChallengeRequest) error
+	// AfterPresent is called once the challenge record is created.
+	AfterPresent(ctx context.Context, ch *v1alpha1.ChallengeRequest)
+	// BeforeCleanUp is called before the challenge record is deleted. An
+	// error aborts CleanUp.
+	BeforeCleanUp(ctx context.Context, ch *v1alpha1.ChallengeRequest) error
+	// AfterCleanUp is called once the challenge record is deleted.
+	AfterCleanUp(ctx context.Context, ch *v1alpha1.ChallengeRequest)
+	// OnError is called when Present or CleanUp, as given by action, fails.
+	OnError(ctx context.Context, action string, ch *v1alpha1.ChallengeRequest, err error)
+}
+
+// NopHooks implements Hooks without doing anything. Extensions can embed it
+// to only implement the hooks they need.
+type NopHooks struct{}
+
+func (NopHooks) BeforePresent(ctx context.Context, ch *v1alpha1.ChallengeRequest) error { return nil }
+func (NopHooks) AfterPresent(ctx context.Context, ch *v1alpha1.ChallengeRequest)        {}
+func (NopHooks) BeforeCleanUp(ctx context.Context, ch *v1alpha1.ChallengeRequest) error { return nil }
+func (NopHooks) AfterCleanUp(ctx context.Context, ch *v1alpha1.ChallengeRequest)        {}
+func (NopHooks) OnError(ctx context.Context, action string, ch *v1alpha1.ChallengeRequest, err error) {
+}
+
+// hookList calls each of its hooks in turn. The Before hooks stop at the
+// first error.
+type hookList []Hooks
+
+func (l hookList) BeforePresent(ctx context.Context, ch *v1alpha1.ChallengeRequest) error {
+	for _, h := range l {
+		if err := h.BeforePresent(ctx, ch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l hookList) AfterPresent(ctx context.Context, ch *v1alpha1.ChallengeRequest) {
+	for _, h := range l {
+		h.AfterPresent(ctx, ch)
+	}
+}
+
+func (l hookList) BeforeCleanUp(ctx context.Context, ch *v1alpha1.ChallengeRequest) error {
+	for _, h := range l {
+		if err := h.BeforeCleanUp(ctx, ch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l hookList) AfterCleanUp(ctx context.Context, ch *v1alpha1.ChallengeRequest) {
+	for _, h := range l {
+		h.AfterCleanUp(ctx, ch)
+	}
+}
+
+func (l hookList) OnError(ctx context.Context, action string, ch *v1alpha1.ChallengeRequest, err error) {
+	for _, h := range l {
+		h.OnError(ctx, action, ch, err)
+	}
+}
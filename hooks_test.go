@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+// recordingHooks records the hooks called, and fails BeforePresent with
+// presentErr if set.
+type recordingHooks struct {
+	NopHooks
+	presentErr error
+	calls      []string
+}
+
+func (h *recordingHooks) BeforePresent(ctx context.Context, ch *v1alpha1.ChallengeRequest) error {
+	h.calls = append(h.calls, "BeforePresent")
+	return h.presentErr
+}
+
+func (h *recordingHooks) AfterPresent(ctx context.Context, ch *v1alpha1.ChallengeRequest) {
+	h.calls = append(h.calls, "AfterPresent")
+}
+
+func (h *recordingHooks) OnError(ctx context.Context, action string, ch *v1alpha1.ChallengeRequest, err error) {
+	h.calls = append(h.calls, "OnError "+action)
+}
+
+func TestHooks(t *testing.T) {
+	errRefused := errors.New("change refused")
+	tests := []struct {
+		name        string
+		presentErr  error
+		wantCalls   []string
+		wantActions []string
+	}{
+		{
+			name:        "success",
+			wantCalls:   []string{"BeforePresent", "AfterPresent"},
+			wantActions: []string{"getinfo", "dnscreate"},
+		},
+		{
+			name:       "aborted",
+			presentErr: errRefused,
+			wantCalls:  []string{"BeforePresent", "OnError present"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFixtureAPI(map[string]string{"example.com": "active"}, nil)
+			server := httptest.NewServer(api)
+			defer server.Close()
+
+			hooks := &recordingHooks{presentErr: tt.presentErr}
+			solver := testSolver(hooks)
+
+			err := solver.Present(testChallenge(t, server.URL, "_acme-challenge.example.com.", "example.com.", "challenge-key", nil))
+			if !errors.Is(err, tt.presentErr) {
+				t.Errorf("Present() error = %v, want %v", err, tt.presentErr)
+			}
+			if !reflect.DeepEqual(hooks.calls, tt.wantCalls) {
+				t.Errorf("hooks called = %q, want %q", hooks.calls, tt.wantCalls)
+			}
+			if actions, _ := api.result(); !reflect.DeepEqual(actions, tt.wantActions) {
+				t.Errorf("actions = %q, want %q", actions, tt.wantActions)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+	"golang.org/x/net/idna"
+)
+
+// idnaProfile converts internationalized domain names to punycode. The
+// labels are not validated as host names, since the challenge records start
+// with an underscore.
+var idnaProfile = idna.New(
+	idna.MapForLookup(),
+	idna.StrictDomainName(false),
+	idna.Transitional(false),
+)
+
+// normalizeFQDN returns fqdn in lowercase with its non-ASCII labels converted
+// to punycode, which is the form DonDominio expects for service and record
+// names, e.g. _acme-challenge.xn--espaa-rta.es. for _acme-challenge.España.es.
+func normalizeFQDN(fqdn string) (string, error) {
+	name, err := idnaProfile.ToASCII(util.UnFqdn(fqdn))
+	if err != nil {
+		return "", fmt.Errorf("invalid domain name %q: %w", fqdn, err)
+	}
+	return util.ToFqdn(name), nil
+}
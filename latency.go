@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// AdaptiveTimeout derives the timeout of each API request from the latency
+// previously observed on the same path, bounded by Min and Max. It uses the
+// retransmission timeout estimator of TCP (RFC 6298): the smoothed latency
+// plus four times its smoothed mean deviation.
+//
+// An AdaptiveTimeout may be shared by several clients.
+type AdaptiveTimeout struct {
+	// Min is the lowest timeout, used when the latency is stable and low
+	Min time.Duration
+	// Max is the highest timeout, used before any latency is observed
+	Max time.Duration
+
+	mu    sync.Mutex
+	paths map[string]*latencyEstimate
+}
+
+// latencyEstimate is the smoothed latency and deviation of a path.
+type latencyEstimate struct {
+	srtt   time.Duration
+	rttvar time.Duration
+}
+
+// observe updates the latency estimate of path with a new sample.
+func (t *AdaptiveTimeout) observe(path string, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.paths == nil {
+		t.paths = make(map[string]*latencyEstimate)
+	}
+	e, ok := t.paths[path]
+	if !ok {
+		t.paths[path] = &latencyEstimate{srtt: latency, rttvar: latency / 2}
+		return
+	}
+
+	deviation := e.srtt - latency
+	if deviation < 0 {
+		deviation = -deviation
+	}
+	e.rttvar = (3*e.rttvar + deviation) / 4
+	e.srtt = (7*e.srtt + latency) / 8
+}
+
+// timeout returns the timeout of the next request on path.
+func (t *AdaptiveTimeout) timeout(path string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.paths[path]
+	if !ok {
+		return t.Max
+	}
+
+	timeout := e.srtt + 4*e.rttvar
+	if timeout < t.Min {
+		return t.Min
+	}
+	if timeout > t.Max {
+		return t.Max
+	}
+	return timeout
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTimeout(t *testing.T) {
+	at := &AdaptiveTimeout{Min: time.Second, Max: time.Minute}
+
+	if got := at.timeout("/service/dnslist"); got != time.Minute {
+		t.Errorf("timeout() without samples = %v, want %v", got, time.Minute)
+	}
+
+	for i := 0; i < 50; i++ {
+		at.observe("/service/dnslist", 2*time.Second)
+	}
+	if got := at.timeout("/service/dnslist"); got < 2*time.Second || got > 3*time.Second {
+		t.Errorf("timeout() with stable latency = %v, want close to 2s", got)
+	}
+
+	for i := 0; i < 50; i++ {
+		at.observe("/service/getinfo", 10*time.Millisecond)
+	}
+	if got := at.timeout("/service/getinfo"); got != time.Second {
+		t.Errorf("timeout() with low latency = %v, want %v", got, time.Second)
+	}
+
+	at.observe("/service/dnscreate", 10*time.Minute)
+	if got := at.timeout("/service/dnscreate"); got != time.Minute {
+		t.Errorf("timeout() with high latency = %v, want %v", got, time.Minute)
+	}
+}
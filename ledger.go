@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// challengeKey identifies the TXT record of a challenge.
+type challengeKey struct {
+	FQDN string
+	Key  string
+}
+
+// operation is a Present or CleanUp call in flight.
+type operation struct {
+	Action    string    `json:"action"`
+	FQDN      string    `json:"fqdn"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// ledgerEntry is a challenge record created by Present and not cleaned up yet.
+type ledgerEntry struct {
+	FQDN      string    `json:"fqdn"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Created maps the IDs of the records created for the challenge, one per
+	// Present call, to their creation time
+	Created map[string]time.Time `json:"created,omitempty"`
+}
+
+// pendingDeletion is a challenge record whose CleanUp failed and has not
+// been retried successfully yet.
+type pendingDeletion struct {
+	FQDN     string    `json:"fqdn"`
+	FailedAt time.Time `json:"failedAt"`
+	Error    string    `json:"error"`
+}
+
+// ledger keeps track in memory of the operations in flight and of the
+// challenge records managed by the webhook. Its zero value is ready to use.
+type ledger struct {
+	mu         sync.Mutex
+	nextID     uint64
+	operations map[uint64]operation
+	records    map[challengeKey]ledgerEntry
+	deletions  map[challengeKey]pendingDeletion
+	// changes counts the changes of the records, to persist them only when
+	// they changed
+	changes uint64
+	// names serializes the operations on the records of a same name
+	names keyedMutex
+}
+
+// lockName waits until no other operation holds the records named fqdn, and
+// returns a function to call to release them, or the error of ctx if it is
+// done first. Several keys may be pending for the same name when several
+// orders validate it concurrently.
+func (l *ledger) lockName(ctx context.Context, fqdn string) (unlock func(), err error) {
+	return l.names.lock(ctx, fqdn)
+}
+
+// pendingKeys returns the keys of the challenge records named fqdn that have
+// been presented and not cleaned up yet.
+func (l *ledger) pendingKeys(fqdn string) map[string]bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	keys := make(map[string]bool)
+	for key := range l.records {
+		if key.FQDN == fqdn {
+			keys[key.Key] = true
+		}
+	}
+	return keys
+}
+
+// begin records the start of an operation and returns a function to call
+// when it ends.
+func (l *ledger) begin(action, fqdn string) (end func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.operations == nil {
+		l.operations = make(map[uint64]operation)
+	}
+	id := l.nextID
+	l.nextID++
+	l.operations[id] = operation{Action: action, FQDN: fqdn, StartedAt: time.Now()}
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.operations, id)
+	}
+}
+
+// presented records that the challenge record with the given ID, empty if
+// unknown, has been created.
+func (l *ledger) presented(key challengeKey, id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.records == nil {
+		l.records = make(map[challengeKey]ledgerEntry)
+	}
+	entry, ok := l.records[key]
+	if !ok {
+		entry = ledgerEntry{FQDN: key.FQDN, CreatedAt: now}
+	}
+	if id != "" {
+		// Copied since the snapshots share the map
+		created := make(map[string]time.Time, len(entry.Created)+1)
+		for other, createdAt := range entry.Created {
+			created[other] = createdAt
+		}
+		created[id] = now
+		entry.Created = created
+	}
+	l.records[key] = entry
+	l.changes++
+}
+
+// createdRecords returns the IDs and creation times of the records created
+// for the challenge, empty if they are unknown, e.g. after a restart.
+func (l *ledger) createdRecords(key challengeKey) map[string]time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	created := make(map[string]time.Time, len(l.records[key].Created))
+	for id, createdAt := range l.records[key].Created {
+		created[id] = createdAt
+	}
+	return created
+}
+
+// createdRecord returns the challenge of the record created with the given
+// ID, if it has not been cleaned up yet.
+func (l *ledger) createdRecord(id string) (challengeKey, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, entry := range l.records {
+		if _, ok := entry.Created[id]; ok {
+			return key, true
+		}
+	}
+	return challengeKey{}, false
+}
+
+// forgetCreated records that the record of the challenge with the given ID
+// has been deleted, and the challenge record as cleaned up if it was the
+// last one known.
+func (l *ledger) forgetCreated(key challengeKey, id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.records[key]
+	if !ok {
+		return
+	}
+	created := make(map[string]time.Time, len(entry.Created))
+	for other, createdAt := range entry.Created {
+		if other != id {
+			created[other] = createdAt
+		}
+	}
+	if len(created) == 0 {
+		delete(l.records, key)
+	} else {
+		entry.Created = created
+		l.records[key] = entry
+	}
+	l.changes++
+}
+
+// cleanedUp records that the challenge record has been deleted.
+func (l *ledger) cleanedUp(key challengeKey) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.records[key]; ok {
+		l.changes++
+	}
+	delete(l.records, key)
+	delete(l.deletions, key)
+}
+
+// deletionFailed records that the challenge record could not be deleted.
+func (l *ledger) deletionFailed(key challengeKey, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.deletions == nil {
+		l.deletions = make(map[challengeKey]pendingDeletion)
+	}
+	l.deletions[key] = pendingDeletion{FQDN: key.FQDN, FailedAt: time.Now(), Error: redactSecrets(err.Error())}
+}
+
+// ledgerSnapshot is a copy of the content of the ledger, sorted by time.
+type ledgerSnapshot struct {
+	Operations       []operation       `json:"operations"`
+	PendingDeletions []pendingDeletion `json:"pendingDeletions"`
+	Records          []ledgerEntry     `json:"records"`
+}
+
+func (l *ledger) snapshot() ledgerSnapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	snapshot := ledgerSnapshot{
+		Operations:       make([]operation, 0, len(l.operations)),
+		PendingDeletions: make([]pendingDeletion, 0, len(l.deletions)),
+		Records:          make([]ledgerEntry, 0, len(l.records)),
+	}
+	for _, op := range l.operations {
+		snapshot.Operations = append(snapshot.Operations, op)
+	}
+	for _, deletion := range l.deletions {
+		snapshot.PendingDeletions = append(snapshot.PendingDeletions, deletion)
+	}
+	for _, entry := range l.records {
+		snapshot.Records = append(snapshot.Records, entry)
+	}
+
+	sort.Slice(snapshot.Operations, func(i, j int) bool {
+		return snapshot.Operations[i].StartedAt.Before(snapshot.Operations[j].StartedAt)
+	})
+	sort.Slice(snapshot.PendingDeletions, func(i, j int) bool {
+		return snapshot.PendingDeletions[i].FailedAt.Before(snapshot.PendingDeletions[j].FailedAt)
+	})
+	sort.Slice(snapshot.Records, func(i, j int) bool {
+		return snapshot.Records[i].CreatedAt.Before(snapshot.Records[j].CreatedAt)
+	})
+	return snapshot
+}
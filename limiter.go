@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var requestQueueWait = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Namespace: metricsNamespace,
+	Name:      "api_request_queue_wait_seconds",
+	Help:      "Time spent by the DonDominio API requests waiting for a free slot of --max-concurrent-requests.",
+	Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+})
+
+func init() {
+	metricsRegistry.MustRegister(requestQueueWait)
+}
+
+// RequestLimiter bounds the number of DonDominio API requests in flight, so
+// that a burst of challenges is queued instead of tripping the rate limits
+// of the account.
+//
+// A RequestLimiter may be shared by several clients.
+type RequestLimiter struct {
+	slots chan struct{}
+}
+
+// NewRequestLimiter returns a limiter allowing max requests at once.
+func NewRequestLimiter(max int) *RequestLimiter {
+	return &RequestLimiter{slots: make(chan struct{}, max)}
+}
+
+// acquire waits for a free slot, or until ctx is done, and returns the
+// function releasing the slot.
+func (l *RequestLimiter) acquire(ctx context.Context) (release func(), err error) {
+	start := time.Now()
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	requestQueueWait.Observe(time.Since(start).Seconds())
+	return func() { <-l.slots }, nil
+}
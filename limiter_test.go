@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequestLimiter(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Write([]byte("1700000000"))
+	}))
+	defer server.Close()
+
+	limiter := NewRequestLimiter(2)
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		// Clients of different issuers share the limiter
+		ddClient, err := NewClient(server.URL, "apiuser", "apipasswd")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ddClient.Limiter = limiter
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := ddClient.PingWithContext(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight != 2 {
+		t.Errorf("%d requests in flight at most, want 2", maxInFlight)
+	}
+}
+
+func TestRequestLimiterCancel(t *testing.T) {
+	limiter := NewRequestLimiter(1)
+	release, err := limiter.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquire() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -1,7 +1,12 @@
-package main 
+package main
 
 import (
+	"bytes"
+	"io"
 	"net/http"
+	"net/url"
+
+	"k8s.io/klog/v2"
 )
 
 // Logger is the interface that should be implemented for loggers that wish to
@@ -13,3 +18,84 @@ type Logger interface {
 	// LogResponse logs an HTTP response.
 	LogResponse(*http.Response)
 }
+
+// maxLoggedBody is the size above which the logged bodies are truncated.
+const maxLoggedBody = 4096
+
+// redactedFields are the form fields whose values are never logged.
+var redactedFields = []string{"apiuser", "apipasswd"}
+
+// requestLogger is the default Logger, based on klog. The API calls are
+// logged from verbosity logDebug, and their bodies only if Bodies is set.
+type requestLogger struct {
+	Bodies bool
+}
+
+func (l requestLogger) LogRequest(req *http.Request) {
+	logger := klog.FromContext(req.Context())
+	if !l.Bodies {
+		logger.V(logDebug).Info("DonDominio API request", "method", req.Method, "path", req.URL.Path)
+		return
+	}
+
+	var body string
+	if req.GetBody != nil {
+		if r, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(r)
+			r.Close()
+			body = redactForm(string(data))
+		}
+	}
+	logger.Info("DonDominio API request", "method", req.Method, "path", req.URL.Path, "body", truncateBody(body))
+}
+
+func (l requestLogger) LogResponse(resp *http.Response) {
+	logger := klog.FromContext(resp.Request.Context())
+	if !l.Bodies {
+		logger.V(logDebug).Info("DonDominio API response",
+			"method", resp.Request.Method, "path", resp.Request.URL.Path, "status", resp.StatusCode)
+		return
+	}
+
+	// The body is read ahead and handed back to the caller, with the read
+	// error if any, e.g. ErrResponseTooLarge
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		logger.Error(err, "Failed to read the DonDominio API response body", "path", resp.Request.URL.Path)
+		resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), errReader{err}))
+	}
+	logger.Info("DonDominio API response",
+		"method", resp.Request.Method, "path", resp.Request.URL.Path, "status", resp.StatusCode, "body", truncateBody(string(data)))
+}
+
+// redactForm replaces the values of the redactedFields of a form encoded body.
+// Bodies that are not valid forms are entirely redacted.
+func redactForm(body string) string {
+	values, err := url.ParseQuery(body)
+	if err != nil {
+		return "REDACTED"
+	}
+	for _, field := range redactedFields {
+		if _, ok := values[field]; ok {
+			values.Set(field, "REDACTED")
+		}
+	}
+	return values.Encode()
+}
+
+// truncateBody shortens body to maxLoggedBody bytes.
+func truncateBody(body string) string {
+	if len(body) <= maxLoggedBody {
+		return body
+	}
+	return body[:maxLoggedBody] + "...(truncated)"
+}
+
+// errReader fails every read with its error.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
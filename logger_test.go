@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedactForm(t *testing.T) {
+	got := redactForm("apiuser=user&apipasswd=s3cret&serviceName=example.com")
+	if strings.Contains(got, "user&") || strings.Contains(got, "s3cret") {
+		t.Errorf("redactForm() = %q, leaks the credentials", got)
+	}
+	if !strings.Contains(got, "serviceName=example.com") {
+		t.Errorf("redactForm() = %q, want the other fields", got)
+	}
+	if got := redactForm("%zz"); got != "REDACTED" {
+		t.Errorf("redactForm() of an invalid form = %q, want REDACTED", got)
+	}
+}
+
+func TestTruncateBody(t *testing.T) {
+	if got := truncateBody("short"); got != "short" {
+		t.Errorf("truncateBody() = %q, want %q", got, "short")
+	}
+	long := strings.Repeat("x", maxLoggedBody+1)
+	if got := truncateBody(long); len(got) >= len(long)+len("...(truncated)") || !strings.HasSuffix(got, "...(truncated)") {
+		t.Errorf("truncateBody() of %d bytes returned %d bytes", len(long), len(got))
+	}
+}
+
+func TestRequestLoggerKeepsBodies(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/service/dnslist", strings.NewReader("apiuser=user&apipasswd=s3cret"))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("apiuser=user&apipasswd=s3cret")), nil
+	}
+	resp := &http.Response{StatusCode: http.StatusOK, Request: req, Body: io.NopCloser(strings.NewReader(`{"success":true}`))}
+
+	logger := requestLogger{Bodies: true}
+	logger.LogRequest(req)
+	logger.LogResponse(resp)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"success":true}` {
+		t.Errorf("response body after logging = %q", body)
+	}
+}
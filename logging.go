@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"k8s.io/component-base/config"
+	logsjson "k8s.io/component-base/logs/json"
+	"k8s.io/klog/v2"
+)
+
+// logDebug is the verbosity, set with -v, from which the DonDominio API calls
+// are logged. Errors and the changes made to the zones are always logged.
+const logDebug = 4
+
+// klogFlags holds the flags of klog, of which only -v and -vmodule are
+// exposed: the others are deprecated.
+var klogFlags = flag.NewFlagSet("klog", flag.ContinueOnError)
+
+func init() {
+	klog.InitFlags(klogFlags)
+	for _, name := range []string{"v", "vmodule"} {
+		f := klogFlags.Lookup(name)
+		flag.CommandLine.Var(f.Value, f.Name, f.Usage)
+	}
+}
+
+// logLevels are the names of the verbosities accepted by --log-level.
+var logLevels = map[string]klog.Level{
+	"info":  0,
+	"debug": logDebug,
+	"trace": 6,
+}
+
+// parseLogLevel returns the verbosity of a --log-level, a name of logLevels
+// or a -v level, -1 if it is empty.
+func parseLogLevel(level string) (klog.Level, error) {
+	if level == "" {
+		return -1, nil
+	}
+	if v, ok := logLevels[strings.ToLower(level)]; ok {
+		return v, nil
+	}
+	v, err := strconv.ParseUint(level, 10, 31)
+	if err != nil {
+		return 0, fmt.Errorf("invalid log level %q, must be info, debug, trace or a -v level", level)
+	}
+	return klog.Level(v), nil
+}
+
+// setupLogging applies the --log-format flag and enables the per-challenge
+// loggers. It must be called once the flags are parsed.
+func setupLogging() error {
+	// cmd.RunWebhookServer disables contextual logging, which the
+	// per-challenge loggers rely on.
+	klog.EnableContextualLogging(true)
+
+	switch *logFormat {
+	case "text":
+		// klog writes to stderr itself unless logtostderr is off, and then
+		// once to its output, which scrubs the credentials, with one_output.
+		for name, value := range map[string]string{"logtostderr": "false", "one_output": "true", "stderrthreshold": "FATAL"} {
+			if err := klogFlags.Set(name, value); err != nil {
+				return err
+			}
+		}
+		klog.SetOutput(redactingWriter{w: os.Stderr})
+	case "json":
+		verbosity := klogFlags.Lookup("v").Value.(flag.Getter).Get().(klog.Level)
+		logger, _ := logsjson.Factory{}.Create(config.LoggingConfiguration{
+			Verbosity: config.VerbosityLevel(verbosity),
+		})
+		klog.SetLogger(redactingLogger(logger))
+	default:
+		return fmt.Errorf("invalid log format %q, must be text or json", *logFormat)
+	}
+	return nil
+}
+
+// challengeLogContext returns ctx with a logger holding the fields of the
+// challenge, for the given action.
+func challengeLogContext(ctx context.Context, action string, ch *v1alpha1.ChallengeRequest) context.Context {
+	logger := klog.LoggerWithValues(klog.Background(),
+		"action", action,
+		"fqdn", ch.ResolvedFQDN,
+		"zone", ch.ResolvedZone,
+		"namespace", ch.ResourceNamespace,
+	)
+	return klog.NewContext(ctx, logger)
+}
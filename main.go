@@ -5,35 +5,42 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
 
 	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
-	"github.com/cert-manager/cert-manager/pkg/acme/webhook/cmd"
 	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+
+	_ "github.com/baarde/cert-manager-webhook-dd/providers"
 )
 
 var GroupName = os.Getenv("GROUP_NAME")
 
 func main() {
-	if GroupName == "" {
-		panic("GROUP_NAME must be specified")
+	root := newRootCommand()
+	root.SetArgs(compatArgs(os.Args[1:]))
+	if isDdctl(os.Args[0]) {
+		root = newDdctlCommand()
+		root.SetArgs(os.Args[1:])
+	}
+	if err := root.Execute(); err != nil {
+		klog.ErrorS(err, "Error executing command")
+		klog.Flush()
+		os.Exit(1)
 	}
-
-	// This will register our dondominio DNS provider with the webhook serving
-	// library, making it available as an API under the provided GroupName.
-	// You can register multiple DNS provider implementations with a single
-	// webhook, where the Name() method will be used to disambiguate between
-	// the different implementations.
-	cmd.RunWebhookServer(GroupName,
-		&ddDNSProviderSolver{},
-	)
 }
 
 // ddDNSProviderSolver implements the provider-specific logic needed to
@@ -41,7 +48,101 @@ func main() {
 // To do so, it must implement the `github.com/jetstack/cert-manager/pkg/acme/webhook.Solver`
 // interface.
 type ddDNSProviderSolver struct {
-	client *kubernetes.Clientset
+	client kubernetes.Interface
+
+	// ledger tracks the operations in flight and the records created
+	ledger ledger
+
+	// stats counts the unique FQDNs presented per zone and per day
+	stats issuanceStats
+
+	// zoneHealth tracks the outcomes of the recent challenges of each zone
+	zoneHealth zoneHealth
+
+	// ctx is cancelled when the webhook stops
+	ctx context.Context
+
+	// inFlight counts the Present and CleanUp calls, drained on shutdown
+	inFlight inFlightCalls
+
+	// adaptiveTimeout is shared by all the clients, nil if disabled
+	adaptiveTimeout *AdaptiveTimeout
+
+	// limiter is shared by all the clients, nil if unlimited
+	limiter *RequestLimiter
+
+	// rateLimits holds the token buckets of the accounts, nil if unlimited
+	rateLimits *accountRateLimits
+
+	// hooks are called around Present and CleanUp
+	hooks hookList
+
+	// provider, if set, is used instead of the DonDominio client of the
+	// issuer
+	provider DNSProvider
+
+	// broker caches the credentials obtained from the credentials brokers
+	broker credentialsBroker
+
+	// clients caches the DonDominio clients by endpoint and credentials
+	clients clientCache
+
+	// verifier runs the DNS checks of the propagation waits
+	verifier verifierPool
+
+	// zones serializes the record mutations of each zone
+	zones keyedMutex
+	// inFlightCalls shares the result of the Present and CleanUp calls with
+	// the identical calls made while they are in flight
+	inFlightCalls callGroup
+
+	// batcher coalesces the lookups of the concurrent challenges of each
+	// zone, nil if disabled
+	batcher *zoneBatcher
+
+	// secrets serves the secrets referenced by the issuers from informers
+	secrets secretCache
+
+	// secretsDir is the directory the secrets are read from instead of the
+	// API, if any
+	secretsDir string
+
+	// secretNamespaces are the namespaces the secrets may be read from, nil
+	// if any
+	secretNamespaces map[string]bool
+
+	// initOnce makes the solvers sharing the solver initialize it once
+	initOnce sync.Once
+	initErr  error
+}
+
+// newSolver returns a solver calling the given hooks around each challenge.
+func newSolver(hooks ...Hooks) *ddDNSProviderSolver {
+	return &ddDNSProviderSolver{hooks: hooks}
+}
+
+// challengeContext returns the context of the DonDominio API calls of a
+// Present or CleanUp call. It is cancelled when ctx is done or after the
+// request timeout.
+func challengeContext(ctx context.Context, cfg *ddDNSProviderConfig) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, cfg.requestTimeout())
+}
+
+// stopContext returns a context cancelled when the webhook stops.
+func (s *ddDNSProviderSolver) stopContext() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// challengeError makes explicit that err is caused by the request timeout,
+// so that the Challenge status tells why cert-manager retries it.
+func challengeError(ctx context.Context, cfg *ddDNSProviderConfig, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("DonDominio API calls did not complete within the %s request timeout, the challenge will be retried: %w", cfg.requestTimeout(), err)
+	}
+	return err
 }
 
 // ddDNSProviderConfig is a structure that is used to decode into when
@@ -59,27 +160,193 @@ type ddDNSProviderSolver struct {
 // be used by your provider here, you should reference a Kubernetes Secret
 // resource and fetch these credentials using a Kubernetes clientset.
 type ddDNSProviderConfig struct {
+	// APIVersion and Kind identify the version of the config, see
+	// configAPIVersion. They are set when a legacy config is converted.
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+
 	Endpoint             string                   `json:"endpoint"`
 	ApplicationKey       string                   `json:"applicationKey"`
 	ApplicationSecretRef corev1.SecretKeySelector `json:"applicationSecretRef"`
+	// ApplicationKeyRef, if set, is the secret holding the application key,
+	// which replaces ApplicationKey.
+	ApplicationKeyRef *corev1.SecretKeySelector `json:"applicationKeyRef,omitempty"`
+	// CredentialsSecretRef, if set, is a secret holding all the credentials
+	// in its apiUser and apiPassword keys, and optionally the endpoint in
+	// its endpoint key. It replaces ApplicationKey, ApplicationKeyRef and
+	// ApplicationSecretRef.
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+	// AuthScheme is how the credentials are sent to DonDominio: form, the
+	// default, or hmac to sign the requests with the API password instead
+	// of sending it.
+	AuthScheme string `json:"authScheme,omitempty"`
+	// ConflictPolicy controls what Present does when a TXT record with the
+	// same name but a different value already exists. It defaults to append.
+	ConflictPolicy string `json:"conflictPolicy,omitempty"`
+	// RequestTimeout bounds the time spent calling DonDominio for a single
+	// Present or CleanUp. It defaults to the --request-timeout flag.
+	RequestTimeout *metav1.Duration `json:"requestTimeout,omitempty"`
+	// WaitForPropagation makes Present wait for the challenge record to be
+	// visible in DNS. It defaults to the --wait-for-propagation flag.
+	WaitForPropagation *bool `json:"waitForPropagation,omitempty"`
+	// PropagationInterval, PropagationTimeout and PropagationResolvers
+	// override the --propagation-* flags.
+	PropagationInterval  *metav1.Duration `json:"propagationInterval,omitempty"`
+	PropagationTimeout   *metav1.Duration `json:"propagationTimeout,omitempty"`
+	PropagationResolvers []string         `json:"propagationResolvers,omitempty"`
+	// ChallengeAliasDomain is the DonDominio domain to which the
+	// _acme-challenge names are delegated with a CNAME, e.g.
+	// _acme-challenge.example.com CNAME _acme-challenge.validation.example.org
+	// for validation.example.org. The challenge records are created in this
+	// domain instead of the domain of the certificate.
+	ChallengeAliasDomain string `json:"challengeAliasDomain,omitempty"`
+	// FollowCNAME makes the challenge records be created at the end of the
+	// chain of CNAME records of the challenge FQDN, if any.
+	FollowCNAME bool `json:"followCNAME,omitempty"`
+	// CredentialsBroker, if set, replaces ApplicationKey and
+	// ApplicationSecretRef: the credentials are obtained from the broker in
+	// exchange for the service account token of the webhook.
+	CredentialsBroker *credentialsBrokerConfig `json:"credentialsBroker,omitempty"`
+	// ExternalDNSRegistry, if set, makes the webhook write ownership records
+	// in the TXT registry format of external-dns next to its challenge
+	// records.
+	ExternalDNSRegistry *externalDNSRegistryConfig `json:"externalDNSRegistry,omitempty"`
+	// DryRun makes the challenges of the issuer perform their lookups and
+	// validation but only log the records they would create or delete, as
+	// the --dry-run flag does for all the issuers.
+	DryRun bool `json:"dryRun,omitempty"`
+	// CAA, if set, makes Present create the CAA records authorizing the
+	// ACME CA at the apex of the zone if they are missing.
+	CAA *caaConfig `json:"caa,omitempty"`
+	// Reseller, if set, makes every API call impersonate a customer
+	// sub-account of the DonDominio reseller account of the credentials.
+	Reseller *resellerConfig `json:"reseller,omitempty"`
+	// RecordTTL is the TTL of the challenge records. It defaults to the
+	// default TTL of the zone. When set, the propagation timeout defaults to
+	// the TTL plus the --propagation-ttl-margin flag.
+	RecordTTL *metav1.Duration `json:"recordTTL,omitempty"`
+	// SkipServiceCheck skips checking that the zone is an active DonDominio
+	// service before creating the challenge record. A zone that is not
+	// active then fails on the record creation instead.
+	SkipServiceCheck bool `json:"skipServiceCheck,omitempty"`
+	// DomainCredentials maps domains to the credentials of the names under
+	// them, so that an issuer can solve the challenges of domains spread
+	// across several DonDominio accounts. The longest matching domain is
+	// used, the names under none of them use the credentials above.
+	DomainCredentials map[string]domainCredentials `json:"domainCredentials,omitempty"`
+	// AllowedDomains, if not empty, are the only domains whose challenges
+	// are solved. DeniedDomains are never solved, even if allowed. A domain
+	// prefixed with "*." matches the names under it.
+	AllowedDomains []string `json:"allowedDomains,omitempty"`
+	DeniedDomains  []string `json:"deniedDomains,omitempty"`
+	// RetryPolicy overrides the --retry-* flags for the DonDominio API
+	// requests of the issuer.
+	RetryPolicy *retryPolicyConfig `json:"retryPolicy,omitempty"`
+
+	// allowAmbientCredentials is set from the challenge request: the missing
+	// credentials are then read from the environment and the configuration
+	// files.
+	allowAmbientCredentials bool
+}
+
+// acmeChallengeLabel is the label prefixed to a name to get the name of its
+// challenge record.
+const acmeChallengeLabel = "_acme-challenge"
+
+// challengeFQDN returns the FQDN of the challenge record, in lowercase
+// punycode. The service and record names of the challenges are all derived
+// from it, and DonDominio does not find services whose name has another
+// casing. The other names, of the ddctl arguments, the --orphan-gc-zones
+// flag and the domains of the config, go through normalizeFQDN too.
+func (cfg *ddDNSProviderConfig) challengeFQDN(ch *v1alpha1.ChallengeRequest) (string, error) {
+	if cfg.ChallengeAliasDomain != "" {
+		return normalizeFQDN(acmeChallengeLabel + "." + util.ToFqdn(cfg.ChallengeAliasDomain))
+	}
+	fqdn, err := normalizeFQDN(ch.ResolvedFQDN)
+	if err != nil {
+		return "", err
+	}
+	fqdn = challengeName(fqdn)
+	if !cfg.FollowCNAME {
+		return fqdn, nil
+	}
+	target, err := followCNAMEs(fqdn, util.RecursiveNameservers)
+	if err != nil {
+		return "", err
+	}
+	// The targets keep the casing with which they were written in the zone.
+	return normalizeFQDN(target)
+}
+
+// dryRun returns whether the record creations and deletions of the
+// challenges are only logged.
+func (cfg *ddDNSProviderConfig) dryRun() bool {
+	return cfg.DryRun || *dryRunFlag
+}
+
+func (cfg *ddDNSProviderConfig) waitForPropagation() bool {
+	if cfg.WaitForPropagation != nil {
+		return *cfg.WaitForPropagation
+	}
+	return *waitForPropagationFlag
+}
+
+// recordTTL returns the TTL of the challenge records, zero for the default
+// TTL of the zone.
+func (cfg *ddDNSProviderConfig) recordTTL() time.Duration {
+	if cfg.RecordTTL != nil {
+		return cfg.RecordTTL.Duration
+	}
+	return 0
+}
+
+func (cfg *ddDNSProviderConfig) requestTimeout() time.Duration {
+	if cfg.RequestTimeout != nil {
+		return cfg.RequestTimeout.Duration
+	}
+	return *requestTimeout
+}
+
+// Conflict policies, see ddDNSProviderConfig.ConflictPolicy.
+const (
+	// conflictPolicyAppend keeps the existing records, which is what ACME
+	// expects when several orders are validated for the same name.
+	conflictPolicyAppend = "append"
+	// conflictPolicyReplace deletes the existing records before creating the
+	// new one. Only use it if a single order is ever active per name.
+	conflictPolicyReplace = "replace"
+	// conflictPolicyUpdate updates one of the existing records in place with
+	// the new value, and deletes the others. Like replace, only use it if a
+	// single order is ever active per name.
+	conflictPolicyUpdate = "update"
+	// conflictPolicyFail refuses to create the new record.
+	conflictPolicyFail = "fail"
+)
+
+// ddResponse holds the fields common to every DonDominio API response.
+type ddResponse struct {
+	Success      bool     `json:"success"`
+	ErrorCode    int64    `json:"errorCode"`
+	ErrorCodeMsg string   `json:"errorCodeMsg"`
+	Action       string   `json:"action"`
+	Version      string   `json:"version"`
+	Messages     []string `json:"messages,omitempty"`
+	// ResponseData is the data of the calls whose response type does not
+	// decode it, kept so that it is not reported as an unexpected field.
+	ResponseData json.RawMessage `json:"responseData,omitempty"`
+}
+
+func (r *ddResponse) response() *ddResponse {
+	return r
 }
 
 type ddServiceInfo struct {
-	Success      bool                  `json:"success"`
-	ErrorCode    int64                 `json:"errorCode"`
-	ErrorCodeMsg string                `json:"errorCodeMsg"`
-	Action       string                `json:"action"`
-	Version      string                `json:"version"`
+	ddResponse
 	ResponseData ddServiceInfoResponse `json:"responseData"`
 }
 
 type ddServiceList struct {
-	Success      bool                  `json:"success"`
-	ErrorCode    int64                 `json:"errorCode"`
-	ErrorCodeMsg string                `json:"errorCodeMsg"`
-	Action       string                `json:"action"`
-	Version      string                `json:"version"`
-	Messages     []string              `json:"messages,omitempty"`
+	ddResponse
 	ResponseData ddServiceListResponse `json:"responseData"`
 }
 
@@ -125,11 +392,23 @@ type ddCreateServiceParams struct {
 	ServiceName string `schema:"serviceName"`
 	Name        string `schema:"name"`
 	Value       string `schema:"value"`
+	TTL         int    `schema:"ttl,omitempty"`
+	Priority    *int   `schema:"priority,omitempty"`
 }
 
 type ddServiceListParams struct {
 	ServiceName string `schema:"serviceName"`
-	FilterValue string `schema:"filterValue"`
+	FilterName  string `schema:"filterName,omitempty"`
+	FilterValue string `schema:"filterValue,omitempty"`
+	Page        int    `schema:"page,omitempty"`
+}
+
+type ddUpdateServiceParams struct {
+	ServiceName string `schema:"serviceName"`
+	EntityId    string `schema:"entityID"`
+	Value       string `schema:"value,omitempty"`
+	TTL         int    `schema:"ttl,omitempty"`
+	Priority    *int   `schema:"priority,omitempty"`
 }
 
 type ddDeleteServiceParams struct {
@@ -144,56 +423,250 @@ type ddDeleteServiceParams struct {
 // within a single webhook deployment**.
 // For example, `cloudflare` may be used as the name of a solver.
 func (s *ddDNSProviderSolver) Name() string {
-	return "don-dominio"
+	return solverName
 }
 
+// validate checks the config of a challenge, reporting all its problems.
 func (s *ddDNSProviderSolver) validate(cfg *ddDNSProviderConfig, allowAmbientCredentials bool) error {
-	if allowAmbientCredentials {
-		// When allowAmbientCredentials is true, DD client can load missing config
-		// values from the environment variables and the dondominio.conf files.
-		return nil
+	return configError(validateConfig(cfg, allowAmbientCredentials))
+}
+
+// config loads and validates the configuration of the challenge request.
+func (s *ddDNSProviderSolver) config(ch *v1alpha1.ChallengeRequest) (*ddDNSProviderConfig, error) {
+	cfg, unknownFields, err := loadConfig(ch.Config)
+	if err != nil {
+		return nil, err
 	}
-	if cfg.Endpoint == "" {
-		return errors.New("no endpoint provided in DonDominio config")
+
+	cfg.allowAmbientCredentials = ch.AllowAmbientCredentials
+	cfg.setDefaults()
+
+	// Report the unknown fields together with the invalid ones
+	allErrs := append(unknownFields, validateConfig(&cfg, ch.AllowAmbientCredentials)...)
+	if err := configError(allErrs); err != nil {
+		return nil, err
 	}
-	if cfg.ApplicationKey == "" {
-		return errors.New("no application key provided in DonDominio config")
+
+	return &cfg, nil
+}
+
+// dnsProvider returns the provider of the challenge records of the issuer.
+// Its mutations of a same zone are serialized across all the challenges.
+func (s *ddDNSProviderSolver) dnsProvider(ctx context.Context, cfg *ddDNSProviderConfig, namespace string) (DNSProvider, error) {
+	provider := s.provider
+	if provider == nil {
+		ddClient, err := s.ddClient(ctx, cfg, namespace)
+		if err != nil {
+			return nil, err
+		}
+		klog.FromContext(ctx).V(2).Info("Using the DonDominio credentials", "credential", ddClient.CredentialFingerprint())
+		provider = ddClient
 	}
-	if cfg.ApplicationSecretRef.Name == "" {
-		return errors.New("no application secret provided in DonDominio config")
+	return s.mutatingProvider(provider, cfg.dryRun()), nil
+}
+
+// mutatingProvider wraps the provider of every caller modifying the zones,
+// the challenges and the orphan collector: its lookups are batched, and its
+// mutations are only logged if dryRun, or else serialized by zone.
+func (s *ddDNSProviderSolver) mutatingProvider(provider DNSProvider, dryRun bool) DNSProvider {
+	if s.batcher != nil {
+		provider = batchedProvider{DNSProvider: provider, batcher: s.batcher}
 	}
-	return nil
+	if dryRun {
+		return dryRunProvider{DNSProvider: provider}
+	}
+	return zoneSerializedProvider{DNSProvider: provider, zones: &s.zones}
 }
 
-func (s *ddDNSProviderSolver) ddClient(ch *v1alpha1.ChallengeRequest) (*Client, error) {
-	cfg, err := loadConfig(ch.Config)
-	if err != nil {
-		return nil, err
+func (s *ddDNSProviderSolver) ddClient(ctx context.Context, cfg *ddDNSProviderConfig, namespace string) (*Client, error) {
+	endpoint := cfg.Endpoint
+	applicationKey := cfg.ApplicationKey
+	var applicationSecret string
+	if cfg.CredentialsSecretRef != nil {
+		creds, err := s.credentialsSecret(ctx, cfg.CredentialsSecretRef.Name, namespace)
+		if err != nil {
+			return nil, err
+		}
+		applicationKey, applicationSecret = creds.APIUser, creds.APIPassword
+		if creds.Endpoint != "" {
+			endpoint = creds.Endpoint
+		}
+	} else if cfg.CredentialsBroker != nil {
+		creds, err := s.broker.credentials(ctx, cfg.CredentialsBroker)
+		if err != nil {
+			return nil, err
+		}
+		applicationKey, applicationSecret = creds.APIUser, creds.APIPassword
+	} else {
+		var err error
+		if cfg.ApplicationKeyRef != nil {
+			applicationKey, err = s.secret(ctx, *cfg.ApplicationKeyRef, namespace, defaultKeySecretKeys)
+			if err != nil {
+				return nil, err
+			}
+		}
+		applicationSecret, err = s.secret(ctx, cfg.ApplicationSecretRef, namespace, defaultSecretKeys)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if applicationKey == "" || applicationSecret == "" {
+		// Without ambient credentials, NewClient must not complete them from
+		// the environment of the webhook.
+		if !cfg.allowAmbientCredentials {
+			return nil, errors.New("empty application key or secret in DonDominio config")
+		}
+		// Resolved before the client cache so that the rotated credentials
+		// get a new client.
+		ambient := ambientConfigs.get(endpoint)
+		if applicationKey == "" {
+			applicationKey = ambient.APIUser
+		}
+		if applicationSecret == "" {
+			applicationSecret = ambient.APIPassword
+		}
+		endpoint = ambient.Endpoint
 	}
 
-	err = s.validate(&cfg, ch.AllowAmbientCredentials)
-	if err != nil {
-		return nil, err
+	return s.cachedClient(endpoint, applicationKey, applicationSecret, cfg.AuthScheme, cfg.retryPolicy(), cfg.Reseller.params())
+}
+
+// cachedClient returns the client of the endpoint, credentials,
+// authentication scheme, retry policy and extra parameters, created with the
+// settings of the command line flags if it is not cached.
+func (s *ddDNSProviderSolver) cachedClient(endpoint, applicationKey, applicationSecret, authScheme string, policy RetryPolicy, params url.Values) (*Client, error) {
+	return s.clients.get(endpoint, applicationKey, applicationSecret, authScheme, policy, params, func() (*Client, error) {
+		ddClient, err := NewClient(endpoint, applicationKey, applicationSecret)
+		if err != nil {
+			return nil, err
+		}
+		// The credentials may have been completed from the ambient ones
+		ddClient.Auth = newAuthenticator(authScheme, ddClient.AppKey, ddClient.AppSecret)
+		ddClient.RetryPolicy = policy
+		ddClient.ExtraParams = params
+		// --dry-run implies --read-only for the calls not made through
+		// mutatingProvider
+		ddClient.ReadOnly = *readOnly || *dryRunFlag
+		ddClient.AdaptiveTimeout = s.adaptiveTimeout
+		ddClient.Limiter = s.limiter
+		ddClient.RateLimiter = s.rateLimits.forAccount(ddClient.AppKey)
+		ddClient.Quota = s.rateLimits.quotaForAccount(ddClient.AppKey)
+		ddClient.ServiceCacheTTL = *serviceCacheTTL
+		// Checked by Initialize
+		ddClient.MaxResponseSize, _ = maxResponseSizeBytes()
+		ddClient.StrictDecoding = *strictResponses
+		ddClient.UserAgent = currentBuild().userAgent()
+		return ddClient, nil
+	})
+}
+
+// Keys of the secret referenced by credentialsSecretRef.
+const (
+	credentialsSecretAPIUser     = "apiUser"
+	credentialsSecretAPIPassword = "apiPassword"
+	credentialsSecretEndpoint    = "endpoint"
+)
+
+// secretCredentials are the credentials read from a credentialsSecretRef.
+type secretCredentials struct {
+	APIUser     string
+	APIPassword string
+	// Endpoint is empty if the secret does not set it
+	Endpoint string
+}
+
+// errSecretNamespaceNotAllowed is returned when a secret is referenced in a
+// namespace not allowed by --secret-namespace.
+var errSecretNamespaceNotAllowed = errors.New("secrets cannot be read from this namespace")
+
+// secretNamespaceSet returns the set of the namespaces of a comma separated
+// list, or nil if it is empty.
+func secretNamespaceSet(list string) map[string]bool {
+	var namespaces map[string]bool
+	for _, namespace := range strings.Split(list, ",") {
+		namespace = strings.TrimSpace(namespace)
+		if namespace == "" {
+			continue
+		}
+		if namespaces == nil {
+			namespaces = make(map[string]bool)
+		}
+		namespaces[namespace] = true
+	}
+	return namespaces
+}
+
+// getSecret returns the secret namespace/name, if --secret-namespace allows
+// its namespace. It is read from --secrets-dir if set.
+func (s *ddDNSProviderSolver) getSecret(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
+	if s.secretNamespaces != nil && !s.secretNamespaces[namespace] {
+		return nil, fmt.Errorf("secret '%s/%s': %w", namespace, name, errSecretNamespaceNotAllowed)
+	}
+	if s.secretsDir != "" {
+		return readLocalSecret(s.secretsDir, namespace, name)
 	}
+	return s.secrets.get(ctx, s.client, namespace, name)
+}
 
-	applicationSecret, err := s.secret(cfg.ApplicationSecretRef, ch.ResourceNamespace)
+// credentialsSecret reads the credentials of the secret namespace/name,
+// failing with the list of the required keys missing or empty.
+func (s *ddDNSProviderSolver) credentialsSecret(ctx context.Context, name, namespace string) (*secretCredentials, error) {
+	secret, err := s.getSecret(ctx, namespace, name)
 	if err != nil {
 		return nil, err
 	}
+	value := func(key string) string {
+		return strings.TrimSuffix(string(secret.Data[key]), "\n")
+	}
 
-	return NewClient(cfg.Endpoint, cfg.ApplicationKey, applicationSecret)
+	creds := &secretCredentials{
+		APIUser:     value(credentialsSecretAPIUser),
+		APIPassword: value(credentialsSecretAPIPassword),
+		Endpoint:    value(credentialsSecretEndpoint),
+	}
+	var missing []string
+	if creds.APIUser == "" {
+		missing = append(missing, credentialsSecretAPIUser)
+	}
+	if creds.APIPassword == "" {
+		missing = append(missing, credentialsSecretAPIPassword)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("credentials secret '%s/%s' has no or empty keys %q", namespace, name, missing)
+	}
+	return creds, nil
 }
 
-func (s *ddDNSProviderSolver) secret(ref corev1.SecretKeySelector, namespace string) (string, error) {
+// defaultSecretKeys are the keys tried in order when the key of the
+// applicationSecretRef is omitted, as found in secrets produced by external
+// secret operators.
+var defaultSecretKeys = []string{"api-password", "password", "secret"}
+
+// defaultKeySecretKeys are the keys tried in order when the key of the
+// applicationKeyRef is omitted.
+var defaultKeySecretKeys = []string{"api-user", "username", "user"}
+
+// secret returns the value of the key of the secret referenced by ref, or of
+// the first of defaultKeys found in the secret if ref has no key.
+func (s *ddDNSProviderSolver) secret(ctx context.Context, ref corev1.SecretKeySelector, namespace string, defaultKeys []string) (string, error) {
 	if ref.Name == "" {
 		return "", nil
 	}
 
-	secret, err := s.client.CoreV1().Secrets(namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+	secret, err := s.getSecret(ctx, namespace, ref.Name)
 	if err != nil {
 		return "", err
 	}
 
+	if ref.Key == "" {
+		for _, key := range defaultKeys {
+			if bytes, ok := secret.Data[key]; ok {
+				return strings.TrimSuffix(string(bytes), "\n"), nil
+			}
+		}
+		return "", fmt.Errorf("none of the keys %q found in secret '%s/%s'", defaultKeys, namespace, ref.Name)
+	}
+
 	bytes, ok := secret.Data[ref.Key]
 	if !ok {
 		return "", fmt.Errorf("key not found %q in secret '%s/%s'", ref.Key, namespace, ref.Name)
@@ -206,16 +679,118 @@ func (s *ddDNSProviderSolver) secret(ref corev1.SecretKeySelector, namespace str
 // This method should tolerate being called multiple times with the same value.
 // cert-manager itself will later perform a self check to ensure that the
 // solver has correctly configured the DNS provider.
-func (s *ddDNSProviderSolver) Present(ch *v1alpha1.ChallengeRequest) error {
-	ddClient, err := s.ddClient(ch)
+func (s *ddDNSProviderSolver) Present(ch *v1alpha1.ChallengeRequest) (err error) {
+	if !s.inFlight.begin() {
+		return errShuttingDown
+	}
+	defer s.inFlight.end()
+	defer s.ledger.begin("present", ch.ResolvedFQDN)()
+
+	ctx, span := startChallengeSpan(auditContext(challengeLogContext(s.stopContext(), "present", ch), ch), "Present", ch)
+	defer func() { endSpan(span, err) }()
+
+	err = s.hooks.BeforePresent(ctx, ch)
+	if err == nil {
+		err = classify(withHint(s.dedupChallenge(ctx, "present", ch, s.presentChallenge)))
+	}
+	err = redactError(err)
 	if err != nil {
+		klog.FromContext(ctx).Error(err, "Present failed", "class", errorClass(err))
+		s.hooks.OnError(ctx, "present", ch, err)
 		return err
 	}
-	fmt.Printf("ResolvedZone: %s, ResolvedFQDN: %s\n", ch.ResolvedZone, ch.ResolvedFQDN)
-	domain := getDomain(ch.ResolvedFQDN)
-	subDomain := getSubDomain(domain, ch.ResolvedFQDN)
+	s.hooks.AfterPresent(ctx, ch)
+	return nil
+}
+
+func (s *ddDNSProviderSolver) presentChallenge(parent context.Context, ch *v1alpha1.ChallengeRequest) error {
+	cfg, err := s.config(ch)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := challengeContext(parent, cfg)
+	defer cancel()
+
+	fqdn, err := cfg.challengeFQDN(ch)
+	if err != nil {
+		return err
+	}
+	// Checked before any DonDominio API call
+	if err := cfg.checkDomain(fqdn); err != nil {
+		return err
+	}
+	zone := getDomain(fqdn)
+	err = challengeError(ctx, cfg, s.present(ctx, cfg, ch, fqdn))
+	if err != nil {
+		s.observeChallenge(ctx, cfg, zone, stagePresent)
+		return err
+	}
+	// No record to wait for in dry run
+	if !cfg.waitForPropagation() || cfg.dryRun() {
+		s.observeChallenge(ctx, cfg, zone, "")
+		return nil
+	}
+
+	settings := cfg.propagationSettings()
+	if cfg.RecordTTL != nil && settings.Timeout < cfg.RecordTTL.Duration {
+		klog.FromContext(ctx).Info("Propagation timeout shorter than the TTL of the challenge record, resolvers may not see the record in time",
+			"propagationTimeout", settings.Timeout, "recordTTL", cfg.RecordTTL.Duration)
+	}
+
+	// The propagation wait does not call DonDominio and is therefore not
+	// bounded by the request timeout.
+	err = s.verifier.waitForPropagation(parent, zone, fqdn, ch.Key, settings)
+	if err != nil {
+		s.observeChallenge(ctx, cfg, zone, stagePropagation)
+		return err
+	}
+	s.observeChallenge(ctx, cfg, zone, "")
+	return nil
+}
+
+func (s *ddDNSProviderSolver) present(ctx context.Context, cfg *ddDNSProviderConfig, ch *v1alpha1.ChallengeRequest, fqdn string) error {
+	provider, err := s.dnsProvider(ctx, cfg.forName(fqdn), ch.ResourceNamespace)
+	if err != nil {
+		return err
+	}
+	domain := getDomain(fqdn)
+	subDomain := getSubDomain(domain, fqdn)
 	target := ch.Key
-	return addTXTRecord(ddClient, domain, subDomain, target)
+	unlock, err := s.ledger.lockName(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if !cfg.SkipServiceCheck {
+		err = provider.ValidateZone(ctx, domain)
+		if err != nil {
+			return err
+		}
+	}
+	if cfg.CAA != nil {
+		if err := ensureCAA(ctx, provider, domain, cfg.CAA); err != nil {
+			return fmt.Errorf("failed to ensure the CAA records of zone %s: %w", domain, err)
+		}
+	}
+	record, err := addTXTRecord(ctx, provider, domain, subDomain, target, cfg.recordTTL(), cfg.ConflictPolicy, s.ledger.pendingKeys(fqdn))
+	if err != nil {
+		return err
+	}
+	if cfg.ExternalDNSRegistry != nil {
+		err = claimOwnership(ctx, provider, cfg.ExternalDNSRegistry, domain, recordName(domain, subDomain))
+		if err != nil {
+			return err
+		}
+	}
+	if cfg.dryRun() {
+		klog.FromContext(ctx).Info("Dry run, challenge record not presented", "record", recordName(domain, subDomain))
+		return nil
+	}
+
+	s.ledger.presented(challengeKey{FQDN: fqdn, Key: ch.Key}, record.ID)
+	s.stats.presented(domain, fqdn, time.Now())
+	klog.FromContext(ctx).Info("Challenge record presented", "record", recordName(domain, subDomain))
+	return nil
 }
 
 // CleanUp should delete the relevant TXT record from the DNS provider console.
@@ -224,14 +799,82 @@ func (s *ddDNSProviderSolver) Present(ch *v1alpha1.ChallengeRequest) error {
 // value provided on the ChallengeRequest should be cleaned up.
 // This is in order to facilitate multiple DNS validations for the same domain
 // concurrently.
-func (s *ddDNSProviderSolver) CleanUp(ch *v1alpha1.ChallengeRequest) error {
-	ddClient, err := s.ddClient(ch)
+func (s *ddDNSProviderSolver) CleanUp(ch *v1alpha1.ChallengeRequest) (err error) {
+	if !s.inFlight.begin() {
+		return errShuttingDown
+	}
+	defer s.inFlight.end()
+	defer s.ledger.begin("cleanup", ch.ResolvedFQDN)()
+
+	ctx, span := startChallengeSpan(auditContext(challengeLogContext(s.stopContext(), "cleanup", ch), ch), "CleanUp", ch)
+	defer func() { endSpan(span, err) }()
+
+	err = s.hooks.BeforeCleanUp(ctx, ch)
+	if err == nil {
+		err = classify(withHint(s.dedupChallenge(ctx, "cleanup", ch, s.cleanUpChallenge)))
+	}
+	err = redactError(err)
 	if err != nil {
+		klog.FromContext(ctx).Error(err, "CleanUp failed", "class", errorClass(err))
+		s.hooks.OnError(ctx, "cleanup", ch, err)
 		return err
 	}
-	domain := getDomain(ch.ResolvedFQDN)
-	target := ch.Key
-	return removeTXTRecord(ddClient, domain, target)
+	s.hooks.AfterCleanUp(ctx, ch)
+	return nil
+}
+
+func (s *ddDNSProviderSolver) cleanUpChallenge(parent context.Context, ch *v1alpha1.ChallengeRequest) error {
+	cfg, err := s.config(ch)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := challengeContext(parent, cfg)
+	defer cancel()
+
+	fqdn, err := cfg.challengeFQDN(ch)
+	if err != nil {
+		return err
+	}
+	if err := cfg.checkDomain(fqdn); err != nil {
+		return err
+	}
+	return challengeError(ctx, cfg, s.cleanUp(ctx, cfg, ch, fqdn))
+}
+
+func (s *ddDNSProviderSolver) cleanUp(ctx context.Context, cfg *ddDNSProviderConfig, ch *v1alpha1.ChallengeRequest, fqdn string) error {
+	provider, err := s.dnsProvider(ctx, cfg.forName(fqdn), ch.ResourceNamespace)
+	if err != nil {
+		return err
+	}
+	domain := getDomain(fqdn)
+	key := challengeKey{FQDN: fqdn, Key: ch.Key}
+	unlock, err := s.ledger.lockName(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// The records known to have been created for the challenge are deleted
+	// by their IDs, the zone is only searched for the others
+	created := s.ledger.createdRecords(key)
+	result, exact, err := deleteCreated(ctx, provider, domain, fqdn, created)
+	if err == nil && !exact {
+		result, err = ensureAbsent(ctx, provider, domain, fqdn, ch.Key, created)
+	}
+	if err == nil && (cfg.ConflictPolicy == conflictPolicyReplace || cfg.ConflictPolicy == conflictPolicyUpdate) && !result.ZoneMissing {
+		// The conflicting records that Present would have replaced, e.g.
+		// left by an earlier challenge, go away with the challenge record
+		_, err = resolveConflicts(ctx, provider, domain, result.Record, ch.Key, conflictPolicyReplace, s.ledger.pendingKeys(fqdn))
+	}
+	if err == nil && cfg.ExternalDNSRegistry != nil && !result.ZoneMissing {
+		err = releaseOwnership(ctx, provider, cfg.ExternalDNSRegistry, domain, result.Record)
+	}
+	if err != nil {
+		s.ledger.deletionFailed(key, err)
+		return err
+	}
+
+	s.ledger.cleanedUp(key)
+	return nil
 }
 
 // Initialize will be called when the webhook first starts.
@@ -243,29 +886,178 @@ func (s *ddDNSProviderSolver) CleanUp(ch *v1alpha1.ChallengeRequest) error {
 // provider accounts.
 // The stopCh can be used to handle early termination of the webhook, in cases
 // where a SIGTERM or similar signal is sent to the webhook process.
+// The solvers sharing s, e.g. the reseller one, initialize it once.
 func (s *ddDNSProviderSolver) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
+	s.initOnce.Do(func() {
+		s.initErr = s.initialize(kubeClientConfig, stopCh)
+	})
+	return s.initErr
+}
+
+func (s *ddDNSProviderSolver) initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
+	if err := setupLogging(); err != nil {
+		return err
+	}
+
 	client, err := kubernetes.NewForConfig(kubeClientConfig)
 	if err != nil {
 		return err
 	}
 
+	settings := transportFlags()
+	if err := settings.validate(); err != nil {
+		return err
+	}
+	settings.tune(sharedTransport)
+	if _, err := maxResponseSizeBytes(); err != nil {
+		return err
+	}
+
 	s.client = client
+	s.secrets.start(client, stopCh)
+	s.secretNamespaces = secretNamespaceSet(*secretNamespace)
+	s.secretsDir = *secretsDir
+
+	if err := setupAuditLog(*auditLogPath); err != nil {
+		return err
+	}
+	if err := setupAPIMessages(*apiMessagesMode, client, stopCh); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.ctx = ctx
+	if err := setupProxyCredentials(ctx, client); err != nil {
+		cancel()
+		return err
+	}
+	go func() {
+		<-stopCh
+		s.shutdown(*shutdownGracePeriod, cancel)
+	}()
+
+	s.verifier.size = *propagationWorkers
+
+	if *batchWindow > 0 {
+		s.batcher = &zoneBatcher{window: *batchWindow}
+	}
+
+	if *adaptiveTimeout {
+		s.adaptiveTimeout = &AdaptiveTimeout{Min: *adaptiveTimeoutMin, Max: *adaptiveTimeoutMax}
+	}
+
+	if *maxConcurrentRequests > 0 {
+		s.limiter = NewRequestLimiter(*maxConcurrentRequests)
+	}
+
+	if *rateLimit != "" || *accountRateLimitsFlag != "" || *quotaCheckInterval > 0 {
+		s.rateLimits, err = newAccountRateLimits(*rateLimit, *accountRateLimitsFlag, *rateLimitBurst)
+		if err != nil {
+			return err
+		}
+		if *quotaCheckInterval > 0 {
+			if err := s.rateLimits.withQuota(*quotaCheckInterval, *quotaThreshold, *quotaRateLimit); err != nil {
+				return err
+			}
+		}
+	}
+
+	if *issuanceStatsConfigMap != "" {
+		if err := s.loadIssuanceStats(*issuanceStatsConfigMap); err != nil {
+			klog.ErrorS(err, "Failed to load the issuance statistics", "configMap", *issuanceStatsConfigMap)
+		}
+		go s.runIssuanceStatsPersister(*issuanceStatsConfigMap, stopCh)
+	}
+
+	if *challengeStateConfigMap != "" {
+		if err := s.loadChallengeState(*challengeStateConfigMap); err != nil {
+			klog.ErrorS(err, "Failed to load the challenge records", "configMap", *challengeStateConfigMap)
+		}
+		go s.runChallengeStatePersister(*challengeStateConfigMap, stopCh)
+	}
+
+	if *metricsAddr != "" {
+		if err := metricsRegistry.Register(issuanceCollector{stats: &s.stats}); err != nil {
+			return err
+		}
+		go serveMetrics(*metricsAddr, http.HandlerFunc(s.serveState), stopCh)
+	}
+
+	if *probeAddr != "" {
+		go serveProbes(*probeAddr, s.readinessChecks(*probePing, *probeCacheTTL), *probeTimeout, stopCh)
+	}
+
+	if *debugAddr != "" {
+		if err := validateDebugAddr(*debugAddr); err != nil {
+			return err
+		}
+		go serveDebug(*debugAddr, stopCh)
+	}
+
+	if *otlpEndpoint != "" {
+		if err := setupTracing(*otlpEndpoint, *otlpInsecure, stopCh); err != nil {
+			return fmt.Errorf("failed to set up tracing: %w", err)
+		}
+	}
+
+	if err := setupHeapWatchdog(stopCh); err != nil {
+		return err
+	}
+
+	if *ambientCredentialsFile != "" {
+		if err := watchCredentialsFile(*ambientCredentialsFile, s.reloadAmbientCredentials, stopCh); err != nil {
+			return fmt.Errorf("failed to watch the credentials file: %w", err)
+		}
+		ambientConfigs.clear(true)
+	}
+
+	if *orphanGCInterval > 0 {
+		dynamicClient, err := dynamic.NewForConfig(kubeClientConfig)
+		if err != nil {
+			return err
+		}
+		activeKeys := func(ctx context.Context) (map[string]bool, error) {
+			return activeChallengeKeys(ctx, dynamicClient)
+		}
+		// Validated with the flags
+		zones, _ := orphanGCZoneList()
+		collector := newOrphanCollector(s, zones, *orphanGCMinAge, activeKeys)
+		collector.unowned = *orphanGCUnowned
+		collector.dryRun = *dryRunFlag
+		go collector.run(*orphanGCInterval, stopCh)
+	}
+
+	if *schemaCanaryInterval > 0 {
+		// The canary is not tied to an issuer, so it can only use the
+		// credentials found in the environment or configuration files.
+		if _, err := s.ambientClient(); err != nil {
+			klog.ErrorS(err, "Schema canary disabled")
+		} else {
+			go runSchemaCanary(s.ambientClient, *schemaCanaryInterval, stopCh)
+		}
+	}
+
 	return nil
 }
 
 // loadConfig is a small helper function that decodes JSON configuration into
-// the typed config struct.
-func loadConfig(cfgJSON *extapi.JSON) (ddDNSProviderConfig, error) {
+// the typed config struct, converted to the current version, and returns its
+// unknown fields.
+func loadConfig(cfgJSON *extapi.JSON) (ddDNSProviderConfig, field.ErrorList, error) {
 	cfg := ddDNSProviderConfig{}
 	// handle the 'base case' where no configuration has been provided
 	if cfgJSON == nil {
-		return cfg, nil
+		return cfg, nil, nil
 	}
-	if err := json.Unmarshal(cfgJSON.Raw, &cfg); err != nil {
-		return cfg, fmt.Errorf("error decoding DonDominio config: %v", err)
+	raw, err := convertConfig(cfgJSON.Raw)
+	if err != nil {
+		return cfg, nil, fmt.Errorf("error decoding DonDominio config: %v", err)
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, nil, fmt.Errorf("error decoding DonDominio config: %v", err)
 	}
 
-	return cfg, nil
+	return cfg, unknownConfigFields(raw), nil
 }
 
 func getDomain(fqdn string) string {
@@ -284,99 +1076,189 @@ func getDomain(fqdn string) string {
 	}
 }
 
+// challengeName returns the name of the challenge record of a requested
+// name: the name itself, or _acme-challenge.domain if it is the bare apex of
+// its domain. The CNAME targets are not passed through it, since they may
+// point at the apex of a zone dedicated to the challenges.
+func challengeName(fqdn string) string {
+	if util.UnFqdn(fqdn) == getDomain(fqdn) {
+		return acmeChallengeLabel + "." + util.ToFqdn(fqdn)
+	}
+	return fqdn
+}
+
+// getSubDomain returns the name of fqdn relative to domain, empty for the
+// apex of the domain.
 func getSubDomain(domain, fqdn string) string {
-	if idx := strings.Index(fqdn, "."+domain); idx != -1 {
-		return fqdn[:idx]
+	name := util.UnFqdn(fqdn)
+	if name == domain {
+		return ""
+	}
+	if strings.HasSuffix(name, "."+domain) {
+		return strings.TrimSuffix(name, "."+domain)
 	}
 
-	return util.UnFqdn(fqdn)
+	return name
 }
 
-func addTXTRecord(ddClient *Client, domain, subDomain, target string) error {
-	err := validateService(ddClient, domain)
-	if err != nil {
-		return err
+// recordName returns the fully qualified name of a record, as used by the
+// DonDominio API, the domain itself for its apex.
+func recordName(domain, subDomain string) string {
+	if subDomain == "" {
+		return domain
 	}
+	return subDomain + "." + domain
+}
 
-	_, err = createRecord(ddClient, domain, "TXT", subDomain, target)
+// addTXTRecord creates the challenge record with the given TTL, zero for the
+// default one, and returns it. The pending values are the ones of concurrent
+// challenges for the same name, never considered conflicting.
+func addTXTRecord(ctx context.Context, provider DNSProvider, domain, subDomain, target string, ttl time.Duration, conflictPolicy string, pending map[string]bool) (TXTRecord, error) {
+	name := recordName(domain, subDomain)
+	tx := recordTransaction{provider: provider, zone: domain, name: name, value: target, ttl: ttl}
+	if conflictPolicy == conflictPolicyReplace || conflictPolicy == conflictPolicyUpdate || conflictPolicy == conflictPolicyFail {
+		var err error
+		tx.replaceID, err = resolveConflicts(ctx, provider, domain, name, target, conflictPolicy, pending)
+		if err != nil {
+			return TXTRecord{}, err
+		}
+	}
 
-	return err
+	return tx.commit(ctx)
 }
 
-func removeTXTRecord(ddClient *Client, domain, target string) error {
-	record, err := findRecords(ddClient, domain, target)
+// resolveConflicts applies the conflict policy to the TXT records with the
+// same name as the challenge record but a different value, other than the
+// pending ones. With the update policy, the last conflicting record is kept
+// and its ID returned, to be updated with the value of the challenge record,
+// unless the challenge record already exists.
+func resolveConflicts(ctx context.Context, provider DNSProvider, domain, name, target, conflictPolicy string, pending map[string]bool) (string, error) {
+	records, err := provider.ListTXT(ctx, domain, name, "")
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	if record != nil && record.ResponseData.Dns != nil && len(record.ResponseData.Dns) > 0 {
-		dns := record.ResponseData.Dns[0]
-		err = deleteRecord(ddClient, domain, dns.EntityID)
-		if err != nil {
-			return err
+	var conflicts []TXTRecord
+	exists := false
+	for _, record := range records {
+		if record.Value == target {
+			exists = true
+			continue
 		}
+		if pending[record.Value] {
+			continue
+		}
+		if conflictPolicy == conflictPolicyFail {
+			return "", fmt.Errorf("TXT record %s already exists with a different value and conflict policy is %s", name, conflictPolicy)
+		}
+		conflicts = append(conflicts, record)
 	}
 
-	return nil
+	replaceID := ""
+	if conflictPolicy == conflictPolicyUpdate && !exists && len(conflicts) > 0 {
+		replaceID = conflicts[len(conflicts)-1].ID
+		conflicts = conflicts[:len(conflicts)-1]
+	}
+	for _, record := range conflicts {
+		klog.FromContext(ctx).Info("Deleting conflicting TXT record", "record", name, "entityID", record.ID)
+		err = provider.DeleteTXT(ctx, domain, record.ID)
+		if err != nil && !errors.Is(err, ErrRecordNotFound) {
+			return "", err
+		}
+	}
+
+	return replaceID, nil
 }
 
-func validateService(ddClient *Client, domain string) error {
+func validateService(ctx context.Context, ddClient *Client, domain string) error {
 	url := "/service/getinfo"
 	serviceInfo := ddServiceInfo{}
 	params := ddServiceStatusParams{
 		ServiceName: domain,
 		InfoType:    "status",
 	}
-	err := ddClient.Post(url, &params, &serviceInfo)
+	err := ddClient.PostWithContext(ctx, url, &params, &serviceInfo)
+	if errors.Is(err, ErrServiceNotActive) {
+		return fmt.Errorf("DonDominio service not deployed for domain %s: %w", domain, err)
+	}
 	if err != nil {
-		return fmt.Errorf("DonDominio API call failed: POST %s - %v", url, err)
+		return fmt.Errorf("DonDominio API call failed: POST %s - %w", url, err)
 	}
-	if !serviceInfo.Success {
-		return fmt.Errorf("DonDominio service not deployed for domain %s", domain)
+	if status := serviceInfo.ResponseData.Status; status != "" && status != "active" {
+		return fmt.Errorf("DonDominio service for domain %s is %s: %w", domain, status, ErrServiceNotActive)
 	}
 
 	return nil
 }
 
-func findRecords(ddClient *Client, domain, target string) (*ddServiceList, error) {
+// findRecords lists the records of domain, filtered by name and value when
+// not empty, following the pages of the response. The filters only reduce
+// the size of the response: the API matches them loosely, or not at all on
+// older versions, so the callers must still check the records they get.
+func findRecords(ctx context.Context, ddClient *Client, domain, name, target string) (*ddServiceList, error) {
 	url := "/service/dnslist"
-	serviceList := ddServiceList{}
 	params := ddServiceListParams{
 		ServiceName: domain,
+		FilterName:  name,
 		FilterValue: target,
 	}
-	err := ddClient.Post(url, &params, &serviceList)
-	if err != nil {
-		return nil, fmt.Errorf("DonDominio API call failed: POST %s - %v", url, err)
+	var serviceList *ddServiceList
+	for page := 1; ; page++ {
+		params.Page = page
+		list := ddServiceList{}
+		// An unsuccessful response fails with an *APIError, and is not
+		// mistaken for a filter matching nothing.
+		if err := ddClient.PostWithContext(ctx, url, &params, &list); err != nil {
+			return nil, fmt.Errorf("DonDominio API call failed: POST %s - %w", url, err)
+		}
+
+		if serviceList == nil {
+			serviceList = &list
+		} else {
+			serviceList.ResponseData.Dns = append(serviceList.ResponseData.Dns, list.ResponseData.Dns...)
+		}
+		// Older versions of the API do not report the total
+		if len(list.ResponseData.Dns) == 0 || uint64(len(serviceList.ResponseData.Dns)) >= list.ResponseData.QueryInfo.Total {
+			return serviceList, nil
+		}
 	}
-	return &serviceList, nil
 }
 
-func deleteRecord(ddClient *Client, domain, entityId string) error {
+func deleteRecord(ctx context.Context, ddClient *Client, domain, entityId string) error {
 	url := "/service/dnsdelete"
 	params := ddDeleteServiceParams{
 		ServiceName: domain,
 		EntityId:    entityId,
 	}
-	err := ddClient.Post(url, &params, nil)
+	err := ddClient.PostWithContext(ctx, url, &params, &ddResponse{})
+	ddClient.audit(ctx, auditEntry{Action: auditDelete, Zone: domain, ID: entityId}, err)
 	if err != nil {
-		return fmt.Errorf("DonDominio API call failed: DELETE %s - %v", url, err)
+		return fmt.Errorf("DonDominio API call failed: DELETE %s - %w", url, err)
 	}
 	return nil
 }
 
-func createRecord(ddClient *Client, domain, fieldType, subDomain, target string) (*ddServiceList, error) {
+// createRecord creates a record. A zero TTL uses the default TTL of the
+// zone.
+func createRecord(ctx context.Context, ddClient *Client, domain string, fields RecordParams) (*ddServiceList, error) {
 	url := "/service/dnscreate"
 	params := ddCreateServiceParams{
-		FieldType:   fieldType,
+		FieldType:   fields.Type,
 		ServiceName: domain,
-		Name:        subDomain + "." + domain,
-		Value:       target,
+		Name:        fields.Name,
+		Value:       fields.Value,
+		TTL:         int(fields.TTL / time.Second),
+		Priority:    fields.Priority,
 	}
 	record := ddServiceList{}
-	err := ddClient.Post(url, &params, &record)
+	err := ddClient.PostWithContext(ctx, url, &params, &record)
+	entry := auditEntry{Action: auditCreate, Zone: domain, Name: fields.Name, Type: fields.Type, ValueHash: auditValueHash(fields.Value)}
+	if err == nil && len(record.ResponseData.Dns) > 0 {
+		entry.ID = record.ResponseData.Dns[0].EntityID
+	}
+	ddClient.audit(ctx, entry, err)
 	if err != nil {
-		return nil, fmt.Errorf("DonDominio API call failed: POST %s - %v", url, err)
+		return nil, fmt.Errorf("DonDominio API call failed: POST %s - %w", url, err)
 	}
 
 	return &record, nil
@@ -1,25 +1,106 @@
 package main
 
 import (
+	"net"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cert-manager/cert-manager/test/acme/dns"
+	miekgdns "github.com/miekg/dns"
 )
 
 var (
 	zone = os.Getenv("TEST_ZONE_NAME")
 )
 
+// TestRunsSuite runs the conformance suite against the DonDominio account
+// configured in testdata/don_dominio, for the TEST_ZONE_NAME zone.
 func TestRunsSuite(t *testing.T) {
+	if zone == "" {
+		t.Skip("TEST_ZONE_NAME is not set")
+	}
+
 	// The manifest path should contain a file named config.json that is a
 	// snippet of valid configuration that should be included on the
 	// ChallengeRequest passed as part of the test cases.
-	fixture := dns.NewFixture(&ddDNSProviderSolver{},
+	fixture := dns.NewFixture(newSolver(),
 		dns.SetResolvedZone(zone),
 		dns.SetAllowAmbientCredentials(false),
 		dns.SetManifestPath("testdata/don_dominio"),
+		dns.SetStrict(true),
+	)
+
+	fixture.RunConformance(t)
+}
+
+// TestRunsSuiteAgainstFakeAPI runs the conformance suite against the fake
+// DonDominio API, whose records are served by a local DNS server, so that it
+// does not need credentials. It needs the control plane binaries fetched by
+// make test.
+func TestRunsSuiteAgainstFakeAPI(t *testing.T) {
+	if os.Getenv("TEST_ASSET_KUBE_APISERVER") == "" && os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("the control plane binaries are not available, run make test")
+	}
+
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, nil)
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	fixture := dns.NewFixture(newSolver(),
+		dns.SetResolvedZone("example.com."),
+		dns.SetAllowAmbientCredentials(false),
+		dns.SetManifestPath("testdata/fake"),
+		dns.SetConfig(map[string]interface{}{
+			"endpoint":       server.URL,
+			"applicationKey": "apiuser",
+			"applicationSecretRef": map[string]string{
+				"name": "dd-credentials",
+				"key":  "applicationSecret",
+			},
+		}),
+		dns.SetDNSServer(serveFixtureRecords(t, api)),
+		dns.SetUseAuthoritative(false),
+		dns.SetStrict(true),
+		dns.SetPollInterval(100*time.Millisecond),
+		dns.SetPropagationLimit(10*time.Second),
 	)
 
 	fixture.RunConformance(t)
 }
+
+// serveFixtureRecords starts a DNS server answering TXT queries with the
+// records of api and returns its address.
+func serveFixtureRecords(t *testing.T, api *fixtureAPI) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &miekgdns.Server{
+		PacketConn: conn,
+		Handler: miekgdns.HandlerFunc(func(w miekgdns.ResponseWriter, req *miekgdns.Msg) {
+			m := new(miekgdns.Msg)
+			m.SetReply(req)
+			q := req.Question[0]
+			if q.Qtype == miekgdns.TypeTXT {
+				_, records := api.result()
+				for _, record := range records {
+					if record.Type == "TXT" && strings.EqualFold(record.Name+".", q.Name) {
+						m.Answer = append(m.Answer, &miekgdns.TXT{
+							Hdr: miekgdns.RR_Header{Name: q.Name, Rrtype: miekgdns.TypeTXT, Class: miekgdns.ClassINET},
+							Txt: []string{record.Value},
+						})
+					}
+				}
+			}
+			w.WriteMsg(m)
+		}),
+	}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+	return conn.LocalAddr().String()
+}
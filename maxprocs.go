@@ -0,0 +1,72 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// cgroupRoot is where the cgroup file systems are mounted.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// setMaxProcs limits GOMAXPROCS to the CPU quota of the container, so that
+// the runtime does not schedule more threads than the CPU limit allows and
+// gets throttled. An explicit GOMAXPROCS environment variable takes precedence.
+func setMaxProcs() {
+	if _, ok := os.LookupEnv("GOMAXPROCS"); ok {
+		return
+	}
+	quota, ok := cgroupCPUQuota(cgroupRoot)
+	if !ok {
+		return
+	}
+
+	procs := int(math.Floor(quota))
+	if procs < 1 {
+		procs = 1
+	}
+	if procs < runtime.NumCPU() {
+		runtime.GOMAXPROCS(procs)
+		klog.InfoS("GOMAXPROCS set from the CPU quota of the container", "gomaxprocs", procs)
+	}
+}
+
+// cgroupCPUQuota returns the CPU quota of the cgroup, as a number of CPUs.
+// The second return value is false if there is no quota. Both cgroup v2
+// (cpu.max) and v1 (cpu.cfs_quota_us and cpu.cfs_period_us) are supported.
+func cgroupCPUQuota(root string) (float64, bool) {
+	if data, err := os.ReadFile(filepath.Join(root, "cpu.max")); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0, false
+		}
+		return cpuQuota(fields[0], fields[1])
+	}
+
+	quota, err := os.ReadFile(filepath.Join(root, "cpu", "cpu.cfs_quota_us"))
+	if err != nil {
+		return 0, false
+	}
+	period, err := os.ReadFile(filepath.Join(root, "cpu", "cpu.cfs_period_us"))
+	if err != nil {
+		return 0, false
+	}
+	return cpuQuota(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+func cpuQuota(quota, period string) (float64, bool) {
+	q, err := strconv.ParseInt(quota, 10, 64)
+	if err != nil || q <= 0 {
+		return 0, false
+	}
+	p, err := strconv.ParseInt(period, 10, 64)
+	if err != nil || p <= 0 {
+		return 0, false
+	}
+	return float64(q) / float64(p), true
+}
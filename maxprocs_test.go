@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCgroupCPUQuota(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		want   float64
+		wantOK bool
+	}{
+		{name: "v2 quota", files: map[string]string{"cpu.max": "150000 100000\n"}, want: 1.5, wantOK: true},
+		{name: "v2 unlimited", files: map[string]string{"cpu.max": "max 100000\n"}},
+		{name: "v1 quota", files: map[string]string{
+			"cpu/cpu.cfs_quota_us":  "50000\n",
+			"cpu/cpu.cfs_period_us": "100000\n",
+		}, want: 0.5, wantOK: true},
+		{name: "v1 unlimited", files: map[string]string{
+			"cpu/cpu.cfs_quota_us":  "-1\n",
+			"cpu/cpu.cfs_period_us": "100000\n",
+		}},
+		{name: "no cgroup"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, ok := cgroupCPUQuota(root)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("cgroupCPUQuota() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
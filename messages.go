@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+var apiMessagesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "api_messages_total",
+	Help:      "Number of messages of the DonDominio API responses, by path and level (warning or info).",
+}, []string{"path", "level"})
+
+func init() {
+	metricsRegistry.MustRegister(apiMessagesTotal)
+}
+
+// Handling of the messages of the API responses, see the --api-messages
+// flag.
+const (
+	// apiMessagesIgnore only counts the messages
+	apiMessagesIgnore = "ignore"
+	// apiMessagesLog logs each message once, with its level
+	apiMessagesLog = "log"
+	// apiMessagesEvent also records the warnings as events of the pod of
+	// the webhook
+	apiMessagesEvent = "event"
+)
+
+// warningMessageWords are the words of the messages announcing something
+// that may break the issuance, e.g. a quota nearly reached or a deprecated
+// parameter. The other messages are informational.
+var warningMessageWords = []string{"deprecat", "obsolete", "removed", "sunset", "quota", "limit", "balance", "expir"}
+
+// isWarningMessage reports whether an API message is a warning.
+func isWarningMessage(message string) bool {
+	message = strings.ToLower(message)
+	for _, word := range warningMessageWords {
+		if strings.Contains(message, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// apiMessageHandler handles the messages of the API responses. Its zero value
+// logs them.
+type apiMessageHandler struct {
+	mode     string
+	recorder record.EventRecorder
+	pod      *corev1.ObjectReference
+}
+
+// apiMessages is set up by Initialize.
+var apiMessages apiMessageHandler
+
+// loggedMessages holds the messages already handled, by path, so that each
+// one is only logged once per process while the metric counts all of them.
+var loggedMessages sync.Map
+
+// validateAPIMessagesMode checks the value of --api-messages.
+func validateAPIMessagesMode(mode string) error {
+	switch mode {
+	case apiMessagesIgnore, apiMessagesLog, apiMessagesEvent:
+		return nil
+	default:
+		return fmt.Errorf("invalid --api-messages %q, must be one of %s, %s or %s", mode, apiMessagesIgnore, apiMessagesLog, apiMessagesEvent)
+	}
+}
+
+// setupAPIMessages configures the handling of the API messages. In event
+// mode, the events are recorded on the pod named by the POD_NAME environment
+// variable.
+func setupAPIMessages(mode string, client kubernetes.Interface, stopCh <-chan struct{}) error {
+	if err := validateAPIMessagesMode(mode); err != nil {
+		return err
+	}
+	if mode != apiMessagesEvent {
+		apiMessages = apiMessageHandler{mode: mode}
+		return nil
+	}
+
+	podName := os.Getenv("POD_NAME")
+	if podName == "" {
+		return fmt.Errorf("--api-messages=%s requires the POD_NAME environment variable", apiMessagesEvent)
+	}
+	namespace, err := podNamespace()
+	if err != nil {
+		return err
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events(namespace)})
+	go func() {
+		<-stopCh
+		broadcaster.Shutdown()
+	}()
+	apiMessages = apiMessageHandler{
+		mode:     mode,
+		recorder: broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "cert-manager-webhook-dd"}),
+		pod:      &corev1.ObjectReference{Kind: "Pod", APIVersion: "v1", Namespace: namespace, Name: podName},
+	}
+	return nil
+}
+
+// handle logs, and records as an event if enabled, a message of the response
+// to path.
+func (h apiMessageHandler) handle(ctx context.Context, path, message string) {
+	message = redactSecrets(message)
+	warning := isWarningMessage(message)
+	level := "info"
+	if warning {
+		level = "warning"
+	}
+	apiMessagesTotal.WithLabelValues(path, level).Inc()
+
+	if h.mode == apiMessagesIgnore {
+		return
+	}
+	if _, logged := loggedMessages.LoadOrStore(path+"\x00"+message, true); logged {
+		return
+	}
+
+	klog.FromContext(ctx).Info("DonDominio API message", "path", path, "level", level, "message", message)
+	if warning && h.recorder != nil {
+		h.recorder.Eventf(h.pod, corev1.EventTypeWarning, "DonDominioAPIMessage", "%s: %s", path, message)
+	}
+}
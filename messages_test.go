@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestIsWarningMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		want    bool
+	}{
+		{"The filterName parameter is deprecated and will be removed", true},
+		{"You have used 90% of your API quota", true},
+		{"Account balance is low", true},
+		{"Record created", false},
+	}
+	for _, tt := range tests {
+		if got := isWarningMessage(tt.message); got != tt.want {
+			t.Errorf("isWarningMessage(%q) = %v, want %v", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestAPIMessageEvents(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	handler := apiMessageHandler{
+		mode:     apiMessagesEvent,
+		recorder: recorder,
+		pod:      &corev1.ObjectReference{Kind: "Pod", Namespace: "cert-manager", Name: "webhook"},
+	}
+	const path = "/test/messages"
+	// The counters and the messages already logged are global, e.g. with
+	// -count=2
+	warningsBefore := testutil.ToFloat64(apiMessagesTotal.WithLabelValues(path, "warning"))
+	infosBefore := testutil.ToFloat64(apiMessagesTotal.WithLabelValues(path, "info"))
+	loggedMessages.Range(func(key, _ interface{}) bool {
+		loggedMessages.Delete(key)
+		return true
+	})
+	for i := 0; i < 2; i++ {
+		handler.handle(context.Background(), path, "Record created")
+		handler.handle(context.Background(), path, "You have used 90% of your API quota")
+	}
+
+	if got := testutil.ToFloat64(apiMessagesTotal.WithLabelValues(path, "warning")) - warningsBefore; got != 2 {
+		t.Errorf("warning messages = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(apiMessagesTotal.WithLabelValues(path, "info")) - infosBefore; got != 2 {
+		t.Errorf("info messages = %v, want 2", got)
+	}
+
+	if len(recorder.Events) != 1 {
+		t.Fatalf("%d events recorded, want 1", len(recorder.Events))
+	}
+	want := "Warning DonDominioAPIMessage " + path + ": You have used 90% of your API quota"
+	if got := <-recorder.Events; got != want {
+		t.Errorf("event = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/klog/v2"
+)
+
+const metricsNamespace = "cert_manager_webhook_dd"
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Duration of the DonDominio API requests, by path.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	}, []string{"path"})
+	rateLimitedRequests = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Number of DonDominio API requests rejected because of rate limiting.",
+	})
+	rateLimitWaitSeconds = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "rate_limit_wait_seconds_total",
+		Help:      "Time spent waiting before retrying DonDominio API requests rejected because of rate limiting.",
+	})
+	apiConnections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_connections_total",
+		Help:      "Number of DonDominio API requests, by path and by whether they reused a kept-alive connection.",
+	}, []string{"path", "reused"})
+	apiTLSHandshakeDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_tls_handshake_duration_seconds",
+		Help:      "Duration of the TLS handshakes of the new connections to the DonDominio API.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 10),
+	})
+	maxProcs = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "gomaxprocs",
+		Help:      "Maximum number of CPUs used by the Go runtime.",
+	}, func() float64 { return float64(runtime.GOMAXPROCS(0)) })
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		apiRequestDuration,
+		rateLimitedRequests,
+		rateLimitWaitSeconds,
+		apiConnections,
+		apiTLSHandshakeDuration,
+		maxProcs,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// serveMetrics serves the metrics on addr, state on /debug/state, the
+// discovery document on /debug/discovery and the build on /version, until
+// stopCh is closed.
+func serveMetrics(addr string, state http.Handler, stopCh <-chan struct{}) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+	mux.Handle("/debug/state", state)
+	mux.HandleFunc("/debug/discovery", serveDiscovery)
+	mux.HandleFunc("/version", serveVersion)
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-stopCh
+		server.Close()
+	}()
+
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		klog.ErrorS(err, "Metrics server failed", "addr", addr)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fixtureAPI is an in-memory implementation of the DonDominio API, served
+// with httptest.NewServer. It implements /auth/time, /service/getinfo,
+// /service/list, /service/dnslist, /service/dnscreate, /service/dnsupdate
+// and /service/dnsdelete with the success and error envelopes of the real
+// API, so that the full Present and CleanUp cycle can be exercised without
+// credentials.
+type fixtureAPI struct {
+	mu       sync.Mutex
+	services map[string]string
+	records  []Dns
+	nextID   int
+	actions  []string
+	failures map[string]int64
+	// latency is added to every response
+	latency time.Duration
+	// pageLength is the default number of records per dnslist page
+	pageLength int
+}
+
+// defaultPageLength is the default number of records per dnslist page of
+// the DonDominio API.
+const defaultPageLength = 1000
+
+func newFixtureAPI(services map[string]string, records []Dns) *fixtureAPI {
+	api := &fixtureAPI{services: services, nextID: 1000, pageLength: defaultPageLength}
+	for i, record := range records {
+		if record.EntityID == "" {
+			record.EntityID = strconv.Itoa(i + 1)
+		}
+		api.records = append(api.records, record)
+	}
+	return api
+}
+
+// result returns the actions performed and the records without entity IDs.
+func (api *fixtureAPI) result() ([]string, []Dns) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	var records []Dns
+	for _, record := range api.records {
+		record.EntityID = ""
+		records = append(records, record)
+	}
+	return api.actions, records
+}
+
+func (api *fixtureAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	latency := api.latency
+	api.mu.Unlock()
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	action := path.Base(r.URL.Path)
+	api.actions = append(api.actions, action)
+
+	if r.URL.Path == "/auth/time" {
+		// The only call answered without the envelope
+		json.NewEncoder(w).Encode(time.Now().Unix())
+		return
+	}
+	if r.PostForm.Get("apiuser") != "apiuser" || r.PostForm.Get("apipasswd") != "apipasswd" {
+		api.fail(w, ddErrLoginInvalid, "Login invalid")
+		return
+	}
+	if code, ok := api.failures[action]; ok {
+		api.fail(w, code, "Injected failure")
+		return
+	}
+	if r.URL.Path == "/service/list" {
+		var services []Service
+		for name, status := range api.services {
+			services = append(services, Service{Name: name, Type: "dns", Status: status})
+		}
+		sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+		api.succeed(w, ddServiceListAllResponse{QueryInfo: QueryInfo{Page: 1, Results: uint64(len(services)), Total: uint64(len(services))}, Services: services})
+		return
+	}
+	serviceName := r.PostForm.Get("serviceName")
+	status, ok := api.services[serviceName]
+	if !ok {
+		api.fail(w, ddErrServiceNotFound, "Service not found")
+		return
+	}
+
+	switch action {
+	case "getinfo":
+		api.succeed(w, ddServiceInfoResponse{Name: serviceName, Status: status})
+	case "dnslist":
+		var records []Dns
+		for _, record := range api.records {
+			// The filters are not assumed to be exact
+			if name := r.PostForm.Get("filterName"); !strings.Contains(record.Name, name) {
+				continue
+			}
+			if value := r.PostForm.Get("filterValue"); !strings.Contains(record.Value, value) {
+				continue
+			}
+			records = append(records, record)
+		}
+		api.succeed(w, api.page(r, records))
+	case "dnscreate":
+		api.nextID++
+		record := Dns{
+			EntityID: strconv.Itoa(api.nextID),
+			Name:     r.PostForm.Get("name"),
+			Type:     r.PostForm.Get("type"),
+			Value:    r.PostForm.Get("value"),
+			Ttl:      r.PostForm.Get("ttl"),
+			Priority: r.PostForm.Get("priority"),
+		}
+		api.records = append(api.records, record)
+		api.succeed(w, ddServiceListResponse{Dns: []Dns{record}})
+	case "dnsupdate":
+		for i, record := range api.records {
+			if record.EntityID == r.PostForm.Get("entityID") {
+				api.records[i].Value = r.PostForm.Get("value")
+				api.records[i].Ttl = r.PostForm.Get("ttl")
+				api.succeed(w, ddServiceListResponse{Dns: []Dns{api.records[i]}})
+				return
+			}
+		}
+		api.fail(w, ddErrEntityNotFound, "Entity not found")
+	case "dnsdelete":
+		for i, record := range api.records {
+			if record.EntityID == r.PostForm.Get("entityID") {
+				api.records = append(api.records[:i], api.records[i+1:]...)
+				api.succeed(w, nil)
+				return
+			}
+		}
+		api.fail(w, ddErrEntityNotFound, "Entity not found")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// page returns the page of records requested by the page and pageLength
+// parameters, numbered from 1.
+func (api *fixtureAPI) page(r *http.Request, records []Dns) ddServiceListResponse {
+	page, err := strconv.Atoi(r.PostForm.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageLength, err := strconv.Atoi(r.PostForm.Get("pageLength"))
+	if err != nil || pageLength < 1 {
+		pageLength = api.pageLength
+	}
+
+	start := (page - 1) * pageLength
+	if start > len(records) {
+		start = len(records)
+	}
+	end := start + pageLength
+	if end > len(records) {
+		end = len(records)
+	}
+	return ddServiceListResponse{
+		QueryInfo: QueryInfo{
+			Page:       uint64(page),
+			PageLength: uint64(pageLength),
+			Results:    uint64(end - start),
+			Total:      uint64(len(records)),
+		},
+		Dns: records[start:end],
+	}
+}
+
+func (api *fixtureAPI) succeed(w http.ResponseWriter, responseData interface{}) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":      true,
+		"errorCode":    0,
+		"responseData": responseData,
+	})
+}
+
+func (api *fixtureAPI) fail(w http.ResponseWriter, code int64, msg string) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":      false,
+		"errorCode":    code,
+		"errorCodeMsg": msg,
+	})
+}
+
+func TestFixtureAPICycle(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, []Dns{
+		{Name: "_acme-challenge.example.com", Type: "TXT", Value: "other-key-1"},
+		{Name: "_acme-challenge.example.com", Type: "TXT", Value: "other-key-2"},
+		{Name: "_acme-challenge.example.com", Type: "TXT", Value: "other-key-3"},
+	})
+	api.pageLength = 2
+	api.latency = 5 * time.Millisecond
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	ddClient, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ddClient.PingWithContext(context.Background()); err != nil {
+		t.Errorf("PingWithContext() error: %v", err)
+	}
+
+	solver := testSolver()
+	ch := testChallenge(t, server.URL, "_acme-challenge.example.com.", "example.com.", "challenge-key", nil)
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("Present() error: %v", err)
+	}
+
+	// Listing the records of the name must go past the first page
+	records, err := ddClient.ListTXT(context.Background(), "example.com", "_acme-challenge.example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Errorf("ListTXT() returned %d records, want 4", len(records))
+	}
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("CleanUp() error: %v", err)
+	}
+
+	actions, remaining := api.result()
+	// The record created is deleted by its ID, without listing the records
+	wantActions := []string{"time", "getinfo", "dnscreate", "dnslist", "dnslist", "dnsdelete"}
+	if !reflect.DeepEqual(actions, wantActions) {
+		t.Errorf("actions = %q, want %q", actions, wantActions)
+	}
+	if len(remaining) != 3 {
+		t.Errorf("%d records left, want 3", len(remaining))
+	}
+}
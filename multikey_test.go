@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+// TestInterleavedKeys presents and cleans up five keys for the same name,
+// partly concurrently, as several orders validating the same name do. With
+// the replace conflict policy, the keys of the other orders must be kept.
+func TestInterleavedKeys(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, []Dns{
+		{Name: "_acme-challenge.example.com", Type: "TXT", Value: "stale-key"},
+	})
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	solver := testSolver()
+	challenges := map[string]*v1alpha1.ChallengeRequest{}
+	for _, key := range []string{"key-1", "key-2", "key-3", "key-4", "key-5"} {
+		challenges[key] = testChallenge(t, server.URL, "_acme-challenge.example.com.", "example.com.", key,
+			map[string]interface{}{"conflictPolicy": conflictPolicyReplace})
+	}
+
+	run := func(action func(*v1alpha1.ChallengeRequest) error, keys ...string) {
+		t.Helper()
+		var wg sync.WaitGroup
+		errs := make([]error, len(keys))
+		for i, key := range keys {
+			wg.Add(1)
+			go func(i int, ch *v1alpha1.ChallengeRequest) {
+				defer wg.Done()
+				errs[i] = action(ch)
+			}(i, challenges[key])
+		}
+		wg.Wait()
+		for i, err := range errs {
+			if err != nil {
+				t.Fatalf("%s: %v", keys[i], err)
+			}
+		}
+	}
+	expect := func(want ...string) {
+		t.Helper()
+		_, records := api.result()
+		var got []string
+		for _, record := range records {
+			got = append(got, record.Value)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("record values = %q, want %q", got, want)
+		}
+	}
+
+	run(solver.Present, "key-1", "key-2", "key-3")
+	expect("key-1", "key-2", "key-3")
+	run(solver.CleanUp, "key-2")
+	expect("key-1", "key-3")
+	run(solver.Present, "key-4", "key-5")
+	expect("key-1", "key-3", "key-4", "key-5")
+	run(solver.CleanUp, "key-1", "key-4")
+	expect("key-3", "key-5")
+	run(solver.CleanUp, "key-5", "key-3")
+	expect()
+
+	if keys := solver.ledger.pendingKeys("_acme-challenge.example.com."); len(keys) != 0 {
+		t.Errorf("pending keys = %v, want none", keys)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+)
+
+// challengeResource is the cert-manager Challenge resource, whose keys are
+// the values of the challenge records still in use.
+var challengeResource = schema.GroupVersionResource{
+	Group:    "acme.cert-manager.io",
+	Version:  "v1",
+	Resource: "challenges",
+}
+
+var orphanRecordsDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "orphan_records_deleted_total",
+	Help:      "Number of orphaned challenge records deleted by the garbage collector, by zone.",
+}, []string{"zone"})
+
+func init() {
+	metricsRegistry.MustRegister(orphanRecordsDeleted)
+}
+
+// orphanGCAllZones, as the zones of the collector, stands for all the active
+// zones of the account.
+const orphanGCAllZones = "*"
+
+// challengeRecords returns the _acme-challenge TXT records of every name of
+// zone.
+func challengeRecords(ctx context.Context, ddClient *Client, zone string) ([]TXTRecord, error) {
+	records, err := findRecords(ctx, ddClient, zone, "", "")
+	if err != nil {
+		return nil, err
+	}
+	var challenges []TXTRecord
+	for _, dns := range records.ResponseData.Dns {
+		if dns.Type == "TXT" && strings.HasPrefix(dns.Name, acmeChallengeLabel+".") {
+			challenges = append(challenges, TXTRecord{ID: dns.EntityID, Name: dns.Name, Value: dns.Value})
+		}
+	}
+	return challenges, nil
+}
+
+// activeChallengeKeys returns the keys of the cert-manager Challenges of all
+// the namespaces.
+func activeChallengeKeys(ctx context.Context, client dynamic.Interface) (map[string]bool, error) {
+	list, err := client.Resource(challengeResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the Challenges: %w", err)
+	}
+	keys := make(map[string]bool, len(list.Items))
+	for _, item := range list.Items {
+		if key, _, _ := unstructured.NestedString(item.Object, "spec", "key"); key != "" {
+			keys[key] = true
+		}
+	}
+	return keys, nil
+}
+
+// orphanCollector deletes the challenge records of its zones that no
+// Challenge uses any longer, e.g. left behind by a CleanUp interrupted by a
+// crash. Only the records created by the webhook, known by their IDs in the
+// ledger, are collected, since the zones may be shared with other clusters
+// or ACME clients whose Challenges are unknown, unless unowned is set. The
+// API does not tell when a record was created, so the age of a record is
+// counted from the first time the collector saw it: after a restart, no
+// record is deleted before minAge has elapsed.
+type orphanCollector struct {
+	solver *ddDNSProviderSolver
+	// zones are listed with the ambient credentials on every collection if
+	// they are only orphanGCAllZones
+	zones  []string
+	minAge time.Duration
+	// unowned also collects the records not created by the webhook, for the
+	// zones whose challenges it solves exclusively
+	unowned bool
+	// dryRun only logs the records that would be deleted
+	dryRun bool
+
+	// activeKeys returns the keys of the Challenges
+	activeKeys func(ctx context.Context) (map[string]bool, error)
+
+	// firstSeen holds when each record was first seen, by zone and ID
+	firstSeen map[string]time.Time
+	now       func() time.Time
+}
+
+func newOrphanCollector(s *ddDNSProviderSolver, zones []string, minAge time.Duration, activeKeys func(ctx context.Context) (map[string]bool, error)) *orphanCollector {
+	return &orphanCollector{
+		solver:     s,
+		zones:      zones,
+		minAge:     minAge,
+		activeKeys: activeKeys,
+		firstSeen:  make(map[string]time.Time),
+		now:        time.Now,
+	}
+}
+
+// run collects the orphaned records every interval until stopCh is closed.
+func (c *orphanCollector) run(interval time.Duration, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(c.solver.stopContext(), interval)
+		if err := c.collect(ctx); err != nil {
+			klog.ErrorS(err, "Orphaned challenge records not collected")
+		}
+		cancel()
+	}
+}
+
+// collect deletes the orphaned records older than minAge. Nothing is deleted
+// if the Challenges cannot be listed.
+func (c *orphanCollector) collect(ctx context.Context) error {
+	active, err := c.activeKeys(ctx)
+	if err != nil {
+		return err
+	}
+	ddClient, err := c.solver.ambientClient()
+	if err != nil {
+		return err
+	}
+
+	zones := c.zones
+	if len(zones) == 1 && zones[0] == orphanGCAllZones {
+		if zones, err = ddClient.ManagedZones(ctx); err != nil {
+			return fmt.Errorf("failed to list the zones of the account: %w", err)
+		}
+	}
+
+	now := c.now()
+	seen := make(map[string]time.Time, len(c.firstSeen))
+	for _, zone := range zones {
+		records, err := challengeRecords(ctx, ddClient, zone)
+		if err != nil {
+			// The records of the zone are kept in firstSeen
+			for key, at := range c.firstSeen {
+				if strings.HasPrefix(key, zone+"/") {
+					seen[key] = at
+				}
+			}
+			klog.ErrorS(err, "Failed to list the challenge records", "zone", zone)
+			continue
+		}
+		for _, record := range records {
+			key := zone + "/" + record.ID
+			firstSeen, ok := c.firstSeen[key]
+			if !ok {
+				firstSeen = now
+			}
+			seen[key] = firstSeen
+			if active[record.Value] || now.Sub(firstSeen) < c.minAge {
+				continue
+			}
+			if c.delete(ctx, ddClient, zone, record) {
+				delete(seen, key)
+			}
+		}
+	}
+	c.firstSeen = seen
+	return nil
+}
+
+// delete deletes the orphaned record if the webhook created it, or else if
+// unowned is set and no challenge of this process presented it in the
+// meantime, and returns whether it is gone.
+func (c *orphanCollector) delete(ctx context.Context, ddClient *Client, zone string, record TXTRecord) bool {
+	fqdn := record.Name + "."
+	unlock, err := c.solver.ledger.lockName(ctx, fqdn)
+	if err != nil {
+		return false
+	}
+	defer unlock()
+
+	provider := c.solver.mutatingProvider(ddClient, c.dryRun)
+	owner, owned := c.solver.ledger.createdRecord(record.ID)
+	switch {
+	case owned && owner.FQDN == fqdn:
+		err = provider.DeleteTXT(ctx, zone, record.ID)
+		if errors.Is(err, ErrRecordNotFound) {
+			err = nil
+		}
+		if err == nil && !c.dryRun {
+			c.solver.ledger.forgetCreated(owner, record.ID)
+		}
+	case c.unowned && !c.solver.ledger.pendingKeys(fqdn)[record.Value]:
+		_, err = EnsureAbsent(ctx, provider, zone, fqdn, record.Value)
+	default:
+		return false
+	}
+	if err != nil {
+		klog.ErrorS(err, "Failed to delete the orphaned challenge record", "zone", zone, "record", record.Name, "entityID", record.ID)
+		return false
+	}
+	if c.dryRun {
+		// Still orphaned on the next collection
+		return false
+	}
+	orphanRecordsDeleted.WithLabelValues(zone).Inc()
+	klog.InfoS("Orphaned challenge record deleted", "zone", zone, "record", record.Name, "entityID", record.ID)
+	return true
+}
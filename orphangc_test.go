@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOrphanCollector(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, []Dns{
+		{Name: "_acme-challenge.example.com", Type: "TXT", Value: "active-key"},
+		{Name: "_acme-challenge.example.com", Type: "TXT", Value: "orphan-key"},
+		{Name: "_acme-challenge.www.example.com", Type: "TXT", Value: "foreign-key"},
+		{Name: "example.com", Type: "TXT", Value: "v=spf1 -all"},
+	})
+	server := httptest.NewServer(api)
+	defer server.Close()
+	isolateAmbientConfig(t, "[default]\nendpoint = "+server.URL+"\napi_user = apiuser\napi_password = apipasswd\n")
+
+	s := testSolver()
+	s.ledger.presented(challengeKey{FQDN: "_acme-challenge.example.com.", Key: "active-key"}, "1")
+	orphan := challengeKey{FQDN: "_acme-challenge.example.com.", Key: "orphan-key"}
+	s.ledger.presented(orphan, "2")
+	listErr := errors.New("forbidden")
+	activeKeys := func(ctx context.Context) (map[string]bool, error) {
+		if listErr != nil {
+			return nil, listErr
+		}
+		return map[string]bool{"active-key": true}, nil
+	}
+	collector := newOrphanCollector(s, []string{"example.com"}, time.Hour, activeKeys)
+	now := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	collector.now = func() time.Time { return now }
+
+	values := func() map[string]bool {
+		_, records := api.result()
+		found := map[string]bool{}
+		for _, record := range records {
+			found[record.Value] = true
+		}
+		return found
+	}
+
+	if err := collector.collect(context.Background()); !errors.Is(err, listErr) {
+		t.Errorf("collect() = %v, want %v", err, listErr)
+	}
+	listErr = nil
+	if err := collector.collect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(values()) != 4 {
+		t.Errorf("records deleted before the minimum age: %v", values())
+	}
+
+	now = now.Add(2 * time.Hour)
+	if err := collector.collect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	found := values()
+	if found["orphan-key"] {
+		t.Error("orphaned record not deleted")
+	}
+	// foreign-key was not created by the webhook, e.g. by another cluster
+	for _, value := range []string{"active-key", "foreign-key", "v=spf1 -all"} {
+		if !found[value] {
+			t.Errorf("record %q deleted", value)
+		}
+	}
+	if created := s.ledger.createdRecords(orphan); len(created) != 0 {
+		t.Errorf("deleted orphaned record still in the ledger: %v", created)
+	}
+}
+
+func TestOrphanCollectorUnowned(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, []Dns{
+		{Name: "_acme-challenge.example.com", Type: "TXT", Value: "unowned-key"},
+		{Name: "_acme-challenge.www.example.com", Type: "TXT", Value: "pending-key"},
+	})
+	server := httptest.NewServer(api)
+	defer server.Close()
+	isolateAmbientConfig(t, "[default]\nendpoint = "+server.URL+"\napi_user = apiuser\napi_password = apipasswd\n")
+
+	s := testSolver()
+	// Presented by this process without a known ID
+	s.ledger.presented(challengeKey{FQDN: "_acme-challenge.www.example.com.", Key: "pending-key"}, "")
+	activeKeys := func(ctx context.Context) (map[string]bool, error) { return nil, nil }
+	collector := newOrphanCollector(s, []string{"example.com"}, 0, activeKeys)
+	if err := collector.collect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, records := api.result(); len(records) != 2 {
+		t.Errorf("records left = %+v, want the unowned ones", records)
+	}
+
+	collector.unowned = true
+	if err := collector.collect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, records := api.result(); len(records) != 1 || records[0].Value != "pending-key" {
+		t.Errorf("records left = %+v, want the pending one", records)
+	}
+}
+
+func TestOrphanCollectorAllZones(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active", "example.org": "active", "example.net": "inactive"}, []Dns{
+		{Name: "_acme-challenge.example.com", Type: "TXT", Value: "orphan-key-1"},
+		{Name: "_acme-challenge.example.org", Type: "TXT", Value: "orphan-key-2"},
+	})
+	server := httptest.NewServer(api)
+	defer server.Close()
+	isolateAmbientConfig(t, "[default]\nendpoint = "+server.URL+"\napi_user = apiuser\napi_password = apipasswd\n")
+
+	activeKeys := func(ctx context.Context) (map[string]bool, error) { return nil, nil }
+	collector := newOrphanCollector(testSolver(), []string{orphanGCAllZones}, 0, activeKeys)
+	collector.unowned = true
+	if err := collector.collect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, records := api.result(); len(records) != 0 {
+		t.Errorf("records left = %+v, want none", records)
+	}
+}
+
+func TestOrphanCollectorDryRun(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, []Dns{
+		{Name: "_acme-challenge.example.com", Type: "TXT", Value: "orphan-key"},
+		{Name: "_acme-challenge.www.example.com", Type: "TXT", Value: "unowned-key"},
+	})
+	server := httptest.NewServer(api)
+	defer server.Close()
+	isolateAmbientConfig(t, "[default]\nendpoint = "+server.URL+"\napi_user = apiuser\napi_password = apipasswd\n")
+
+	s := testSolver()
+	orphan := challengeKey{FQDN: "_acme-challenge.example.com.", Key: "orphan-key"}
+	s.ledger.presented(orphan, "1")
+	activeKeys := func(ctx context.Context) (map[string]bool, error) { return nil, nil }
+	collector := newOrphanCollector(s, []string{"example.com"}, 0, activeKeys)
+	collector.unowned = true
+	collector.dryRun = true
+	if err := collector.collect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, records := api.result(); len(records) != 2 {
+		t.Errorf("records left = %+v, want all of them in dry run", records)
+	}
+	if created := s.ledger.createdRecords(orphan); len(created) != 1 {
+		t.Errorf("created records = %v, want the orphaned one kept in the ledger", created)
+	}
+}
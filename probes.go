@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// readinessCheck is a dependency checked by /readyz.
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// cachedCheck returns check, with its result reused for ttl so that frequent
+// probes do not turn into as many calls to the dependency.
+func cachedCheck(ttl time.Duration, check func(ctx context.Context) error) func(ctx context.Context) error {
+	var (
+		mu        sync.Mutex
+		checkedAt time.Time
+		lastErr   error
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !checkedAt.IsZero() && time.Since(checkedAt) < ttl {
+			return lastErr
+		}
+		lastErr = check(ctx)
+		checkedAt = time.Now()
+		return lastErr
+	}
+}
+
+// readyzHandler runs the checks, each bounded by timeout, and answers 503 if
+// any of them fails. The body lists the result of each check.
+func readyzHandler(checks []readinessCheck, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body strings.Builder
+		failed := false
+		for _, c := range checks {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			err := c.check(ctx)
+			cancel()
+			if err != nil {
+				failed = true
+				fmt.Fprintf(&body, "[-]%s failed: %v\n", c.name, err)
+				klog.V(logDebug).InfoS("Readiness check failed", "check", c.name, "err", err)
+			} else {
+				fmt.Fprintf(&body, "[+]%s ok\n", c.name)
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if failed {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(&body, "readyz check failed\n")
+		} else {
+			fmt.Fprint(&body, "readyz check passed\n")
+		}
+		fmt.Fprint(w, body.String())
+	})
+}
+
+// readinessChecks returns the checks of /readyz: the connectivity to the
+// Kubernetes API and, if ping is set, to the DonDominio API of the
+// configured endpoint.
+func (s *ddDNSProviderSolver) readinessChecks(ping bool, ttl time.Duration) []readinessCheck {
+	checks := []readinessCheck{{
+		name: "kubernetes",
+		check: cachedCheck(ttl, func(ctx context.Context) error {
+			return s.client.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Error()
+		}),
+	}}
+	if !ping {
+		return checks
+	}
+
+	// Like the canary, the probe is not tied to an issuer, so it can only
+	// use the credentials found in the environment or configuration files.
+	if _, err := s.ambientClient(); err != nil {
+		klog.ErrorS(err, "DonDominio readiness check disabled")
+		return checks
+	}
+	return append(checks, readinessCheck{
+		name: "dondominio",
+		check: cachedCheck(ttl, func(ctx context.Context) error {
+			ddClient, err := s.ambientClient()
+			if err != nil {
+				return err
+			}
+			return ddClient.PingWithContext(ctx)
+		}),
+	})
+}
+
+// serveProbes serves /healthz and /readyz on addr until stopCh is closed.
+func serveProbes(addr string, checks []readinessCheck, timeout time.Duration, stopCh <-chan struct{}) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	})
+	mux.Handle("/readyz", readyzHandler(checks, timeout))
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-stopCh
+		server.Close()
+	}()
+
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		klog.ErrorS(err, "Probe server failed", "addr", addr)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadyz(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name       string
+		checks     []readinessCheck
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "ready",
+			checks:     []readinessCheck{{"kubernetes", ok}, {"dondominio", ok}},
+			wantStatus: http.StatusOK,
+			wantBody:   "[+]kubernetes ok\n[+]dondominio ok\nreadyz check passed\n",
+		},
+		{
+			name:       "dondominio down",
+			checks:     []readinessCheck{{"kubernetes", ok}, {"dondominio", down}},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "[+]kubernetes ok\n[-]dondominio failed: connection refused\nreadyz check failed\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			readyzHandler(tt.checks, time.Second).ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCachedCheck(t *testing.T) {
+	calls := 0
+	check := cachedCheck(50*time.Millisecond, func(ctx context.Context) error {
+		calls++
+		return errors.New(strings.Repeat("x", calls))
+	})
+
+	first := check(context.Background())
+	if second := check(context.Background()); calls != 1 || second.Error() != first.Error() {
+		t.Errorf("check called %d times within the TTL, want 1", calls)
+	}
+	time.Sleep(60 * time.Millisecond)
+	check(context.Background())
+	if calls != 2 {
+		t.Errorf("check called %d times after the TTL, want 2", calls)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+)
+
+// propagationSettings configures the wait for a challenge record to be
+// visible in DNS before Present returns.
+type propagationSettings struct {
+	// Interval between two DNS queries
+	Interval time.Duration
+	// Timeout after which Present fails if the record is still not visible
+	Timeout time.Duration
+	// Resolvers optionally queried for the record once it is visible on the
+	// authoritative nameservers, as host:port
+	Resolvers []string
+}
+
+// propagationSettings returns the propagation settings of the issuer, which
+// override the ones of the command line flags. Unless set explicitly, the
+// timeout is derived from the record TTL if the issuer sets it.
+func (cfg *ddDNSProviderConfig) propagationSettings() propagationSettings {
+	settings := propagationSettings{
+		Interval:  *propagationInterval,
+		Timeout:   *propagationTimeout,
+		Resolvers: splitResolvers(*propagationResolvers),
+	}
+	if cfg.PropagationInterval != nil {
+		settings.Interval = cfg.PropagationInterval.Duration
+	}
+	if cfg.PropagationTimeout != nil {
+		settings.Timeout = cfg.PropagationTimeout.Duration
+	} else if cfg.RecordTTL != nil {
+		// Resolvers may keep serving the records of a previous challenge for
+		// up to their TTL
+		settings.Timeout = cfg.RecordTTL.Duration + *propagationTTLMargin
+	}
+	if len(cfg.PropagationResolvers) > 0 {
+		settings.Resolvers = cfg.PropagationResolvers
+	}
+
+	resolvers := make([]string, len(settings.Resolvers))
+	for i, resolver := range settings.Resolvers {
+		if _, _, err := net.SplitHostPort(resolver); err != nil {
+			resolver = net.JoinHostPort(resolver, "53")
+		}
+		resolvers[i] = resolver
+	}
+	settings.Resolvers = resolvers
+	return settings
+}
+
+// splitResolvers splits a comma separated list of resolvers.
+func splitResolvers(list string) []string {
+	var resolvers []string
+	for _, resolver := range strings.Split(list, ",") {
+		if resolver = strings.TrimSpace(resolver); resolver != "" {
+			resolvers = append(resolvers, resolver)
+		}
+	}
+	return resolvers
+}
+
+// waitForPropagation queries the authoritative nameservers of the zone, then
+// the additional resolvers if any, until all of them return the TXT record
+// with the expected value, or the timeout expires. Each round of queries is
+// run by the verifier pool.
+func (p *verifierPool) waitForPropagation(ctx context.Context, zone, fqdn, value string, settings propagationSettings) error {
+	ctx, cancel := context.WithTimeout(ctx, settings.Timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(settings.Interval)
+	defer ticker.Stop()
+
+	// Where the record was missing on the last completed check
+	where := "the authoritative nameservers"
+	var err error
+	for {
+		// The variables of a check are only read once it has completed
+		var (
+			checkWhere string
+			ok         bool
+			checkErr   error
+		)
+		if p.do(ctx, zone, func() {
+			checkWhere, ok, checkErr = checkPropagation(fqdn, value, settings.Resolvers)
+		}) == nil {
+			if ok {
+				return nil
+			}
+			where, err = checkWhere, checkErr
+		}
+
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return fmt.Errorf("TXT record %s not visible on %s after %s: %w", fqdn, where, settings.Timeout, err)
+			}
+			return fmt.Errorf("TXT record %s not visible on %s after %s", fqdn, where, settings.Timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkPropagation reports whether the TXT record is visible on the
+// authoritative nameservers and on the resolvers. If not, it also returns
+// where it is missing.
+func checkPropagation(fqdn, value string, resolvers []string) (string, bool, error) {
+	// The recursive nameservers are only used to find the authoritative ones
+	ok, err := util.PreCheckDNS(fqdn, value, util.RecursiveNameservers, true)
+	if !ok || err != nil {
+		return "the authoritative nameservers", false, err
+	}
+	if len(resolvers) == 0 {
+		return "", true, nil
+	}
+
+	ok, err = util.PreCheckDNS(fqdn, value, resolvers, false)
+	if !ok || err != nil {
+		return strings.Join(resolvers, ", "), false, err
+	}
+	return "", true, nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPropagationTimeoutFromTTL(t *testing.T) {
+	ttl := &metav1.Duration{Duration: 5 * time.Minute}
+	tests := []struct {
+		name string
+		cfg  ddDNSProviderConfig
+		want time.Duration
+	}{
+		{"default", ddDNSProviderConfig{}, *propagationTimeout},
+		{"record TTL", ddDNSProviderConfig{RecordTTL: ttl}, ttl.Duration + *propagationTTLMargin},
+		{"explicit", ddDNSProviderConfig{RecordTTL: ttl, PropagationTimeout: &metav1.Duration{Duration: time.Minute}}, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.propagationSettings().Timeout; got != tt.want {
+			t.Errorf("%s: propagation timeout = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// TXTRecord is a TXT record of a zone.
+type TXTRecord struct {
+	// ID identifies the record within its zone
+	ID string
+	// Name is the fully qualified name of the record, without the trailing
+	// dot
+	Name  string
+	Value string
+}
+
+// DNSProvider is the backend in which the solver manages the challenge
+// records. The zones are the registered domains returned by getDomain and the
+// record names are fully qualified, without the trailing dot.
+// The DonDominio Client implements it.
+type DNSProvider interface {
+	// ValidateZone checks that the zone exists and can be managed. Its error
+	// wraps ErrServiceNotActive if it does not.
+	ValidateZone(ctx context.Context, zone string) error
+	// ListTXT returns the TXT records of zone with the given name and, if not
+	// empty, value.
+	ListTXT(ctx context.Context, zone, name, value string) ([]TXTRecord, error)
+	// CreateTXT creates a TXT record with the given TTL, zero for the
+	// default one, and returns it as stored by the provider, whose value may
+	// differ if the provider mangled it. It does not create a duplicate if a
+	// retried creation had already succeeded. The ID of the record returned
+	// is empty if the provider does not report it.
+	CreateTXT(ctx context.Context, zone, name, value string, ttl time.Duration) (TXTRecord, error)
+	// UpdateTXT sets the value and the TTL, zero to leave it unchanged, of the
+	// TXT record of zone with the given ID, and returns it as stored by the
+	// provider. Its error wraps ErrRecordNotFound if there is no such record.
+	UpdateTXT(ctx context.Context, zone, id, value string, ttl time.Duration) (TXTRecord, error)
+	// DeleteTXT deletes the record of zone with the given ID. Its error
+	// wraps ErrRecordNotFound if there is no such record.
+	DeleteTXT(ctx context.Context, zone, id string) error
+}
+
+var _ DNSProvider = (*Client)(nil)
+
+// ValidateZone implements DNSProvider: the zone must be an active DonDominio
+// service. The zones found active are cached for ServiceCacheTTL.
+func (c *Client) ValidateZone(ctx context.Context, zone string) error {
+	return c.services.validate(ctx, zone, c.ServiceCacheTTL, func(ctx context.Context, zone string) error {
+		return validateService(ctx, c, zone)
+	})
+}
+
+// ListTXT implements DNSProvider.
+func (c *Client) ListTXT(ctx context.Context, zone, name, value string) ([]TXTRecord, error) {
+	records, err := c.ListRecords(ctx, zone, RecordFilter{Name: name, Type: RecordTypeTXT, Value: value})
+	if err != nil {
+		return nil, err
+	}
+	var txt []TXTRecord
+	for _, record := range records {
+		txt = append(txt, txtRecord(record))
+	}
+	return txt, nil
+}
+
+// CreateTXT implements DNSProvider.
+func (c *Client) CreateTXT(ctx context.Context, zone, name, value string, ttl time.Duration) (TXTRecord, error) {
+	record, err := c.CreateRecord(ctx, zone, RecordParams{Name: name, Type: RecordTypeTXT, Value: value, TTL: ttl})
+	return txtRecord(record), err
+}
+
+// UpdateTXT implements DNSProvider.
+func (c *Client) UpdateTXT(ctx context.Context, zone, id, value string, ttl time.Duration) (TXTRecord, error) {
+	record, err := c.ModifyRecord(ctx, zone, id, RecordUpdate{Value: value, TTL: ttl})
+	if err != nil {
+		return TXTRecord{}, err
+	}
+	return txtRecord(record), nil
+}
+
+// DeleteTXT implements DNSProvider.
+func (c *Client) DeleteTXT(ctx context.Context, zone, id string) error {
+	return c.DeleteRecord(ctx, zone, id)
+}
+
+// txtRecord returns the TXTRecord of a record.
+func txtRecord(record Record) TXTRecord {
+	return TXTRecord{ID: record.ID, Name: record.Name, Value: record.Value}
+}
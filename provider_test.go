@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeProvider is an in-memory DNSProvider.
+type fakeProvider struct {
+	zones   map[string][]TXTRecord
+	nextID  int
+	created int
+	updated int
+	deleted int
+	// mangle, if set, changes the values of the records created or updated
+	mangle func(value string) string
+}
+
+func (p *fakeProvider) ValidateZone(ctx context.Context, zone string) error {
+	if _, ok := p.zones[zone]; !ok {
+		return fmt.Errorf("zone %s: %w", zone, ErrServiceNotActive)
+	}
+	return nil
+}
+
+func (p *fakeProvider) ListTXT(ctx context.Context, zone, name, value string) ([]TXTRecord, error) {
+	records, ok := p.zones[zone]
+	if !ok {
+		return nil, fmt.Errorf("zone %s: %w", zone, ErrServiceNotActive)
+	}
+	var found []TXTRecord
+	for _, record := range records {
+		if record.Name == name && (value == "" || record.Value == value) {
+			found = append(found, record)
+		}
+	}
+	return found, nil
+}
+
+func (p *fakeProvider) CreateTXT(ctx context.Context, zone, name, value string, ttl time.Duration) (TXTRecord, error) {
+	p.nextID++
+	p.created++
+	if p.mangle != nil {
+		value = p.mangle(value)
+	}
+	record := TXTRecord{ID: fmt.Sprint(p.nextID), Name: name, Value: value}
+	p.zones[zone] = append(p.zones[zone], record)
+	return record, nil
+}
+
+func (p *fakeProvider) UpdateTXT(ctx context.Context, zone, id, value string, ttl time.Duration) (TXTRecord, error) {
+	for i, record := range p.zones[zone] {
+		if record.ID == id {
+			if p.mangle != nil {
+				value = p.mangle(value)
+			}
+			p.zones[zone][i].Value = value
+			p.updated++
+			return p.zones[zone][i], nil
+		}
+	}
+	return TXTRecord{}, ErrRecordNotFound
+}
+
+func (p *fakeProvider) DeleteTXT(ctx context.Context, zone, id string) error {
+	for i, record := range p.zones[zone] {
+		if record.ID == id {
+			p.zones[zone] = append(p.zones[zone][:i], p.zones[zone][i+1:]...)
+			p.deleted++
+			return nil
+		}
+	}
+	return ErrRecordNotFound
+}
+
+func TestSolverWithFakeProvider(t *testing.T) {
+	provider := &fakeProvider{zones: map[string][]TXTRecord{
+		"example.com": {{ID: "old", Name: "_acme-challenge.www.example.com", Value: "stale-key"}},
+	}}
+	solver := testSolver()
+	solver.provider = provider
+
+	ch := testChallenge(t, "http://dondominio.invalid", "_acme-challenge.www.example.com.", "example.com.", "challenge-key",
+		map[string]interface{}{"conflictPolicy": conflictPolicyReplace})
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("Present() error: %v", err)
+	}
+	want := []TXTRecord{{ID: "1", Name: "_acme-challenge.www.example.com", Value: "challenge-key"}}
+	if got := provider.zones["example.com"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("records after Present = %+v, want %+v", got, want)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := solver.CleanUp(ch); err != nil {
+			t.Fatalf("CleanUp() error: %v", err)
+		}
+	}
+	if got := provider.zones["example.com"]; len(got) != 0 {
+		t.Errorf("records after CleanUp = %+v, want none", got)
+	}
+	if provider.created != 1 || provider.deleted != 2 {
+		t.Errorf("created %d and deleted %d records, want 1 and 2", provider.created, provider.deleted)
+	}
+
+	missing := testChallenge(t, "http://dondominio.invalid", "_acme-challenge.example.org.", "example.org.", "challenge-key", nil)
+	if err := solver.Present(missing); !errors.Is(err, ErrServiceNotActive) {
+		t.Errorf("Present() in a missing zone error = %v, want %v", err, ErrServiceNotActive)
+	}
+}
+
+func TestSolverKeepsRecordsOfOtherWebhooks(t *testing.T) {
+	// The same challenge is also solved by the webhook of another cluster
+	// sharing the zone
+	provider := &fakeProvider{nextID: 10, zones: map[string][]TXTRecord{
+		"example.com": {{ID: "other", Name: "_acme-challenge.example.com", Value: "challenge-key"}},
+	}}
+	solver := testSolver()
+	solver.provider = provider
+
+	ch := testChallenge(t, "http://dondominio.invalid", "_acme-challenge.example.com.", "example.com.", "challenge-key", nil)
+	for i := 0; i < 2; i++ {
+		if err := solver.Present(ch); err != nil {
+			t.Fatalf("Present() error: %v", err)
+		}
+	}
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("CleanUp() error: %v", err)
+	}
+	want := []TXTRecord{{ID: "other", Name: "_acme-challenge.example.com", Value: "challenge-key"}}
+	if got := provider.zones["example.com"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("records after CleanUp = %+v, want %+v", got, want)
+	}
+}
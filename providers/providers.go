@@ -0,0 +1,16 @@
+// Package providers is imported by the webhook for its side effects: each
+// file of the package registers a DNS01 solver with registry.RegisterSolver
+// from an init function. Forks add their providers here, next to the
+// DonDominio solver registered by the main package, without editing main.go.
+//
+// For example, a providers/example.go file holding:
+//
+//	func init() {
+//		registry.RegisterSolver("example", func() webhook.Solver {
+//			return &exampleSolver{}
+//		})
+//	}
+//
+// makes the webhook serve the "example" solver, unless the --solvers flag
+// lists the solvers to serve without it.
+package providers
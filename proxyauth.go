@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// proxyCredentials are the credentials sent to the proxies whose URL has
+// none. The transport sends them in the Proxy-Authorization header of the
+// plain HTTP requests and of the CONNECT requests tunneling HTTPS, including
+// to the https:// proxies.
+var proxyCredentials atomic.Pointer[url.Userinfo]
+
+// withProxyCredentials returns the proxy URL with the proxyCredentials, if
+// any and if it has no credentials of its own.
+func withProxyCredentials(proxy *url.URL) *url.URL {
+	creds := proxyCredentials.Load()
+	if proxy == nil || proxy.User != nil || creds == nil {
+		return proxy
+	}
+	withCreds := *proxy
+	withCreds.User = creds
+	return &withCreds
+}
+
+// setupProxyCredentials sets the proxyCredentials from the "namespace/name"
+// basic-auth secret of --proxy-credentials-secret if set, or else from the
+// --proxy-username and --proxy-password flags. The secret is only read on
+// startup.
+func setupProxyCredentials(ctx context.Context, client kubernetes.Interface) error {
+	username, password := *proxyUsername, *proxyPassword
+	if *proxyCredentialsSecret != "" {
+		namespace, name, err := splitConfigMap(*proxyCredentialsSecret)
+		if err != nil {
+			return fmt.Errorf("invalid --proxy-credentials-secret: %w", err)
+		}
+		secret, err := client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to read the proxy credentials: %w", err)
+		}
+		username = strings.TrimSuffix(string(secret.Data[corev1.BasicAuthUsernameKey]), "\n")
+		password = strings.TrimSuffix(string(secret.Data[corev1.BasicAuthPasswordKey]), "\n")
+		if username == "" {
+			return fmt.Errorf("no %q key in the proxy credentials secret %s", corev1.BasicAuthUsernameKey, *proxyCredentialsSecret)
+		}
+	}
+	if username == "" {
+		proxyCredentials.Store(nil)
+		return nil
+	}
+	registerSecret(password)
+	proxyCredentials.Store(url.UserPassword(username, password))
+	return nil
+}
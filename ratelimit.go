@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+var rateLimitThrottleSeconds = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "rate_limit_throttle_seconds_total",
+	Help:      "Time spent by the DonDominio API requests waiting for the client-side rate limit of their account.",
+})
+
+func init() {
+	metricsRegistry.MustRegister(rateLimitThrottleSeconds)
+}
+
+// rateLimitUnits are the units of a rate limit, e.g. 300/m.
+var rateLimitUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+// parseRateLimit parses a rate limit written as a number of requests per
+// second, minute or hour, e.g. 10/s or 300/m.
+func parseRateLimit(value string) (rate.Limit, error) {
+	count, unit, ok := strings.Cut(value, "/")
+	per, known := rateLimitUnits[unit]
+	if !ok || !known {
+		return 0, fmt.Errorf("invalid rate limit %q, want <requests>/s, /m or /h", value)
+	}
+	n, err := strconv.ParseFloat(count, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid rate limit %q, want a positive number of requests", value)
+	}
+	return rate.Limit(n / per.Seconds()), nil
+}
+
+// accountRateLimits holds the token buckets of the DonDominio accounts, so
+// that the issuers sharing an account, e.g. the tenants of a reseller
+// account, share its budget. Its zero value does not limit any account.
+type accountRateLimits struct {
+	// defaultLimit applies to the accounts without a limit of their own,
+	// zero if they are not limited
+	defaultLimit rate.Limit
+	// limits are the limits of the accounts, by API user
+	limits map[string]rate.Limit
+	burst  int
+	// quotaInterval, if not zero, is the interval between two checks of the
+	// API quota of an account, whose requests are limited to quotaLowLimit
+	// when less than the quotaThreshold fraction of it remains
+	quotaInterval  time.Duration
+	quotaThreshold float64
+	quotaLowLimit  rate.Limit
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	quotas   map[string]*accountQuota
+}
+
+// newAccountRateLimits returns the rate limits configured by defaultLimit,
+// empty for none, and perAccount, a comma separated list of apiuser=limit.
+func newAccountRateLimits(defaultLimit, perAccount string, burst int) (*accountRateLimits, error) {
+	if burst < 1 {
+		return nil, fmt.Errorf("invalid rate limit burst %d, want at least 1", burst)
+	}
+	l := &accountRateLimits{limits: make(map[string]rate.Limit), burst: burst}
+	if defaultLimit != "" {
+		limit, err := parseRateLimit(defaultLimit)
+		if err != nil {
+			return nil, err
+		}
+		l.defaultLimit = limit
+	}
+	for _, item := range strings.Split(perAccount, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		account, value, ok := strings.Cut(item, "=")
+		if !ok || account == "" {
+			return nil, fmt.Errorf("invalid account rate limit %q, want apiuser=<requests>/<unit>", item)
+		}
+		limit, err := parseRateLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("account %s: %w", account, err)
+		}
+		l.limits[account] = limit
+	}
+	return l, nil
+}
+
+// withQuota enables the checks of the API quota of the accounts every
+// interval, limiting the requests of an account to lowLimit, e.g. 10/m, when
+// less than the threshold fraction of its quota remains.
+func (l *accountRateLimits) withQuota(interval time.Duration, threshold float64, lowLimit string) error {
+	if threshold <= 0 || threshold >= 1 {
+		return fmt.Errorf("invalid quota threshold %v, want a fraction between 0 and 1", threshold)
+	}
+	limit, err := parseRateLimit(lowLimit)
+	if err != nil {
+		return fmt.Errorf("quota rate limit: %w", err)
+	}
+	l.quotaInterval, l.quotaThreshold, l.quotaLowLimit = interval, threshold, limit
+	return nil
+}
+
+// limit returns the limit of the account, rate.Inf if it is not limited.
+func (l *accountRateLimits) limit(apiUser string) rate.Limit {
+	limit, ok := l.limits[apiUser]
+	if !ok {
+		limit = l.defaultLimit
+	}
+	if limit == 0 {
+		return rate.Inf
+	}
+	return limit
+}
+
+// forAccount returns the token bucket of the account, nil if it is not
+// limited. The accounts whose quota is checked always have one.
+func (l *accountRateLimits) forAccount(apiUser string) *rate.Limiter {
+	if l == nil {
+		return nil
+	}
+	limit := l.limit(apiUser)
+	if limit == rate.Inf && l.quotaInterval == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limiter, ok := l.limiters[apiUser]; ok {
+		return limiter
+	}
+	if l.limiters == nil {
+		l.limiters = make(map[string]*rate.Limiter)
+	}
+	limiter := rate.NewLimiter(limit, l.burst)
+	l.limiters[apiUser] = limiter
+	return limiter
+}
+
+// quotaForAccount returns the checks of the API quota of the account, nil if
+// they are disabled.
+func (l *accountRateLimits) quotaForAccount(apiUser string) *accountQuota {
+	if l == nil || l.quotaInterval == 0 {
+		return nil
+	}
+	limiter := l.forAccount(apiUser)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if quota, ok := l.quotas[apiUser]; ok {
+		return quota
+	}
+	if l.quotas == nil {
+		l.quotas = make(map[string]*accountQuota)
+	}
+	quota := &accountQuota{
+		account:     apiUser,
+		interval:    l.quotaInterval,
+		threshold:   l.quotaThreshold,
+		lowLimit:    l.quotaLowLimit,
+		limiter:     limiter,
+		normalLimit: l.limit(apiUser),
+	}
+	l.quotas[apiUser] = quota
+	return quota
+}
+
+// throttle waits for a token of limiter, or until ctx is done.
+func throttle(ctx context.Context, limiter *rate.Limiter) error {
+	start := time.Now()
+	if err := limiter.Wait(ctx); err != nil {
+		return err
+	}
+	rateLimitThrottleSeconds.Add(time.Since(start).Seconds())
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    rate.Limit
+		wantErr bool
+	}{
+		{value: "10/s", want: 10},
+		{value: "300/m", want: 5},
+		{value: "1800/h", want: 0.5},
+		{value: "10", wantErr: true},
+		{value: "10/d", wantErr: true},
+		{value: "0/s", wantErr: true},
+		{value: "many/s", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseRateLimit(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRateLimit(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseRateLimit(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestAccountRateLimits(t *testing.T) {
+	limits, err := newAccountRateLimits("10/s", "reseller=300/m, tenant=1/s", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reseller := limits.forAccount("reseller")
+	if reseller == nil || reseller.Limit() != 5 || reseller.Burst() != 2 {
+		t.Fatalf("reseller limiter = %+v, want 5/s with a burst of 2", reseller)
+	}
+	if limits.forAccount("reseller") != reseller {
+		t.Error("the issuers of an account do not share its limiter")
+	}
+	if other := limits.forAccount("other"); other == nil || other.Limit() != 10 {
+		t.Errorf("default limiter = %+v, want 10/s", other)
+	}
+
+	unlimited, err := newAccountRateLimits("", "reseller=300/m", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limiter := unlimited.forAccount("other"); limiter != nil {
+		t.Errorf("limiter of an account without limit = %+v, want none", limiter)
+	}
+	var none *accountRateLimits
+	if limiter := none.forAccount("reseller"); limiter != nil {
+		t.Errorf("limiter without rate limits = %+v, want none", limiter)
+	}
+
+	for _, perAccount := range []string{"reseller", "=300/m", "reseller=fast"} {
+		if _, err := newAccountRateLimits("", perAccount, 1); err == nil {
+			t.Errorf("newAccountRateLimits(%q) did not fail", perAccount)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Record types of the DonDominio zones supported by the client.
+const (
+	RecordTypeA     = "A"
+	RecordTypeAAAA  = "AAAA"
+	RecordTypeCNAME = "CNAME"
+	RecordTypeMX    = "MX"
+	RecordTypeTXT   = "TXT"
+	RecordTypeSRV   = "SRV"
+	RecordTypeCAA   = "CAA"
+)
+
+// recordTypes is the set of the supported record types.
+var recordTypes = map[string]bool{
+	RecordTypeA:     true,
+	RecordTypeAAAA:  true,
+	RecordTypeCNAME: true,
+	RecordTypeMX:    true,
+	RecordTypeTXT:   true,
+	RecordTypeSRV:   true,
+	RecordTypeCAA:   true,
+}
+
+// Record is a record of a zone, as stored by DonDominio.
+type Record struct {
+	// ID identifies the record within its zone
+	ID string
+	// Name is the fully qualified name of the record, without the trailing
+	// dot
+	Name string
+	Type string
+	// TTL is zero if DonDominio does not report it
+	TTL time.Duration
+	// Priority of the MX and SRV records
+	Priority int
+	// Value is in the format of DonDominio, e.g. "weight port target" for
+	// the SRV records or "0 issue \"letsencrypt.org\"" for the CAA ones
+	Value string
+}
+
+// recordFromDns converts a record of a DonDominio response.
+func recordFromDns(dns Dns) Record {
+	ttl, _ := strconv.Atoi(dns.Ttl)
+	priority, _ := strconv.Atoi(dns.Priority)
+	return Record{
+		ID:       dns.EntityID,
+		Name:     dns.Name,
+		Type:     dns.Type,
+		TTL:      time.Duration(ttl) * time.Second,
+		Priority: priority,
+		Value:    dns.Value,
+	}
+}
+
+// RecordParams are the fields of a record created by CreateRecord.
+type RecordParams struct {
+	// Name is the fully qualified name of the record, without the trailing
+	// dot
+	Name  string
+	Type  string
+	Value string
+	// TTL is zero for the default TTL of the zone
+	TTL time.Duration
+	// Priority of the MX and SRV records
+	Priority *int
+}
+
+// RecordFilter selects the records returned by ListRecords. The empty fields
+// match any record.
+type RecordFilter struct {
+	Name  string
+	Type  string
+	Value string
+}
+
+// matches reports whether the record is selected by the filter.
+func (f RecordFilter) matches(dns Dns) bool {
+	return (f.Name == "" || dns.Name == f.Name) &&
+		(f.Type == "" || dns.Type == f.Type) &&
+		(f.Value == "" || dns.Value == f.Value)
+}
+
+// validateRecordType fails if the client does not support the record type.
+func validateRecordType(recordType string) error {
+	if !recordTypes[recordType] {
+		return fmt.Errorf("unsupported record type %q", recordType)
+	}
+	return nil
+}
+
+// ListRecords returns the records of zone selected by the filter.
+func (c *Client) ListRecords(ctx context.Context, zone string, filter RecordFilter) ([]Record, error) {
+	if filter.Type != "" {
+		if err := validateRecordType(filter.Type); err != nil {
+			return nil, err
+		}
+	}
+	records, err := findRecords(ctx, c, zone, filter.Name, filter.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	// The filters of the API are not exact
+	var selected []Record
+	for _, dns := range records.ResponseData.Dns {
+		if filter.matches(dns) {
+			selected = append(selected, recordFromDns(dns))
+		}
+	}
+	return selected, nil
+}
+
+// GetRecord returns the record of zone with the given ID. Its error wraps
+// ErrRecordNotFound if there is no such record.
+func (c *Client) GetRecord(ctx context.Context, zone, id string) (Record, error) {
+	records, err := findRecords(ctx, c, zone, "", "")
+	if err != nil {
+		return Record{}, err
+	}
+	for _, dns := range records.ResponseData.Dns {
+		if dns.EntityID == id {
+			return recordFromDns(dns), nil
+		}
+	}
+	return Record{}, fmt.Errorf("record %s of zone %s: %w", id, zone, ErrRecordNotFound)
+}
+
+// CreateRecord creates a record in zone and returns it as stored by
+// DonDominio, whose value may differ if DonDominio mangled it. It does not
+// create a duplicate if a retried creation had already succeeded. The ID of
+// the record returned is empty if DonDominio does not report it.
+func (c *Client) CreateRecord(ctx context.Context, zone string, params RecordParams) (Record, error) {
+	if err := validateRecordType(params.Type); err != nil {
+		return Record{}, err
+	}
+	created := Record{Name: params.Name, Type: params.Type, TTL: params.TTL, Value: params.Value}
+	if params.Priority != nil {
+		created.Priority = *params.Priority
+	}
+	// dnscreate is not retried by the client: a failed attempt may still
+	// have created the record, so look it up before trying again.
+	err := c.Retry(ctx, func(attempt int) error {
+		if attempt > 0 {
+			existing, err := c.ListRecords(ctx, zone, RecordFilter{Name: params.Name, Type: params.Type, Value: params.Value})
+			if err != nil || len(existing) > 0 {
+				if len(existing) > 0 {
+					created = existing[len(existing)-1]
+				}
+				return err
+			}
+		}
+		records, err := createRecord(ctx, c, zone, params)
+		if errors.Is(err, ErrServiceNotActive) {
+			c.services.forget(zone)
+		}
+		if err == nil && len(records.ResponseData.Dns) > 0 {
+			created = recordFromDns(records.ResponseData.Dns[0])
+		}
+		return err
+	})
+	return created, err
+}
+
+// RecordUpdate is a change of a record made by ModifyRecord. The zero fields
+// are left unchanged.
+type RecordUpdate struct {
+	Value string
+	TTL   time.Duration
+	// Priority of the MX and SRV records
+	Priority *int
+}
+
+// ModifyRecord applies the update to the record of zone with the given ID
+// and returns the record as stored by DonDominio. Its error wraps
+// ErrRecordNotFound if there is no such record. dnsupdate is idempotent, so
+// it is retried by the client.
+func (c *Client) ModifyRecord(ctx context.Context, zone, id string, update RecordUpdate) (Record, error) {
+	url := "/service/dnsupdate"
+	params := ddUpdateServiceParams{
+		ServiceName: zone,
+		EntityId:    id,
+		Value:       update.Value,
+		TTL:         int(update.TTL / time.Second),
+		Priority:    update.Priority,
+	}
+	records := ddServiceList{}
+	err := c.PostWithContext(ctx, url, &params, &records)
+	c.audit(ctx, auditEntry{Action: auditUpdate, Zone: zone, ID: id, ValueHash: auditValueHash(update.Value)}, err)
+	if err != nil {
+		return Record{}, fmt.Errorf("DonDominio API call failed: POST %s - %w", url, err)
+	}
+
+	// Older versions of the API do not return the record
+	if len(records.ResponseData.Dns) == 0 {
+		return Record{ID: id, TTL: update.TTL, Value: update.Value}, nil
+	}
+	return recordFromDns(records.ResponseData.Dns[0]), nil
+}
+
+// DeleteRecord deletes the record of zone with the given ID. Its error wraps
+// ErrRecordNotFound if there is no such record.
+func (c *Client) DeleteRecord(ctx context.Context, zone, id string) error {
+	return deleteRecord(ctx, c, zone, id)
+}
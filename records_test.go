@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClientRecords(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, []Dns{
+		{EntityID: "1", Name: "example.com", Type: "A", Ttl: "300", Value: "192.0.2.1"},
+		{EntityID: "2", Name: "example.com", Type: "AAAA", Ttl: "300", Value: "2001:db8::1"},
+		{EntityID: "3", Name: "www.example.com", Type: "CNAME", Ttl: "300", Value: "example.com"},
+	})
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	ddClient, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	records, err := ddClient.ListRecords(ctx, "example.com", RecordFilter{Name: "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Record{
+		{ID: "1", Name: "example.com", Type: RecordTypeA, TTL: 5 * time.Minute, Value: "192.0.2.1"},
+		{ID: "2", Name: "example.com", Type: RecordTypeAAAA, TTL: 5 * time.Minute, Value: "2001:db8::1"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("ListRecords() = %+v, want %+v", records, want)
+	}
+
+	priority := 10
+	mx, err := ddClient.CreateRecord(ctx, "example.com", RecordParams{Name: "example.com", Type: RecordTypeMX, Value: "mx.example.com", Priority: &priority})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mx.ID == "" || mx.Priority != 10 {
+		t.Errorf("CreateRecord() = %+v, want an MX record with priority 10", mx)
+	}
+	got, err := ddClient.GetRecord(ctx, "example.com", mx.ID)
+	if err != nil || got != mx {
+		t.Errorf("GetRecord() = %+v, %v, want %+v", got, err, mx)
+	}
+
+	if err := ddClient.DeleteRecord(ctx, "example.com", mx.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ddClient.GetRecord(ctx, "example.com", mx.ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetRecord() of a deleted record error = %v, want %v", err, ErrRecordNotFound)
+	}
+
+	if _, err := ddClient.CreateRecord(ctx, "example.com", RecordParams{Name: "example.com", Type: "NS", Value: "ns.example.com"}); err == nil {
+		t.Error("CreateRecord() of an unsupported type succeeded")
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// redactedValue replaces the credentials scrubbed from the errors and logs.
+const redactedValue = "REDACTED"
+
+// minRedactedSecretLength is the length under which the values of the
+// secrets are not scrubbed, since they would match innocent text.
+const minRedactedSecretLength = 8
+
+// plainWordPattern matches the values that could be plain text, e.g. a
+// field name or a word of a message, which are not scrubbed either: the
+// real secrets mix cases, digits or symbols.
+var plainWordPattern = regexp.MustCompile(`^[a-z]+$`)
+
+// credentialFieldPattern matches the redactedFields of a form encoded body,
+// e.g. echoed by a proxy in an error page, or of a JSON object.
+var credentialFieldPattern = regexp.MustCompile(`(?i)\b(` + strings.Join(redactedFields, "|") + `)(=|"\s*:\s*")[^&\s"]*`)
+
+// secretRegistry holds the values of the secrets the webhook has read, e.g.
+// the API passwords and the proxy password, scrubbed wherever they appear.
+// Its zero value is ready to use.
+type secretRegistry struct {
+	mu     sync.RWMutex
+	values map[string]bool
+}
+
+// knownSecrets are the secrets scrubbed by redactSecrets.
+var knownSecrets secretRegistry
+
+// register adds the value of a secret, unless it could be mistaken for
+// plain text.
+func (r *secretRegistry) register(value string) {
+	if len(value) < minRedactedSecretLength || plainWordPattern.MatchString(value) {
+		return
+	}
+	for _, field := range redactedFields {
+		if strings.EqualFold(value, field) {
+			return
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.values == nil {
+		r.values = make(map[string]bool)
+	}
+	r.values[value] = true
+}
+
+// redact replaces the values of the secrets in s.
+func (r *secretRegistry) redact(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for secret := range r.values {
+		s = strings.ReplaceAll(s, secret, redactedValue)
+	}
+	return s
+}
+
+// reset forgets all the secrets.
+func (r *secretRegistry) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values = nil
+}
+
+// registerSecret makes the value of a secret scrubbed from the errors and
+// logs.
+func registerSecret(value string) {
+	knownSecrets.register(value)
+}
+
+// redactSecrets scrubs the credential form fields and the values of the
+// known secrets from s.
+func redactSecrets(s string) string {
+	return knownSecrets.redact(credentialFieldPattern.ReplaceAllString(s, "${1}${2}"+redactedValue))
+}
+
+// redactedError is an error whose message has been scrubbed of credentials.
+// It still matches the errors it wraps.
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return redactSecrets(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactError returns err with its message scrubbed of credentials, or err
+// itself if there is nothing to scrub.
+func redactError(err error) error {
+	if err == nil || redactSecrets(err.Error()) == err.Error() {
+		return err
+	}
+	return &redactedError{err: err}
+}
+
+// redactingWriter scrubs the credentials from the log lines written to w.
+// klog writes each line with a single call.
+type redactingWriter struct {
+	w io.Writer
+}
+
+func (r redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, redactSecrets(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redactingSink is a logr.LogSink scrubbing the credentials from the
+// messages, the string values and the errors it logs.
+type redactingSink struct {
+	sink logr.LogSink
+}
+
+// redactingLogger returns logger with the credentials scrubbed from what it
+// logs.
+func redactingLogger(logger logr.Logger) logr.Logger {
+	if logger.GetSink() == nil {
+		return logger
+	}
+	return logr.New(redactingSink{sink: logger.GetSink()})
+}
+
+func (s redactingSink) Init(info logr.RuntimeInfo) {
+	// One more frame for the redactingSink
+	info.CallDepth++
+	s.sink.Init(info)
+}
+
+func (s redactingSink) Enabled(level int) bool {
+	return s.sink.Enabled(level)
+}
+
+func (s redactingSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.sink.Info(level, redactSecrets(msg), redactValues(keysAndValues)...)
+}
+
+func (s redactingSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.sink.Error(redactError(err), redactSecrets(msg), redactValues(keysAndValues)...)
+}
+
+func (s redactingSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return redactingSink{sink: s.sink.WithValues(redactValues(keysAndValues)...)}
+}
+
+func (s redactingSink) WithName(name string) logr.LogSink {
+	return redactingSink{sink: s.sink.WithName(name)}
+}
+
+// redactValues returns the key and value pairs with their string and error
+// values scrubbed of credentials.
+func redactValues(keysAndValues []interface{}) []interface{} {
+	redacted := make([]interface{}, len(keysAndValues))
+	for i, value := range keysAndValues {
+		switch v := value.(type) {
+		case string:
+			value = redactSecrets(v)
+		case error:
+			value = redactError(v)
+		}
+		redacted[i] = value
+	}
+	return redacted
+}
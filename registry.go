@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+	"k8s.io/klog/v2"
+)
+
+// externalDNSRegistryConfig configures the ownership records written in the
+// TXT registry format of external-dns next to the challenge records, so that
+// an external-dns instance managing the same zone leaves them alone.
+type externalDNSRegistryConfig struct {
+	// OwnerID is the owner of the records. It must differ from the
+	// --txt-owner-id of external-dns, which otherwise deletes them.
+	OwnerID string `json:"ownerID"`
+	// Prefix is the --txt-prefix of external-dns.
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// recordName returns the name of the ownership record of the TXT record
+// name, in the format of external-dns 0.12 and later: the record type is
+// inserted after the prefix in the first label.
+func (r *externalDNSRegistryConfig) recordName(name string) string {
+	first, rest, ok := strings.Cut(name, ".")
+	owner := r.Prefix + "txt-" + first
+	if !ok {
+		return owner
+	}
+	return owner + "." + rest
+}
+
+// value returns the value of the ownership records, quoted like external-dns
+// writes it.
+func (r *externalDNSRegistryConfig) value() string {
+	return `"heritage=external-dns,external-dns/owner=` + r.OwnerID + `"`
+}
+
+// claimOwnership creates the ownership record of the challenge record name,
+// unless it already exists for a concurrent challenge.
+func claimOwnership(ctx context.Context, provider DNSProvider, registry *externalDNSRegistryConfig, zone, name string) error {
+	ownerName := registry.recordName(name)
+	existing, err := provider.ListTXT(ctx, zone, ownerName, registry.value())
+	if err != nil || len(existing) > 0 {
+		return err
+	}
+	_, err = provider.CreateTXT(ctx, zone, ownerName, registry.value(), 0)
+	if err == nil {
+		klog.FromContext(ctx).Info("External-dns ownership record created", "record", ownerName)
+	}
+	return err
+}
+
+// releaseOwnership deletes the ownership record of the challenge record name
+// once no challenge record with that name remains.
+func releaseOwnership(ctx context.Context, provider DNSProvider, registry *externalDNSRegistryConfig, zone, name string) error {
+	remaining, err := provider.ListTXT(ctx, zone, name, "")
+	if err != nil || len(remaining) > 0 {
+		return err
+	}
+	_, err = EnsureAbsent(ctx, provider, zone, util.ToFqdn(registry.recordName(name)), registry.value())
+	return err
+}
@@ -0,0 +1,68 @@
+// Package registry holds the DNS01 solvers built into the webhook. Each
+// provider registers its solver from an init function, so that adding a
+// provider only requires adding a file under providers/ and the shipped
+// binary can select the providers it serves with the --solvers flag.
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook"
+)
+
+// Factory returns a new solver.
+type Factory func() webhook.Solver
+
+var (
+	mu        sync.Mutex
+	factories = map[string]Factory{}
+)
+
+// RegisterSolver registers the factory of the solver with the given name,
+// which must be the one returned by its Name method. It panics if the name
+// is already registered.
+func RegisterSolver(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("solver %q registered twice", name))
+	}
+	factories[name] = factory
+}
+
+// Names returns the names of the registered solvers, sorted.
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Solvers returns new instances of the named solvers, or of all the
+// registered solvers if names is empty.
+func Solvers(names []string) ([]webhook.Solver, error) {
+	if len(names) == 0 {
+		names = Names()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	solvers := make([]webhook.Solver, 0, len(names))
+	for _, name := range names {
+		factory, ok := factories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown solver %q", name)
+		}
+		solvers = append(solvers, factory())
+	}
+	return solvers, nil
+}
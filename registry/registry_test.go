@@ -0,0 +1,70 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"k8s.io/client-go/rest"
+)
+
+type namedSolver string
+
+func (s namedSolver) Name() string                                   { return string(s) }
+func (s namedSolver) Present(ch *v1alpha1.ChallengeRequest) error    { return nil }
+func (s namedSolver) CleanUp(ch *v1alpha1.ChallengeRequest) error    { return nil }
+func (s namedSolver) Initialize(*rest.Config, <-chan struct{}) error { return nil }
+
+// isolateSolvers empties the registry for the duration of the test, e.g. so
+// that it can register its solvers again with -count=2.
+func isolateSolvers(t *testing.T) {
+	mu.Lock()
+	registered := factories
+	factories = map[string]Factory{}
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		factories = registered
+		mu.Unlock()
+	})
+}
+
+func TestSolvers(t *testing.T) {
+	isolateSolvers(t)
+	for _, name := range []string{"second", "first"} {
+		name := name
+		RegisterSolver(name, func() webhook.Solver { return namedSolver(name) })
+	}
+
+	if got, want := Names(), []string{"first", "second"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		names   []string
+		want    []webhook.Solver
+		wantErr bool
+	}{
+		{names: nil, want: []webhook.Solver{namedSolver("first"), namedSolver("second")}},
+		{names: []string{"second"}, want: []webhook.Solver{namedSolver("second")}},
+		{names: []string{"third"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Solvers(tt.names)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Solvers(%q) error = %v, wantErr %v", tt.names, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Solvers(%q) = %v, want %v", tt.names, got, tt.want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice did not panic")
+		}
+	}()
+	RegisterSolver("first", func() webhook.Solver { return namedSolver("first") })
+}
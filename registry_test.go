@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func TestExternalDNSRegistryRecordName(t *testing.T) {
+	tests := []struct {
+		prefix string
+		name   string
+		want   string
+	}{
+		{"", "_acme-challenge.www.example.com", "txt-_acme-challenge.www.example.com"},
+		{"edns-", "_acme-challenge.example.com", "edns-txt-_acme-challenge.example.com"},
+	}
+	for _, tt := range tests {
+		registry := &externalDNSRegistryConfig{OwnerID: "cert-manager", Prefix: tt.prefix}
+		if got := registry.recordName(tt.name); got != tt.want {
+			t.Errorf("recordName(%q) with prefix %q = %q, want %q", tt.name, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestExternalDNSRegistry(t *testing.T) {
+	provider := &fakeProvider{zones: map[string][]TXTRecord{"example.com": nil}}
+	solver := testSolver()
+	solver.provider = provider
+
+	config := map[string]interface{}{
+		"externalDNSRegistry": map[string]string{"ownerID": "cert-manager"},
+	}
+	first := testChallenge(t, "http://dondominio.invalid", "_acme-challenge.example.com.", "example.com.", "first-key", config)
+	second := testChallenge(t, "http://dondominio.invalid", "_acme-challenge.example.com.", "example.com.", "second-key", config)
+	for _, ch := range []*v1alpha1.ChallengeRequest{first, second} {
+		if err := solver.Present(ch); err != nil {
+			t.Fatalf("Present() error: %v", err)
+		}
+	}
+
+	owner := TXTRecord{ID: "2", Name: "txt-_acme-challenge.example.com", Value: `"heritage=external-dns,external-dns/owner=cert-manager"`}
+	want := []TXTRecord{
+		{ID: "1", Name: "_acme-challenge.example.com", Value: "first-key"},
+		owner,
+		{ID: "3", Name: "_acme-challenge.example.com", Value: "second-key"},
+	}
+	if got := provider.zones["example.com"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("records after Present = %+v, want %+v", got, want)
+	}
+
+	// The ownership record is kept while a challenge record remains
+	if err := solver.CleanUp(first); err != nil {
+		t.Fatalf("CleanUp() error: %v", err)
+	}
+	want = []TXTRecord{owner, {ID: "3", Name: "_acme-challenge.example.com", Value: "second-key"}}
+	if got := provider.zones["example.com"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("records after the first CleanUp = %+v, want %+v", got, want)
+	}
+
+	if err := solver.CleanUp(second); err != nil {
+		t.Fatalf("CleanUp() error: %v", err)
+	}
+	if got := provider.zones["example.com"]; len(got) != 0 {
+		t.Errorf("records after the second CleanUp = %+v, want none", got)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// queryIDHistory is the number of query IDs of mutating calls remembered to
+// detect replayed responses.
+const queryIDHistory = 4096
+
+var replayedResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "replayed_responses_total",
+	Help:      "Number of responses to mutating DonDominio API calls carrying the query ID of another request, by path.",
+}, []string{"path"})
+
+func init() {
+	metricsRegistry.MustRegister(replayedResponses)
+}
+
+// queryIDs is shared by all the clients, since a proxy replaying responses
+// may do so across credentials.
+var queryIDs queryIDTracker
+
+type trackedQuery struct {
+	queryID     string
+	fingerprint string
+}
+
+// queryIDTracker remembers the X-Dd-QueryID of the last responses to the
+// mutating calls together with the request they answered. The API assigns a
+// new ID to every request, so the same ID answering a different request is a
+// response replayed by a proxy. Its zero value is ready to use.
+type queryIDTracker struct {
+	mu      sync.Mutex
+	queries map[string]*list.Element
+	order   list.List
+}
+
+// observe records that queryID answered the request with the given
+// fingerprint, and reports the fingerprint of the other request it answered
+// before, if any.
+func (t *queryIDTracker) observe(queryID, fingerprint string) (previous string, replayed bool) {
+	if queryID == "" {
+		return "", false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if e, ok := t.queries[queryID]; ok {
+		t.order.MoveToFront(e)
+		query := e.Value.(*trackedQuery)
+		// A retry of the same request may legitimately get the same answer
+		return query.fingerprint, query.fingerprint != fingerprint
+	}
+
+	if t.queries == nil {
+		t.queries = make(map[string]*list.Element)
+	}
+	t.queries[queryID] = t.order.PushFront(&trackedQuery{queryID: queryID, fingerprint: fingerprint})
+	for t.order.Len() > queryIDHistory {
+		oldest := t.order.Back()
+		t.order.Remove(oldest)
+		delete(t.queries, oldest.Value.(*trackedQuery).queryID)
+	}
+	return "", false
+}
+
+// requestFingerprint identifies a request by its method, path and parameters,
+// without the credentials.
+func requestFingerprint(method, path string, reqBody interface{}) (string, error) {
+	params := url.Values{}
+	if reqBody != nil {
+		if err := encoder.Encode(reqBody, params); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%s %s?%s", method, path, params.Encode()), nil
+}
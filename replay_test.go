@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestQueryIDTracker(t *testing.T) {
+	var tracker queryIDTracker
+	if _, replayed := tracker.observe("1", "POST /service/dnsdelete?entityID=1"); replayed {
+		t.Error("first response reported as replayed")
+	}
+	if _, replayed := tracker.observe("1", "POST /service/dnsdelete?entityID=1"); replayed {
+		t.Error("response to a retry of the same request reported as replayed")
+	}
+	previous, replayed := tracker.observe("1", "POST /service/dnsdelete?entityID=2")
+	if !replayed || previous != "POST /service/dnsdelete?entityID=1" {
+		t.Errorf("observe() = %q, %v, want the first request replayed", previous, replayed)
+	}
+	if _, replayed := tracker.observe("", "POST /service/dnsdelete?entityID=3"); replayed {
+		t.Error("response without a query ID reported as replayed")
+	}
+
+	for i := 0; i < queryIDHistory; i++ {
+		tracker.observe(fmt.Sprint("other-", i), "POST /service/dnscreate")
+	}
+	if _, replayed := tracker.observe("1", "POST /service/dnsdelete?entityID=2"); replayed {
+		t.Error("query ID not forgotten after queryIDHistory other responses")
+	}
+}
+
+func TestReplayedResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Dd-QueryID", "replayed-"+t.Name())
+		w.Write([]byte(`{"success": true}`))
+	}))
+	defer server.Close()
+
+	ddClient, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ddClient.RetryPolicy = RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}
+
+	ctx := context.Background()
+	if err := deleteRecord(ctx, ddClient, "example.com", "1"); err != nil {
+		t.Fatalf("deleteRecord() = %v", err)
+	}
+	if err := deleteRecord(ctx, ddClient, "example.com", "2"); !errors.Is(err, ErrReplayedResponse) {
+		t.Errorf("deleteRecord() = %v, want a replayed response", err)
+	}
+	if !ddClient.replayed.Load() {
+		t.Error("client not invalidated")
+	}
+
+	var cache clientCache
+	cache.get(server.URL, "apiuser", "apipasswd", "", DefaultRetryPolicy, nil, func() (*Client, error) { return ddClient, nil })
+	cached, _ := cache.get(server.URL, "apiuser", "apipasswd", "", DefaultRetryPolicy, nil, func() (*Client, error) { return &Client{AppKey: "new"}, nil })
+	if cached == ddClient {
+		t.Error("client cache returned the client that got a replayed response")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// resellerSolver is the solver of the zones of the customers of a
+// DonDominio reseller. It is the DonDominio solver, under another name, for
+// the configs setting the reseller field, so that a single webhook serves
+// both the direct and the reseller-managed zones.
+type resellerSolver struct {
+	*ddDNSProviderSolver
+}
+
+func (r resellerSolver) Name() string {
+	return resellerSolverName
+}
+
+func (r resellerSolver) Present(ch *v1alpha1.ChallengeRequest) error {
+	if err := requireReseller(ch); err != nil {
+		return err
+	}
+	return r.ddDNSProviderSolver.Present(ch)
+}
+
+func (r resellerSolver) CleanUp(ch *v1alpha1.ChallengeRequest) error {
+	if err := requireReseller(ch); err != nil {
+		return err
+	}
+	return r.ddDNSProviderSolver.CleanUp(ch)
+}
+
+// requireReseller fails if the config of the challenge does not set the
+// reseller field. The other problems of the config are left to the
+// DonDominio solver.
+func requireReseller(ch *v1alpha1.ChallengeRequest) error {
+	cfg, _, err := loadConfig(ch.Config)
+	if err != nil || cfg.Reseller != nil {
+		return nil
+	}
+	return configError(field.ErrorList{field.Required(field.NewPath("reseller"), "required by the "+resellerSolverName+" solver")})
+}
+
+// resellerSubUserParam is the API parameter naming the customer sub-account
+// impersonated by a reseller.
+const resellerSubUserParam = "subuser"
+
+// resellerConfig sets the impersonation parameters with which a DonDominio
+// reseller manages the zones of its customers, sent with every API call.
+type resellerConfig struct {
+	// SubUser is the customer sub-account impersonated
+	SubUser string `json:"subUser"`
+	// Params are additional parameters of the impersonation, as agreed with
+	// DonDominio for the reseller account
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// params returns the parameters added to the API calls, nil if r is nil.
+func (r *resellerConfig) params() url.Values {
+	if r == nil {
+		return nil
+	}
+	params := url.Values{}
+	for name, value := range r.Params {
+		params.Set(name, value)
+	}
+	params.Set(resellerSubUserParam, r.SubUser)
+	return params
+}
+
+// validate returns the problems of the reseller config.
+func (r *resellerConfig) validate(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if r.SubUser == "" {
+		allErrs = append(allErrs, field.Required(path.Child("subUser"), ""))
+	}
+	names := make([]string, 0, len(r.Params))
+	for name := range r.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch name {
+		case "", "apiuser", "apipasswd", resellerSubUserParam:
+			allErrs = append(allErrs, field.Invalid(path.Child("params").Key(name), r.Params[name], "must be a parameter name other than apiuser, apipasswd and "+resellerSubUserParam))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestResellerConfigValidate(t *testing.T) {
+	valid := &resellerConfig{SubUser: "customer", Params: map[string]string{"resellerID": "42"}}
+	if errs := valid.validate(field.NewPath("reseller")); len(errs) != 0 {
+		t.Errorf("validate() = %v", errs)
+	}
+	invalid := &resellerConfig{Params: map[string]string{"apipasswd": "stolen", "subuser": "other"}}
+	errs := invalid.validate(field.NewPath("reseller"))
+	if len(errs) != 3 || errs[0].Field != "reseller.subUser" || errs[1].Field != "reseller.params[apipasswd]" || errs[2].Field != "reseller.params[subuser]" {
+		t.Errorf("validate() = %v", errs)
+	}
+}
+
+func TestResellerSolver(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, nil)
+	var mu sync.Mutex
+	var subUsers []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		form, _ := url.ParseQuery(string(body))
+		mu.Lock()
+		subUsers = append(subUsers, form.Get("subuser")+"/"+form.Get("apiuser"))
+		mu.Unlock()
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+		api.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	solver := resellerSolver{testSolver()}
+	if solver.Name() != resellerSolverName {
+		t.Errorf("Name() = %q", solver.Name())
+	}
+	direct := testChallenge(t, server.URL, "_acme-challenge.example.com.", "example.com.", "direct-key", nil)
+	if err := solver.Present(direct); err == nil || !strings.Contains(err.Error(), "reseller: Required value") {
+		t.Errorf("Present() without reseller config = %v", err)
+	}
+
+	ch := testChallenge(t, server.URL, "_acme-challenge.example.com.", "example.com.", "reseller-key", map[string]interface{}{
+		"reseller": map[string]interface{}{"subUser": "customer", "params": map[string]string{"apiuser": "stolen"}},
+	})
+	if err := solver.Present(ch); err == nil || !strings.Contains(err.Error(), "reseller.params[apiuser]") {
+		t.Errorf("Present() with credential params = %v", err)
+	}
+
+	ch = testChallenge(t, server.URL, "_acme-challenge.example.com.", "example.com.", "reseller-key", map[string]interface{}{
+		"reseller": map[string]interface{}{"subUser": "customer"},
+	})
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("Present() error: %v", err)
+	}
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("CleanUp() error: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(subUsers) == 0 {
+		t.Fatal("no API call")
+	}
+	for _, subUser := range subUsers {
+		if subUser != "customer/apiuser" {
+			t.Errorf("API call with subuser/apiuser %q, want customer/apiuser", subUser)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// RetryPolicy configures how the client retries requests that failed with a
+// transient error. Delays grow exponentially from BaseDelay up to MaxDelay,
+// and are reduced by a random fraction of up to Jitter to spread retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values lower than 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts.
+	MaxDelay time.Duration
+	// Jitter is the maximum fraction of the delay, between 0 and 1, that is
+	// randomly removed from it.
+	Jitter float64
+}
+
+// DefaultRetryPolicy is the retry policy of the clients created by NewClient.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+	Jitter:      0.2,
+}
+
+// nonIdempotentPaths lists the API calls that may not be retried blindly,
+// because a failed attempt may still have been applied.
+var nonIdempotentPaths = map[string]bool{
+	"/service/dnscreate": true,
+}
+
+// delay returns the delay to wait before the given retry, starting at 1.
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < retry && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * math.Min(p.Jitter, 1) * float64(d))
+	}
+	return d
+}
+
+// clamp caps a delay requested by the API, e.g. by a Retry-After header, to
+// MaxDelay and to the time left before the deadline of ctx.
+func (p RetryPolicy) clamp(ctx context.Context, d time.Duration) time.Duration {
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if left := time.Until(deadline); d > left {
+			d = left
+		}
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Retry calls fn until it succeeds, fails with an error that is not
+// retryable, the retry policy gives up or ctx is done. attempt starts at 0.
+//
+// Retry is meant for operations that are not idempotent, where fn must check
+// whether a previous attempt was applied before trying again.
+func (c *Client) Retry(ctx context.Context, fn func(attempt int) error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn(attempt)
+		if err == nil || !isRetryable(err) || attempt+1 >= c.RetryPolicy.MaxAttempts {
+			return err
+		}
+
+		delay := c.RetryPolicy.delay(attempt + 1)
+		if errors.Is(err, ErrRateLimited) {
+			// Honor the delay requested by the API, if any
+			var apiError *APIError
+			if errors.As(err, &apiError) && apiError.RetryAfter > 0 {
+				delay = c.RetryPolicy.clamp(ctx, apiError.RetryAfter)
+			}
+			rateLimitedRequests.Inc()
+			rateLimitWaitSeconds.Add(delay.Seconds())
+			klog.FromContext(ctx).Info("DonDominio API rate limit reached, retrying", "delay", delay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// parseRetryAfter parses the value of a Retry-After header, either a number
+// of seconds or an HTTP date, and returns the delay it requests from now.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// isRetryable reports whether err is a transient failure: a server error, a
+// rate limit, a replayed response or a network timeout.
+func isRetryable(err error) bool {
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrReplayedResponse) {
+		return true
+	}
+
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		return apiError.Code >= 500
+	}
+
+	var netError net.Error
+	return errors.As(err, &netError) && netError.Timeout()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for retry, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		if got := p.delay(retry); got != want {
+			t.Errorf("delay(%d) = %v, want %v", retry, got, want)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.delay(2); got <= time.Second || got > 2*time.Second {
+			t.Fatalf("delay(2) with jitter = %v, want in (1s, 2s]", got)
+		}
+	}
+}
+
+func TestRetryPolicyClamp(t *testing.T) {
+	p := RetryPolicy{MaxDelay: 30 * time.Second}
+	if got := p.clamp(context.Background(), time.Hour); got != 30*time.Second {
+		t.Errorf("clamp(1h) = %v, want MaxDelay", got)
+	}
+	if got := p.clamp(context.Background(), time.Second); got != time.Second {
+		t.Errorf("clamp(1s) = %v, want 1s", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if got := p.clamp(ctx, time.Hour); got <= 0 || got > 5*time.Second {
+		t.Errorf("clamp(1h) with a 5s deadline = %v, want at most 5s", got)
+	}
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if got := p.clamp(expired, time.Hour); got != 0 {
+		t.Errorf("clamp(1h) past the deadline = %v, want 0", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2022, 10, 10, 10, 10, 10, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"-1":                            0,
+		"Mon, 10 Oct 2022 10:10:40 GMT": 30 * time.Second,
+		"Mon, 10 Oct 2022 10:00:00 GMT": 0,
+		"soon":                          0,
+	}
+	for value, want := range tests {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	c := &Client{RetryPolicy: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}}
+	tests := []struct {
+		name     string
+		err      error
+		attempts int
+	}{
+		{"success", nil, 1},
+		{"server error", &APIError{Code: http.StatusBadGateway}, 3},
+		{"rate limited", &APIError{Code: http.StatusOK, ErrorCode: ddErrTooManyRequests}, 3},
+		{"authentication failure", &APIError{Code: http.StatusOK, ErrorCode: ddErrLoginInvalid}, 1},
+		{"other error", errors.New("boom"), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := c.Retry(context.Background(), func(int) error {
+				attempts++
+				return tt.err
+			})
+			if err != tt.err {
+				t.Errorf("Retry() = %v, want %v", err, tt.err)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("Retry() made %d attempts, want %d", attempts, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestClientRetryAfter(t *testing.T) {
+	c := &Client{RetryPolicy: RetryPolicy{MaxAttempts: 2, BaseDelay: time.Hour}}
+	start := time.Now()
+	attempts := 0
+	err := c.Retry(context.Background(), func(int) error {
+		attempts++
+		if attempts == 1 {
+			return &APIError{Code: http.StatusTooManyRequests, RetryAfter: time.Millisecond}
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("Retry() = %v after %d attempts, want success after 2 attempts", err, attempts)
+	}
+	if elapsed := time.Since(start); elapsed > time.Minute {
+		t.Errorf("Retry() waited %v, want the Retry-After delay", elapsed)
+	}
+}
+
+func TestClientRetryAfterClamped(t *testing.T) {
+	c := &Client{RetryPolicy: RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}}
+	start := time.Now()
+	attempts := 0
+	err := c.Retry(context.Background(), func(int) error {
+		attempts++
+		if attempts == 1 {
+			return &APIError{Code: http.StatusTooManyRequests, RetryAfter: time.Hour}
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("Retry() = %v after %d attempts, want success after 2 attempts", err, attempts)
+	}
+	if elapsed := time.Since(start); elapsed > time.Minute {
+		t.Errorf("Retry() waited %v, want the Retry-After delay capped by MaxDelay", elapsed)
+	}
+}
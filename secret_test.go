@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSecretKeys(t *testing.T) {
+	secret := func(name string, data map[string]string) *corev1.Secret {
+		s := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Data:       map[string][]byte{},
+		}
+		for k, v := range data {
+			s.Data[k] = []byte(v)
+		}
+		return s
+	}
+	solver := &ddDNSProviderSolver{client: fake.NewSimpleClientset(
+		secret("explicit", map[string]string{"applicationSecret": "explicit-secret\n", "password": "other"}),
+		secret("conventional", map[string]string{"password": "password-secret", "secret": "other"}),
+		secret("precedence", map[string]string{"secret": "other", "api-password": "api-password-secret"}),
+		secret("unknown", map[string]string{"token": "other"}),
+	)}
+
+	tests := []struct {
+		ref     corev1.SecretKeySelector
+		want    string
+		wantErr bool
+	}{
+		{ref: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "explicit"}, Key: "applicationSecret"}, want: "explicit-secret"},
+		{ref: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "explicit"}, Key: "missing"}, wantErr: true},
+		{ref: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "conventional"}}, want: "password-secret"},
+		{ref: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "precedence"}}, want: "api-password-secret"},
+		{ref: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "unknown"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := solver.secret(context.Background(), tt.ref, "default", defaultSecretKeys)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("secret(%s/%q) error = %v, wantErr %v", tt.ref.Name, tt.ref.Key, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("secret(%s/%q) = %q, want %q", tt.ref.Name, tt.ref.Key, got, tt.want)
+		}
+	}
+}
+
+func TestApplicationKeyRef(t *testing.T) {
+	solver := &ddDNSProviderSolver{client: fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "dd-credentials", Namespace: "default"},
+		Data:       map[string][]byte{"username": []byte("apiuser\n"), "password": []byte("apipasswd")},
+	})}
+	ref := corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "dd-credentials"}}
+	cfg := &ddDNSProviderConfig{
+		Endpoint:             "http://dondominio.invalid",
+		ApplicationKeyRef:    &ref,
+		ApplicationSecretRef: ref,
+	}
+	if err := solver.validate(cfg, false); err != nil {
+		t.Fatalf("validate() = %v", err)
+	}
+
+	ddClient, err := solver.ddClient(context.Background(), cfg, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ddClient.AppKey != "apiuser" || ddClient.AppSecret != "apipasswd" {
+		t.Errorf("credentials = %q/%q, want apiuser/apipasswd", ddClient.AppKey, ddClient.AppSecret)
+	}
+
+	cfg.ApplicationKeyRef = nil
+	if err := solver.validate(cfg, false); err == nil {
+		t.Error("validate() accepted a config without application key")
+	}
+}
+
+func TestCredentialsSecretRef(t *testing.T) {
+	solver := &ddDNSProviderSolver{client: fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "dd-credentials", Namespace: "default"},
+			Data: map[string][]byte{
+				"apiUser":     []byte("apiuser"),
+				"apiPassword": []byte("apipasswd\n"),
+				"endpoint":    []byte("http://secret.dondominio.invalid"),
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "dd-incomplete", Namespace: "default"},
+			Data:       map[string][]byte{"apiPassword": []byte("")},
+		},
+	)}
+	cfg := &ddDNSProviderConfig{
+		CredentialsSecretRef: &corev1.LocalObjectReference{Name: "dd-credentials"},
+	}
+	if err := solver.validate(cfg, false); err != nil {
+		t.Fatalf("validate() = %v", err)
+	}
+
+	ddClient, err := solver.ddClient(context.Background(), cfg, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ddClient.AppKey != "apiuser" || ddClient.AppSecret != "apipasswd" {
+		t.Errorf("credentials = %q/%q, want apiuser/apipasswd", ddClient.AppKey, ddClient.AppSecret)
+	}
+	if ddClient.endpoint != "http://secret.dondominio.invalid" {
+		t.Errorf("endpoint = %q, want the one of the secret", ddClient.endpoint)
+	}
+
+	cfg.CredentialsSecretRef.Name = "dd-incomplete"
+	_, err = solver.ddClient(context.Background(), cfg, "default")
+	if err == nil || !strings.Contains(err.Error(), `["apiUser" "apiPassword"]`) {
+		t.Errorf("ddClient() = %v, want the missing keys", err)
+	}
+
+	cfg.ApplicationKey = "apiuser"
+	if err := solver.validate(cfg, false); err == nil {
+		t.Error("validate() accepted credentialsSecretRef combined with applicationKey")
+	}
+}
+
+func TestSecretNamespaces(t *testing.T) {
+	solver := &ddDNSProviderSolver{
+		client: fake.NewSimpleClientset(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "dd-credentials", Namespace: "cert-manager"},
+				Data:       map[string][]byte{"password": []byte("apipasswd")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "dd-credentials", Namespace: "default"},
+				Data:       map[string][]byte{"password": []byte("apipasswd")},
+			},
+		),
+		secretNamespaces: secretNamespaceSet("cert-manager, kube-system"),
+	}
+	ref := corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "dd-credentials"}}
+
+	if _, err := solver.secret(context.Background(), ref, "cert-manager", defaultSecretKeys); err != nil {
+		t.Errorf("secret() in an allowed namespace = %v", err)
+	}
+	_, err := solver.secret(context.Background(), ref, "default", defaultSecretKeys)
+	if !errors.Is(err, errSecretNamespaceNotAllowed) {
+		t.Errorf("secret() in another namespace = %v, want %v", err, errSecretNamespaceNotAllowed)
+	}
+	if secretNamespaceSet(" ,") != nil {
+		t.Error("an empty list restricts the namespaces")
+	}
+}
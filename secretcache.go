@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// secretSyncTimeout bounds the wait for the initial list of a secret
+	// informer.
+	secretSyncTimeout = 5 * time.Second
+
+	// secretInformerRetry is the time after which an informer that failed to
+	// sync, e.g. because the secret cannot be watched, is tried again. The
+	// secret is read directly from the API server in the meantime.
+	secretInformerRetry = 10 * time.Minute
+)
+
+type secretInformer struct {
+	informer cache.SharedIndexInformer
+	stop     chan struct{}
+	stopOnce sync.Once
+	failedAt time.Time
+}
+
+func (si *secretInformer) close() {
+	si.stopOnce.Do(func() { close(si.stop) })
+}
+
+// secretCache serves the secrets referenced by the issuers from informers
+// watching each of them, so that the challenges don't read them from the API
+// server and the updates of the secrets are picked up as soon as they are
+// made.
+//
+// Every informer lists and watches a single secret with a metadata.name
+// field selector, which is allowed by a Role restricted to that secret.
+// Until start has been called, the secrets are read directly.
+type secretCache struct {
+	client kubernetes.Interface
+	stopCh <-chan struct{}
+
+	mu        sync.Mutex
+	informers map[types.NamespacedName]*secretInformer
+}
+
+// start enables the informers, which are stopped when stopCh is closed.
+func (c *secretCache) start(client kubernetes.Interface, stopCh <-chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.client = client
+	c.stopCh = stopCh
+}
+
+// get returns the secret namespace/name, from its informer if it can be
+// synced, from the API server otherwise.
+func (c *secretCache) get(ctx context.Context, client kubernetes.Interface, namespace, name string) (*corev1.Secret, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	if informer := c.informer(ctx, key); informer != nil {
+		obj, exists, err := informer.GetStore().GetByKey(key.String())
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, apierrors.NewNotFound(corev1.Resource("secrets"), name)
+		}
+		return obj.(*corev1.Secret), nil
+	}
+	return client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// informer returns the synced informer of the secret, starting it on first
+// use, or nil if the secret must be read directly.
+func (c *secretCache) informer(ctx context.Context, key types.NamespacedName) cache.SharedIndexInformer {
+	c.mu.Lock()
+	if c.stopCh == nil {
+		c.mu.Unlock()
+		return nil
+	}
+	si, ok := c.informers[key]
+	if ok && si.informer != nil {
+		c.mu.Unlock()
+		if si.informer.HasSynced() {
+			return si.informer
+		}
+		return c.waitForSync(ctx, key, si)
+	}
+	if ok && time.Since(si.failedAt) < secretInformerRetry {
+		c.mu.Unlock()
+		return nil
+	}
+
+	si = &secretInformer{
+		informer: coreinformers.NewFilteredSecretInformer(c.client, key.Namespace, 0, cache.Indexers{}, func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", key.Name).String()
+		}),
+		stop: make(chan struct{}),
+	}
+	if c.informers == nil {
+		c.informers = make(map[types.NamespacedName]*secretInformer)
+	}
+	c.informers[key] = si
+	stopCh := c.stopCh
+	c.mu.Unlock()
+
+	go si.informer.Run(si.stop)
+	go func() {
+		select {
+		case <-stopCh:
+			si.close()
+		case <-si.stop:
+		}
+	}()
+
+	return c.waitForSync(ctx, key, si)
+}
+
+// waitForSync waits for the initial list of the informer. An informer that
+// does not sync in time is stopped and the secret is read directly until
+// secretInformerRetry has elapsed.
+func (c *secretCache) waitForSync(ctx context.Context, key types.NamespacedName, si *secretInformer) cache.SharedIndexInformer {
+	syncCtx, cancel := context.WithTimeout(ctx, secretSyncTimeout)
+	defer cancel()
+	if cache.WaitForCacheSync(syncCtx.Done(), si.informer.HasSynced) {
+		return si.informer
+	}
+	if ctx.Err() != nil {
+		// The challenge was cancelled, the informer may still sync.
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.informers[key] == si && si.informer != nil {
+		klog.FromContext(ctx).Info("Failed to sync the secret informer, reading the secret directly", "secret", key)
+		si.close()
+		c.informers[key] = &secretInformer{failedAt: time.Now()}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSecretCache(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "dd", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("first")},
+	})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	var c secretCache
+	c.start(client, stopCh)
+
+	secret, err := c.get(ctx, client, "default", "dd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(secret.Data["password"]); got != "first" {
+		t.Fatalf("password = %q, want %q", got, "first")
+	}
+	if c.informers[types.NamespacedName{Namespace: "default", Name: "dd"}] == nil {
+		t.Fatal("secret not served by an informer")
+	}
+
+	secret = secret.DeepCopy()
+	secret.Data["password"] = []byte("second")
+	if _, err := client.CoreV1().Secrets("default").Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		secret, err := c.get(ctx, client, "default", "dd")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(secret.Data["password"]) == "second" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("update of the secret not picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := c.get(ctx, client, "default", "missing"); !apierrors.IsNotFound(err) {
+		t.Errorf("get of a missing secret error = %v, want not found", err)
+	}
+}
+
+func TestSecretCacheNotStarted(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "dd", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	})
+
+	var c secretCache
+	if _, err := c.get(context.Background(), client, "default", "dd"); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.informers) != 0 {
+		t.Errorf("informers started before start")
+	}
+}
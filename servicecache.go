@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// serviceCache remembers the zones found active by the service/getinfo call,
+// so that it is not repeated for every challenge of the same zone. Failures
+// are not cached: a zone fixed in the DonDominio panel is retried at once.
+// Its zero value is ready to use.
+type serviceCache struct {
+	mu        sync.Mutex
+	validated map[string]time.Time
+}
+
+// validate calls check unless zone was validated less than ttl ago. A zero
+// ttl disables the cache.
+func (c *serviceCache) validate(ctx context.Context, zone string, ttl time.Duration, check func(ctx context.Context, zone string) error) error {
+	now := time.Now()
+	if ttl > 0 {
+		c.mu.Lock()
+		validatedAt, ok := c.validated[zone]
+		c.mu.Unlock()
+		if ok && now.Sub(validatedAt) < ttl {
+			return nil
+		}
+	}
+
+	if err := check(ctx, zone); err != nil {
+		c.forget(zone)
+		return err
+	}
+	if ttl > 0 {
+		c.mu.Lock()
+		if c.validated == nil {
+			c.validated = make(map[string]time.Time)
+		}
+		c.validated[zone] = now
+		c.mu.Unlock()
+	}
+	return nil
+}
+
+// forget drops the cached validation of zone.
+func (c *serviceCache) forget(zone string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.validated, zone)
+}
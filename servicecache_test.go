@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestServiceCache(t *testing.T) {
+	var cache serviceCache
+	checks := 0
+	var result error
+	check := func(ctx context.Context, zone string) error {
+		checks++
+		return result
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := cache.validate(ctx, "example.com", time.Hour, check); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if checks != 1 {
+		t.Errorf("zone checked %d times, want 1", checks)
+	}
+
+	cache.forget("example.com")
+	result = ErrServiceNotActive
+	for i := 0; i < 2; i++ {
+		if err := cache.validate(ctx, "example.com", time.Hour, check); err != ErrServiceNotActive {
+			t.Errorf("validate() = %v, want %v", err, ErrServiceNotActive)
+		}
+	}
+	if checks != 3 {
+		t.Errorf("zone checked %d times, want failures not to be cached", checks)
+	}
+
+	result = nil
+	cache.validate(ctx, "example.org", 0, check)
+	cache.validate(ctx, "example.org", 0, check)
+	if checks != 5 {
+		t.Errorf("zone checked %d times, want every time with a zero TTL", checks)
+	}
+}
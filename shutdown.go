@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// shutdownReportKey is the ConfigMap key holding the shutdown report.
+const shutdownReportKey = "report.json"
+
+// errShuttingDown is returned by the Present and CleanUp calls received once
+// the webhook is stopping, so that cert-manager retries them, possibly on
+// another replica.
+var errShuttingDown = errors.New("the webhook is shutting down, the challenge will be retried")
+
+// inFlightCalls counts the Present and CleanUp calls in progress, and stops
+// admitting new ones once draining.
+type inFlightCalls struct {
+	mu       sync.Mutex
+	draining bool
+	count    int
+	// idle is closed when the last call completes while draining
+	idle chan struct{}
+}
+
+// begin admits a call, returning false if the webhook is draining. end must
+// be called when an admitted call completes.
+func (c *inFlightCalls) begin() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.draining {
+		return false
+	}
+	c.count++
+	return true
+}
+
+// end marks the completion of a call admitted by begin.
+func (c *inFlightCalls) end() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count--
+	if c.count == 0 && c.idle != nil {
+		close(c.idle)
+		c.idle = nil
+	}
+}
+
+// drain stops admitting calls and waits for those in progress to complete,
+// for at most timeout. It returns the number of calls still in progress.
+func (c *inFlightCalls) drain(timeout time.Duration) int {
+	c.mu.Lock()
+	c.draining = true
+	if c.count == 0 {
+		c.mu.Unlock()
+		return 0
+	}
+	idle := make(chan struct{})
+	c.idle = idle
+	c.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-idle:
+	case <-timer.C:
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count
+}
+
+// shutdownCancelTimeout bounds the wait for the cancelled challenges to
+// return once the grace period has elapsed.
+const shutdownCancelTimeout = 5 * time.Second
+
+// shutdown stops the webhook when stopCh is closed: it stops admitting
+// challenges, waits for at most gracePeriod for those in progress, cancels
+// the DonDominio calls of the remaining ones with cancel and waits for them
+// to return, then reports what was left behind and flushes the logs.
+func (s *ddDNSProviderSolver) shutdown(gracePeriod time.Duration, cancel context.CancelFunc) {
+	klog.InfoS("Draining the challenges in progress", "gracePeriod", gracePeriod)
+	remaining := s.inFlight.drain(gracePeriod)
+	cancel()
+	if remaining > 0 {
+		klog.InfoS("Grace period elapsed, cancelled the challenges in progress", "inFlight", remaining)
+		// The cancelled calls return as soon as their DonDominio requests
+		// are aborted.
+		s.inFlight.drain(shutdownCancelTimeout)
+	}
+
+	s.reportShutdown(*shutdownReportConfigMap)
+	if *issuanceStatsConfigMap != "" {
+		s.persistIssuanceStats(*issuanceStatsConfigMap)
+	}
+	auditLog.close()
+	klog.Flush()
+}
+
+// shutdownReport summarizes what was left behind when the webhook stopped,
+// so that operators know what to reconcile after an unclean restart.
+type shutdownReport struct {
+	StoppedAt time.Time `json:"stoppedAt"`
+	ledgerSnapshot
+}
+
+// reportShutdown logs the shutdown report and, if configMap is not empty,
+// writes it to the "namespace/name" ConfigMap. Writing the ConfigMap is best
+// effort: the process may exit before it completes.
+func (s *ddDNSProviderSolver) reportShutdown(configMap string) {
+	report := shutdownReport{
+		StoppedAt:      time.Now(),
+		ledgerSnapshot: s.ledger.snapshot(),
+	}
+
+	klog.InfoS("Shutdown report", "inFlight", len(report.Operations),
+		"pendingDeletions", len(report.PendingDeletions), "notCleanedUp", len(report.Records))
+	for _, op := range report.Operations {
+		klog.InfoS("Operation in flight", "action", op.Action, "fqdn", op.FQDN, "startedAt", op.StartedAt)
+	}
+	for _, deletion := range report.PendingDeletions {
+		klog.InfoS("Pending deletion", "fqdn", deletion.FQDN, "failedAt", deletion.FailedAt, "error", deletion.Error)
+	}
+	for _, entry := range report.Records {
+		klog.InfoS("Record not cleaned up", "fqdn", entry.FQDN, "createdAt", entry.CreatedAt)
+	}
+
+	if configMap == "" {
+		return
+	}
+	if err := s.writeShutdownReport(configMap, &report); err != nil {
+		klog.ErrorS(err, "Failed to write the shutdown report", "configMap", configMap)
+	}
+}
+
+func (s *ddDNSProviderSolver) writeShutdownReport(configMap string, report *shutdownReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.writeConfigMapKey(ctx, configMap, shutdownReportKey, data)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func TestInFlightCallsDrain(t *testing.T) {
+	var calls inFlightCalls
+	if !calls.begin() {
+		t.Fatal("begin() = false before draining")
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		calls.end()
+	}()
+	if remaining := calls.drain(time.Second); remaining != 0 {
+		t.Errorf("drain() = %d, want 0", remaining)
+	}
+	if calls.begin() {
+		t.Error("begin() = true while draining")
+	}
+}
+
+func TestInFlightCallsDrainTimeout(t *testing.T) {
+	var calls inFlightCalls
+	calls.begin()
+	if remaining := calls.drain(10 * time.Millisecond); remaining != 1 {
+		t.Errorf("drain() = %d, want 1", remaining)
+	}
+	calls.end()
+	if remaining := calls.drain(0); remaining != 0 {
+		t.Errorf("drain() = %d after end, want 0", remaining)
+	}
+}
+
+func TestShutdownRejectsChallenges(t *testing.T) {
+	s := newSolver()
+	ctx, cancel := context.WithCancel(context.Background())
+	s.ctx = ctx
+	s.shutdown(time.Second, cancel)
+
+	if ctx.Err() == nil {
+		t.Error("shutdown did not cancel the context")
+	}
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com."}
+	if err := s.Present(ch); !errors.Is(err, errShuttingDown) {
+		t.Errorf("Present() = %v, want %v", err, errShuttingDown)
+	}
+	if err := s.CleanUp(ch); !errors.Is(err, errShuttingDown) {
+		t.Errorf("CleanUp() = %v, want %v", err, errShuttingDown)
+	}
+}
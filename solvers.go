@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook"
+
+	"github.com/baarde/cert-manager-webhook-dd/registry"
+)
+
+// Names of the DonDominio solvers.
+const (
+	solverName = "don-dominio"
+	// resellerSolverName is the solver of the zones of the customers of a
+	// DonDominio reseller, whose configs must set the reseller field.
+	resellerSolverName = "don-dominio-reseller"
+)
+
+var (
+	sharedSolverOnce sync.Once
+	sharedSolverInst *ddDNSProviderSolver
+)
+
+// sharedSolver returns the solver behind the DonDominio solvers, so that
+// they are initialized once and share their clients, ledger and zone locks.
+func sharedSolver() *ddDNSProviderSolver {
+	sharedSolverOnce.Do(func() {
+		sharedSolverInst = newSolver()
+	})
+	return sharedSolverInst
+}
+
+func init() {
+	registry.RegisterSolver(solverName, func() webhook.Solver {
+		return sharedSolver()
+	})
+	registry.RegisterSolver(resellerSolverName, func() webhook.Solver {
+		return resellerSolver{sharedSolver()}
+	})
+}
+
+// enabledSolvers returns the names listed by the --solvers flag of args, or
+// else by its environment variable. The solvers must be known before the
+// command of the webhook server parses the command line, so the flag is
+// looked up directly.
+func enabledSolvers(args []string) []string {
+	return solverList(preparsedFlag(args, "solvers"))
+}
+
+// solverList splits a comma separated list of solver names.
+func solverList(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnabledSolvers(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, nil},
+		{[]string{"--secure-port=8443"}, nil},
+		{[]string{"--solvers=don-dominio"}, []string{"don-dominio"}},
+		{[]string{"-solvers", "don-dominio, example", "-v", "4"}, []string{"don-dominio", "example"}},
+		{[]string{"--", "--solvers=example"}, nil},
+	}
+	for _, tt := range tests {
+		if got := enabledSolvers(tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("enabledSolvers(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
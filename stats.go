@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// issuanceStatsDays is the number of days, including the current one,
+	// for which the issuance statistics are kept.
+	issuanceStatsDays = 7
+	// issuanceStatsKey is the ConfigMap key holding the issuance statistics.
+	issuanceStatsKey = "issuance.json"
+	// issuanceStatsPersistInterval is the interval at which the issuance
+	// statistics are written to their ConfigMap.
+	issuanceStatsPersistInterval = 5 * time.Minute
+	// issuanceDayLayout is the layout of the days, in UTC, the FQDNs are
+	// counted by.
+	issuanceDayLayout = "2006-01-02"
+)
+
+// issuanceDays holds the unique FQDNs presented by day and by zone. It is
+// also the format in which the statistics are persisted.
+type issuanceDays map[string]map[string][]string
+
+// issuanceStats counts the unique FQDNs presented per zone and per day, to
+// help anticipate the DonDominio API quota needed as the number of
+// certificates grows. Its zero value is ready to use.
+type issuanceStats struct {
+	mu   sync.Mutex
+	days map[string]map[string]map[string]bool
+}
+
+// presented records that a challenge record has been presented for fqdn in
+// zone.
+func (st *issuanceStats) presented(zone, fqdn string, now time.Time) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.prune(now)
+	if st.days == nil {
+		st.days = make(map[string]map[string]map[string]bool)
+	}
+	day := now.UTC().Format(issuanceDayLayout)
+	if st.days[day] == nil {
+		st.days[day] = make(map[string]map[string]bool)
+	}
+	if st.days[day][zone] == nil {
+		st.days[day][zone] = make(map[string]bool)
+	}
+	st.days[day][zone][fqdn] = true
+}
+
+// prune forgets the days older than issuanceStatsDays. It must be called
+// with st.mu held.
+func (st *issuanceStats) prune(now time.Time) {
+	oldest := now.UTC().AddDate(0, 0, 1-issuanceStatsDays).Format(issuanceDayLayout)
+	for day := range st.days {
+		if day < oldest {
+			delete(st.days, day)
+		}
+	}
+}
+
+// zoneIssuance is the number of unique FQDNs presented in a zone.
+type zoneIssuance struct {
+	Zone  string `json:"zone"`
+	Today int    `json:"today"`
+	Week  int    `json:"last7Days"`
+}
+
+// summary returns the number of unique FQDNs presented per zone on the
+// current day and over the last issuanceStatsDays days, sorted by zone.
+func (st *issuanceStats) summary(now time.Time) []zoneIssuance {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.prune(now)
+	today := now.UTC().Format(issuanceDayLayout)
+	week := make(map[string]map[string]bool)
+	for _, zones := range st.days {
+		for zone, fqdns := range zones {
+			if week[zone] == nil {
+				week[zone] = make(map[string]bool)
+			}
+			for fqdn := range fqdns {
+				week[zone][fqdn] = true
+			}
+		}
+	}
+
+	summary := make([]zoneIssuance, 0, len(week))
+	for zone, fqdns := range week {
+		summary = append(summary, zoneIssuance{
+			Zone:  zone,
+			Today: len(st.days[today][zone]),
+			Week:  len(fqdns),
+		})
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		return summary[i].Zone < summary[j].Zone
+	})
+	return summary
+}
+
+// export returns a copy of the statistics, with sorted FQDNs.
+func (st *issuanceStats) export() issuanceDays {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	days := make(issuanceDays, len(st.days))
+	for day, zones := range st.days {
+		days[day] = make(map[string][]string, len(zones))
+		for zone, fqdns := range zones {
+			list := make([]string, 0, len(fqdns))
+			for fqdn := range fqdns {
+				list = append(list, fqdn)
+			}
+			sort.Strings(list)
+			days[day][zone] = list
+		}
+	}
+	return days
+}
+
+// load merges previously exported statistics into st.
+func (st *issuanceStats) load(days issuanceDays, now time.Time) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.days == nil {
+		st.days = make(map[string]map[string]map[string]bool)
+	}
+	for day, zones := range days {
+		if st.days[day] == nil {
+			st.days[day] = make(map[string]map[string]bool)
+		}
+		for zone, fqdns := range zones {
+			if st.days[day][zone] == nil {
+				st.days[day][zone] = make(map[string]bool)
+			}
+			for _, fqdn := range fqdns {
+				st.days[day][zone][fqdn] = true
+			}
+		}
+	}
+	st.prune(now)
+}
+
+var issuanceUniqueFQDNsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(metricsNamespace, "", "issuance_unique_fqdns"),
+	"Number of unique FQDNs for which a challenge record was presented, by zone, over the current UTC day (1d) or the last 7 days (7d).",
+	[]string{"zone", "window"}, nil,
+)
+
+// issuanceCollector exports the issuance statistics as Prometheus metrics.
+type issuanceCollector struct {
+	stats *issuanceStats
+}
+
+func (c issuanceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- issuanceUniqueFQDNsDesc
+}
+
+func (c issuanceCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, zone := range c.stats.summary(time.Now()) {
+		ch <- prometheus.MustNewConstMetric(issuanceUniqueFQDNsDesc, prometheus.GaugeValue, float64(zone.Today), zone.Zone, "1d")
+		ch <- prometheus.MustNewConstMetric(issuanceUniqueFQDNsDesc, prometheus.GaugeValue, float64(zone.Week), zone.Zone, "7d")
+	}
+}
+
+// debugState is the content of /debug/state.
+type debugState struct {
+	ledgerSnapshot
+	Issuance struct {
+		Zones []zoneIssuance `json:"zones"`
+		Days  issuanceDays   `json:"days"`
+	} `json:"issuance"`
+}
+
+// serveState serves the content of the ledger and the issuance statistics
+// as JSON.
+func (s *ddDNSProviderSolver) serveState(w http.ResponseWriter, r *http.Request) {
+	state := debugState{ledgerSnapshot: s.ledger.snapshot()}
+	state.Issuance.Zones = s.stats.summary(time.Now())
+	state.Issuance.Days = s.stats.export()
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(state)
+}
+
+// loadIssuanceStats loads the issuance statistics persisted in the
+// "namespace/name" ConfigMap, if any.
+func (s *ddDNSProviderSolver) loadIssuanceStats(configMap string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	data, ok, err := s.readConfigMapKey(ctx, configMap, issuanceStatsKey)
+	if err != nil || !ok {
+		return err
+	}
+	var days issuanceDays
+	if err := json.Unmarshal([]byte(data), &days); err != nil {
+		return err
+	}
+	s.stats.load(days, time.Now())
+	return nil
+}
+
+// persistIssuanceStats writes the issuance statistics to the
+// "namespace/name" ConfigMap.
+func (s *ddDNSProviderSolver) persistIssuanceStats(configMap string) {
+	data, err := json.Marshal(s.stats.export())
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = s.writeConfigMapKey(ctx, configMap, issuanceStatsKey, data)
+	}
+	if err != nil {
+		klog.ErrorS(err, "Failed to write the issuance statistics", "configMap", configMap)
+	}
+}
+
+// runIssuanceStatsPersister writes the issuance statistics to the
+// "namespace/name" ConfigMap every issuanceStatsPersistInterval until stopCh
+// is closed.
+func (s *ddDNSProviderSolver) runIssuanceStatsPersister(configMap string, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(issuanceStatsPersistInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.persistIssuanceStats(configMap)
+		case <-stopCh:
+			return
+		}
+	}
+}
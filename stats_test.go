@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestIssuanceStats(t *testing.T) {
+	day := func(n int) time.Time {
+		return time.Date(2022, 3, n, 12, 0, 0, 0, time.UTC)
+	}
+
+	var stats issuanceStats
+	stats.presented("example.com", "_acme-challenge.old.example.com.", day(1))
+	stats.presented("example.com", "_acme-challenge.www.example.com.", day(7))
+	stats.presented("example.com", "_acme-challenge.example.com.", day(10))
+	stats.presented("example.com", "_acme-challenge.www.example.com.", day(10))
+	stats.presented("example.com", "_acme-challenge.www.example.com.", day(10))
+	stats.presented("example.org", "_acme-challenge.example.org.", day(9))
+
+	want := []zoneIssuance{
+		{Zone: "example.com", Today: 2, Week: 2},
+		{Zone: "example.org", Today: 0, Week: 1},
+	}
+	if got := stats.summary(day(10)); !reflect.DeepEqual(got, want) {
+		t.Errorf("summary() = %+v, want %+v", got, want)
+	}
+
+	// Statistics survive a round trip through their persisted format
+	var loaded issuanceStats
+	loaded.load(stats.export(), day(10))
+	if got := loaded.summary(day(10)); !reflect.DeepEqual(got, want) {
+		t.Errorf("summary() after load = %+v, want %+v", got, want)
+	}
+
+	want = []zoneIssuance{{Zone: "example.com", Today: 0, Week: 2}}
+	if got := stats.summary(day(16)); !reflect.DeepEqual(got, want) {
+		t.Errorf("summary() six days later = %+v, want %+v", got, want)
+	}
+}
+
+func TestIssuanceCollector(t *testing.T) {
+	var stats issuanceStats
+	stats.presented("example.com", "_acme-challenge.example.com.", time.Now())
+
+	expected := `
+# HELP cert_manager_webhook_dd_issuance_unique_fqdns Number of unique FQDNs for which a challenge record was presented, by zone, over the current UTC day (1d) or the last 7 days (7d).
+# TYPE cert_manager_webhook_dd_issuance_unique_fqdns gauge
+cert_manager_webhook_dd_issuance_unique_fqdns{window="1d",zone="example.com"} 1
+cert_manager_webhook_dd_issuance_unique_fqdns{window="7d",zone="example.com"} 1
+`
+	if err := testutil.CollectAndCompare(issuanceCollector{stats: &stats}, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpgrpc"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/klog/v2"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+// tracer creates the spans of the webhook. Until setupTracing is called, the
+// global provider discards them.
+var tracer = otel.Tracer("github.com/baarde/cert-manager-webhook-dd")
+
+// setupTracing exports the spans to the OTLP gRPC endpoint until stopCh is
+// closed.
+func setupTracing(endpoint string, insecure bool, stopCh <-chan struct{}) error {
+	opts := []otlpgrpc.Option{otlpgrpc.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlpgrpc.WithInsecure())
+	}
+	exporter, err := otlp.NewExporter(context.Background(), otlpgrpc.NewDriver(opts...))
+	if err != nil {
+		return err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(sdkresource.NewWithAttributes(
+			semconv.ServiceNameKey.String("cert-manager-webhook-dd"),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	go func() {
+		<-stopCh
+		// Flush the spans of the challenges in flight
+		if err := provider.Shutdown(context.Background()); err != nil {
+			klog.ErrorS(err, "Failed to flush the traces")
+		}
+	}()
+	return nil
+}
+
+// startChallengeSpan starts the span of a Present or CleanUp call.
+func startChallengeSpan(ctx context.Context, name string, ch *v1alpha1.ChallengeRequest) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.String("acme.fqdn", ch.ResolvedFQDN),
+		attribute.String("acme.zone", ch.ResolvedZone),
+		attribute.String("k8s.namespace.name", ch.ResourceNamespace),
+	))
+}
+
+// endSpan records the outcome of the operation traced by span and ends it.
+// The DonDominio error code, if any, is added as an attribute.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		var apiError *APIError
+		if errors.As(err, &apiError) && apiError.ErrorCode != 0 {
+			span.SetAttributes(attribute.Int64("dondominio.error_code", apiError.ErrorCode))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans makes the spans of the test exported to the returned exporter.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := tracer
+	tracer = provider.Tracer("test")
+	t.Cleanup(func() { tracer = previous })
+	return exporter
+}
+
+// spanAttribute returns the value of the attribute key of span, if any.
+func spanAttribute(span *sdktrace.SpanSnapshot, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestChallengeSpans(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, nil)
+	server := httptest.NewServer(api)
+	defer server.Close()
+	exporter := recordSpans(t)
+
+	solver := testSolver()
+	ch := testChallenge(t, server.URL, "_acme-challenge.example.com.", "example.com.", "challenge-key", nil)
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("Present() error: %v", err)
+	}
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("CleanUp() error: %v", err)
+	}
+
+	spans := map[string]*sdktrace.SpanSnapshot{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	for _, name := range []string{"Present", "CleanUp"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("no %s span in %v", name, exporter.GetSpans())
+			continue
+		}
+		if span.StatusCode == codes.Error {
+			t.Errorf("%s span status = %v %q, want no error", name, span.StatusCode, span.StatusMessage)
+		}
+		for key, want := range map[attribute.Key]string{
+			"acme.fqdn":          "_acme-challenge.example.com.",
+			"acme.zone":          "example.com.",
+			"k8s.namespace.name": "default",
+		} {
+			if got, _ := spanAttribute(span, key); got.AsString() != want {
+				t.Errorf("%s span attribute %s = %q, want %q", name, key, got.AsString(), want)
+			}
+		}
+	}
+	create, ok := spans["DonDominio /service/dnscreate"]
+	if !ok {
+		t.Fatalf("no DonDominio /service/dnscreate span in %v", exporter.GetSpans())
+	}
+	if got, _ := spanAttribute(create, "dondominio.path"); got.AsString() != "/service/dnscreate" {
+		t.Errorf("dondominio.path = %q, want /service/dnscreate", got.AsString())
+	}
+	if present := spans["Present"]; present != nil && create.Parent.SpanID() != present.SpanContext.SpanID() {
+		t.Error("DonDominio /service/dnscreate span is not a child of the Present span")
+	}
+
+	// A failed call marks the spans as errors, with the DonDominio error code
+	exporter.Reset()
+	api.mu.Lock()
+	api.failures = map[string]int64{"dnscreate": ddErrInsufficientBalance}
+	api.mu.Unlock()
+	if err := solver.Present(testChallenge(t, server.URL, "_acme-challenge.example.com.", "example.com.", "other-key", nil)); err == nil {
+		t.Fatal("Present() succeeded despite the API error")
+	}
+	failed := 0
+	for _, span := range exporter.GetSpans() {
+		if span.Name != "Present" && span.Name != "DonDominio /service/dnscreate" {
+			continue
+		}
+		failed++
+		if span.StatusCode != codes.Error {
+			t.Errorf("%s span status = %v, want %v", span.Name, span.StatusCode, codes.Error)
+		}
+		if got, _ := spanAttribute(span, "dondominio.error_code"); got.AsInt64() != ddErrInsufficientBalance {
+			t.Errorf("%s span dondominio.error_code = %d, want %d", span.Name, got.AsInt64(), ddErrInsufficientBalance)
+		}
+	}
+	if failed != 2 {
+		t.Errorf("%d failed Present and dnscreate spans, want 2: %v", failed, exporter.GetSpans())
+	}
+}
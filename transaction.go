@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// errValueMangled is returned when the provider stored a challenge record
+// with another value than the one requested.
+var errValueMangled = errors.New("record value mangled by the provider")
+
+// recordTransaction creates a challenge record, or updates a stale one in
+// place, and checks that the provider stored its value unchanged. A mangled record is rolled back, i.e. deleted,
+// and created again once before giving up, so that no corrupted record is
+// left in the zone.
+type recordTransaction struct {
+	provider DNSProvider
+	zone     string
+	name     string
+	value    string
+	ttl      time.Duration
+	// replaceID, if set, is the ID of the stale record updated with the
+	// value instead of creating a new one
+	replaceID string
+}
+
+// commit creates or updates the record, verifies it and returns it. A
+// mangled update is rolled back and the record created again.
+func (tx *recordTransaction) commit(ctx context.Context) (TXTRecord, error) {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var record TXTRecord
+		if attempt == 0 && tx.replaceID != "" {
+			record, err = tx.provider.UpdateTXT(ctx, tx.zone, tx.replaceID, tx.value, tx.ttl)
+		} else {
+			record, err = tx.provider.CreateTXT(ctx, tx.zone, tx.name, tx.value, tx.ttl)
+		}
+		if err != nil {
+			return TXTRecord{}, err
+		}
+		if err = tx.verify(record); err == nil {
+			return record, nil
+		}
+		klog.FromContext(ctx).Info("Challenge record mangled by the provider, deleting it", "record", tx.name, "entityID", record.ID, "value", record.Value)
+		if rollbackErr := tx.rollback(ctx, record); rollbackErr != nil {
+			return TXTRecord{}, fmt.Errorf("%w, and rolling it back failed: %v", err, rollbackErr)
+		}
+	}
+	return TXTRecord{}, err
+}
+
+// verify checks that the record created holds the value requested.
+func (tx *recordTransaction) verify(record TXTRecord) error {
+	if record.Value != tx.value {
+		return fmt.Errorf("TXT record %s created with value %q instead of %q: %w", tx.name, record.Value, tx.value, errValueMangled)
+	}
+	return nil
+}
+
+// rollback deletes the record created.
+func (tx *recordTransaction) rollback(ctx context.Context, record TXTRecord) error {
+	if record.ID == "" {
+		return errors.New("the provider did not report the ID of the record")
+	}
+	err := tx.provider.DeleteTXT(ctx, tx.zone, record.ID)
+	if errors.Is(err, ErrRecordNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRecordTransaction(t *testing.T) {
+	truncate := func(value string) string { return value[:len(value)-1] }
+	tests := []struct {
+		name    string
+		mangled int
+		wantErr bool
+		created int
+	}{
+		{name: "stored unchanged", created: 1},
+		{name: "mangled once", mangled: 1, created: 2},
+		{name: "always mangled", mangled: 2, wantErr: true, created: 2},
+	}
+	for _, tt := range tests {
+		provider := &fakeProvider{zones: map[string][]TXTRecord{"example.com": nil}}
+		mangled := 0
+		provider.mangle = func(value string) string {
+			if mangled < tt.mangled {
+				mangled++
+				return truncate(value)
+			}
+			return value
+		}
+		tx := recordTransaction{provider: provider, zone: "example.com", name: "_acme-challenge.example.com", value: "challenge-key"}
+
+		record, err := tx.commit(context.Background())
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: commit() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if tt.wantErr && !errors.Is(err, errValueMangled) {
+			t.Errorf("%s: commit() error = %v, want %v", tt.name, err, errValueMangled)
+		}
+		if provider.created != tt.created || provider.deleted != tt.mangled {
+			t.Errorf("%s: created %d and deleted %d records, want %d and %d", tt.name, provider.created, provider.deleted, tt.created, tt.mangled)
+		}
+
+		var want []TXTRecord
+		if !tt.wantErr {
+			want = []TXTRecord{record}
+			if record.Value != "challenge-key" {
+				t.Errorf("%s: commit() = %+v, want the challenge key", tt.name, record)
+			}
+		}
+		if got := provider.zones["example.com"]; len(got) != len(want) || (len(want) > 0 && !reflect.DeepEqual(got, want)) {
+			t.Errorf("%s: records = %+v, want %+v", tt.name, got, want)
+		}
+	}
+}
+
+func TestRecordTransactionUpdate(t *testing.T) {
+	stale := TXTRecord{ID: "stale", Name: "_acme-challenge.example.com", Value: "stale-key"}
+	provider := &fakeProvider{zones: map[string][]TXTRecord{"example.com": {stale}}}
+	tx := recordTransaction{provider: provider, zone: "example.com", name: stale.Name, value: "challenge-key", replaceID: stale.ID}
+
+	record, err := tx.commit(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TXTRecord{ID: "stale", Name: stale.Name, Value: "challenge-key"}
+	if record != want || provider.updated != 1 || provider.created != 0 {
+		t.Errorf("commit() = %+v after %d updates and %d creations, want %+v updated", record, provider.updated, provider.created, want)
+	}
+
+	// A mangled update is rolled back and the record created instead
+	provider = &fakeProvider{zones: map[string][]TXTRecord{"example.com": {stale}}}
+	provider.mangle = func(value string) string {
+		provider.mangle = nil
+		return value[:len(value)-1]
+	}
+	tx.provider = provider
+	record, err = tx.commit(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Value != "challenge-key" || provider.deleted != 1 || provider.created != 1 {
+		t.Errorf("commit() = %+v after %d deletions and %d creations, want a new record", record, provider.deleted, provider.created)
+	}
+}
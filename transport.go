@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// sharedTransport is used by all the clients, so that the connections to
+// the DonDominio API are kept alive and reused across challenges instead of
+// paying a TLS handshake for each of them.
+var sharedTransport = newTransport()
+
+// newTransport returns a transport using the proxies of the environment,
+// see proxyFunc.
+func newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc(os.Getenv)
+	return transport
+}
+
+// transportSettings tune the connections of sharedTransport.
+type transportSettings struct {
+	// MaxIdleConnsPerHost is the number of idle connections kept per host
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is the time after which an idle connection is closed
+	IdleConnTimeout time.Duration
+	// DialTimeout bounds the TCP connection establishment
+	DialTimeout time.Duration
+	// KeepAlive is the period of the TCP keep-alive probes, negative
+	// disables them
+	KeepAlive time.Duration
+	// TLSHandshakeTimeout bounds the TLS handshake
+	TLSHandshakeTimeout time.Duration
+	// HTTP2 enables HTTP/2 when the endpoint supports it
+	HTTP2 bool
+}
+
+// transportFlags returns the transport settings of the command line flags.
+func transportFlags() transportSettings {
+	return transportSettings{
+		MaxIdleConnsPerHost: *httpMaxIdleConnsPerHost,
+		IdleConnTimeout:     *httpIdleConnTimeout,
+		DialTimeout:         *httpDialTimeout,
+		KeepAlive:           *httpKeepAlive,
+		TLSHandshakeTimeout: *httpTLSHandshakeTimeout,
+		HTTP2:               *httpHTTP2,
+	}
+}
+
+// validate checks the transport settings.
+func (settings transportSettings) validate() error {
+	if settings.MaxIdleConnsPerHost < 0 || settings.IdleConnTimeout < 0 || settings.DialTimeout < 0 || settings.TLSHandshakeTimeout < 0 {
+		return errors.New("invalid --http-* flags, the connection counts and timeouts must not be negative")
+	}
+	return nil
+}
+
+// tune applies the settings to the transport. It must be called before the
+// transport is used, since it is shared by all the clients.
+func (settings transportSettings) tune(transport *http.Transport) {
+	transport.MaxIdleConnsPerHost = settings.MaxIdleConnsPerHost
+	// The idle connections of the other hosts, e.g. the proxy, are not
+	// limited more strictly
+	if transport.MaxIdleConns != 0 && transport.MaxIdleConns < settings.MaxIdleConnsPerHost {
+		transport.MaxIdleConns = settings.MaxIdleConnsPerHost
+	}
+	transport.IdleConnTimeout = settings.IdleConnTimeout
+	transport.TLSHandshakeTimeout = settings.TLSHandshakeTimeout
+	transport.DialContext = (&net.Dialer{
+		Timeout:   settings.DialTimeout,
+		KeepAlive: settings.KeepAlive,
+	}).DialContext
+	transport.ForceAttemptHTTP2 = settings.HTTP2
+	if !settings.HTTP2 {
+		// A non-nil empty map disables HTTP/2
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+}
+
+// proxyFunc returns the proxy of the standard HTTP_PROXY and HTTPS_PROXY
+// environment variables, or their lowercase versions, replaced by the PROXY
+// variable if it is not empty. The endpoints matching NO_PROXY, and the
+// loopback ones, are never proxied, whichever variable sets the proxy. The
+// proxies without credentials in their URL get the proxyCredentials.
+func proxyFunc(getenv func(string) string) func(*http.Request) (*url.URL, error) {
+	cfg := &httpproxy.Config{
+		HTTPProxy:  getenvAny(getenv, "HTTP_PROXY", "http_proxy"),
+		HTTPSProxy: getenvAny(getenv, "HTTPS_PROXY", "https_proxy"),
+		NoProxy:    getenvAny(getenv, "NO_PROXY", "no_proxy"),
+	}
+	if proxy := strings.TrimSpace(getenv("PROXY")); proxy != "" {
+		cfg.HTTPProxy, cfg.HTTPSProxy = proxy, proxy
+	}
+	proxy := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		proxyURL, err := proxy(req.URL)
+		return withProxyCredentials(proxyURL), err
+	}
+}
+
+// getenvAny returns the value of the first of names that is set.
+func getenvAny(getenv func(string) string, names ...string) string {
+	for _, name := range names {
+		if value := getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// connectionTrace counts whether the request to path reused a connection and
+// how long the TLS handshakes of the new connections took.
+func connectionTrace(path string) *httptrace.ClientTrace {
+	var handshakeStart time.Time
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			apiConnections.WithLabelValues(path, strconv.FormatBool(info.Reused)).Inc()
+		},
+		TLSHandshakeStart: func() {
+			handshakeStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil && !handshakeStart.IsZero() {
+				apiTLSHandshakeDuration.Observe(time.Since(handshakeStart).Seconds())
+			}
+		},
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestConnectionReuse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success": true, "errorCode": 0}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const path = "/test/connections"
+	// The counters are global, e.g. with -count=2
+	newBefore := testutil.ToFloat64(apiConnections.WithLabelValues(path, "false"))
+	reusedBefore := testutil.ToFloat64(apiConnections.WithLabelValues(path, "true"))
+	for i := 0; i < 3; i++ {
+		if err := client.PostWithContext(context.Background(), path, nil, &ddResponse{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := testutil.ToFloat64(apiConnections.WithLabelValues(path, "false")) - newBefore; got != 1 {
+		t.Errorf("new connections = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(apiConnections.WithLabelValues(path, "true")) - reusedBefore; got != 2 {
+		t.Errorf("reused connections = %v, want 2", got)
+	}
+}
+
+func TestProxyFunc(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{}, ""},
+		{map[string]string{"HTTPS_PROXY": "http://proxy:8080"}, "http://proxy:8080"},
+		{map[string]string{"https_proxy": "http://proxy:8080"}, "http://proxy:8080"},
+		{map[string]string{"HTTP_PROXY": "http://proxy:8080"}, ""},
+		{map[string]string{"HTTPS_PROXY": "http://proxy:8080", "NO_PROXY": "localhost,.dondominio.net"}, ""},
+		{map[string]string{"PROXY": "", "HTTPS_PROXY": "http://proxy:8080"}, "http://proxy:8080"},
+		{map[string]string{"PROXY": "http://override:3128", "HTTPS_PROXY": "http://proxy:8080"}, "http://override:3128"},
+		{map[string]string{"PROXY": "http://override:3128", "NO_PROXY": "dondominio.net"}, ""},
+	}
+	req, _ := http.NewRequest(http.MethodPost, "https://simple-api.dondominio.net/service/dnslist/", nil)
+	for _, test := range tests {
+		proxy, err := proxyFunc(func(name string) string { return test.env[name] })(req)
+		if err != nil {
+			t.Errorf("%v: proxy() = %v", test.env, err)
+			continue
+		}
+		got := ""
+		if proxy != nil {
+			got = proxy.String()
+		}
+		if got != test.want {
+			t.Errorf("%v: proxy() = %q, want %q", test.env, got, test.want)
+		}
+	}
+}
+
+func TestTransportSettings(t *testing.T) {
+	settings := transportSettings{
+		MaxIdleConnsPerHost: 200,
+		IdleConnTimeout:     time.Minute,
+		DialTimeout:         5 * time.Second,
+		KeepAlive:           15 * time.Second,
+		TLSHandshakeTimeout: 3 * time.Second,
+	}
+	if err := settings.validate(); err != nil {
+		t.Fatal(err)
+	}
+	transport := newTransport()
+	settings.tune(transport)
+	if transport.MaxIdleConnsPerHost != 200 || transport.MaxIdleConns < 200 {
+		t.Errorf("idle connections = %d per host, %d in total", transport.MaxIdleConnsPerHost, transport.MaxIdleConns)
+	}
+	if transport.IdleConnTimeout != time.Minute || transport.TLSHandshakeTimeout != 3*time.Second {
+		t.Errorf("timeouts = %s idle, %s TLS handshake", transport.IdleConnTimeout, transport.TLSHandshakeTimeout)
+	}
+	if transport.ForceAttemptHTTP2 || transport.TLSNextProto == nil || len(transport.TLSNextProto) != 0 {
+		t.Error("HTTP/2 not disabled")
+	}
+
+	settings.DialTimeout = -time.Second
+	if settings.validate() == nil {
+		t.Error("validate() accepted a negative dial timeout")
+	}
+}
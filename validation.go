@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// errInvalidConfig is matched by the errors returned by configError.
+var errInvalidConfig = errors.New("invalid DonDominio config")
+
+// invalidConfigError reports the problems of a config.
+type invalidConfigError struct {
+	errs utilerrors.Aggregate
+}
+
+func (e *invalidConfigError) Error() string {
+	return errInvalidConfig.Error() + ": " + e.errs.Error()
+}
+
+func (e *invalidConfigError) Unwrap() error {
+	return e.errs
+}
+
+func (e *invalidConfigError) Is(target error) bool {
+	return target == errInvalidConfig
+}
+
+// configError returns the error reporting all the problems of the config,
+// so that they are fixed at once from the status of the Challenge, or nil if
+// there are none.
+func configError(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return &invalidConfigError{errs: allErrs.ToAggregate()}
+}
+
+// validateConfig returns the problems of the config, with the path of the
+// field each one is about.
+func validateConfig(cfg *ddDNSProviderConfig, allowAmbientCredentials bool) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch cfg.ConflictPolicy {
+	case "", conflictPolicyAppend, conflictPolicyReplace, conflictPolicyUpdate, conflictPolicyFail:
+	default:
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("conflictPolicy"), cfg.ConflictPolicy,
+			[]string{conflictPolicyAppend, conflictPolicyReplace, conflictPolicyUpdate, conflictPolicyFail}))
+	}
+	switch cfg.AuthScheme {
+	case "", authSchemeForm, authSchemeHMAC:
+	default:
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("authScheme"), cfg.AuthScheme, authSchemes))
+	}
+	allErrs = append(allErrs, validateDuration(field.NewPath("requestTimeout"), cfg.RequestTimeout, 0)...)
+	allErrs = append(allErrs, validateDuration(field.NewPath("propagationInterval"), cfg.PropagationInterval, 0)...)
+	allErrs = append(allErrs, validateDuration(field.NewPath("propagationTimeout"), cfg.PropagationTimeout, 0)...)
+	allErrs = append(allErrs, validateDuration(field.NewPath("recordTTL"), cfg.RecordTTL, time.Second)...)
+	if cfg.RetryPolicy != nil {
+		path := field.NewPath("retryPolicy")
+		// Like the --retry-* flags, a single attempt disables the retries
+		if cfg.RetryPolicy.MaxAttempts != nil && *cfg.RetryPolicy.MaxAttempts < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("maxAttempts"), *cfg.RetryPolicy.MaxAttempts, "must not be negative"))
+		}
+		if d := cfg.RetryPolicy.BaseDelay; d != nil && d.Duration < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("baseDelay"), d.Duration.String(), "must not be negative"))
+		}
+		if d := cfg.RetryPolicy.MaxDelay; d != nil && d.Duration < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("maxDelay"), d.Duration.String(), "must not be negative"))
+		}
+	}
+	for i, resolver := range cfg.PropagationResolvers {
+		if strings.TrimSpace(resolver) == "" {
+			allErrs = append(allErrs, field.Required(field.NewPath("propagationResolvers").Index(i), "must be a host or host:port"))
+		}
+	}
+	if strings.Contains(cfg.Endpoint, "/") {
+		allErrs = append(allErrs, validateURL(field.NewPath("endpoint"), cfg.Endpoint)...)
+	}
+	if cfg.CredentialsBroker != nil {
+		path := field.NewPath("credentialsBroker", "url")
+		if cfg.CredentialsBroker.URL == "" {
+			allErrs = append(allErrs, field.Required(path, ""))
+		} else {
+			allErrs = append(allErrs, validateURL(path, cfg.CredentialsBroker.URL)...)
+		}
+	}
+	if cfg.Reseller != nil {
+		allErrs = append(allErrs, cfg.Reseller.validate(field.NewPath("reseller"))...)
+	}
+	if cfg.CAA != nil {
+		allErrs = append(allErrs, cfg.CAA.validate(field.NewPath("caa"))...)
+	}
+	if cfg.ExternalDNSRegistry != nil && cfg.ExternalDNSRegistry.OwnerID == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("externalDNSRegistry", "ownerID"), ""))
+	}
+	for i, pattern := range cfg.AllowedDomains {
+		if err := validateDomainPattern(pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("allowedDomains").Index(i), pattern, err.Error()))
+		}
+	}
+	for i, pattern := range cfg.DeniedDomains {
+		if err := validateDomainPattern(pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("deniedDomains").Index(i), pattern, err.Error()))
+		}
+	}
+	domains := make([]string, 0, len(cfg.DomainCredentials))
+	for domain := range cfg.DomainCredentials {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	for _, domain := range domains {
+		creds := cfg.DomainCredentials[domain]
+		allErrs = append(allErrs, creds.validate(field.NewPath("domainCredentials").Key(domain), domain)...)
+	}
+
+	return append(allErrs, validateCredentials(cfg, allowAmbientCredentials)...)
+}
+
+// validateCredentials checks that the config has complete credentials.
+func validateCredentials(cfg *ddDNSProviderConfig, allowAmbientCredentials bool) field.ErrorList {
+	var allErrs field.ErrorList
+	if cfg.CredentialsSecretRef != nil {
+		path := field.NewPath("credentialsSecretRef")
+		if cfg.CredentialsSecretRef.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Child("name"), ""))
+		}
+		if cfg.ApplicationKey != "" || cfg.ApplicationKeyRef != nil || cfg.ApplicationSecretRef.Name != "" || cfg.CredentialsBroker != nil {
+			allErrs = append(allErrs, field.Forbidden(path, "cannot be combined with applicationKey, applicationKeyRef, applicationSecretRef or credentialsBroker"))
+		}
+		// The endpoint may be in the secret
+		return allErrs
+	}
+	if allowAmbientCredentials {
+		// When allowAmbientCredentials is true, DD client can load missing config
+		// values from the environment variables and the dondominio.conf files.
+		return allErrs
+	}
+	if cfg.Endpoint == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("endpoint"), ""))
+	}
+	if cfg.CredentialsBroker != nil {
+		return allErrs
+	}
+	if cfg.ApplicationKeyRef != nil {
+		if cfg.ApplicationKeyRef.Name == "" {
+			allErrs = append(allErrs, field.Required(field.NewPath("applicationKeyRef", "name"), ""))
+		}
+	} else if cfg.ApplicationKey == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("applicationKey"), "or applicationKeyRef, credentialsSecretRef or credentialsBroker"))
+	}
+	if cfg.ApplicationSecretRef.Name == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("applicationSecretRef", "name"), ""))
+	}
+	return allErrs
+}
+
+// validateDuration checks that d, if set, is positive and at least min.
+func validateDuration(path *field.Path, d *metav1.Duration, min time.Duration) field.ErrorList {
+	switch {
+	case d == nil:
+		return nil
+	case d.Duration <= 0:
+		return field.ErrorList{field.Invalid(path, d.Duration.String(), "must be positive")}
+	case d.Duration < min:
+		return field.ErrorList{field.Invalid(path, d.Duration.String(), fmt.Sprintf("must be at least %s", min))}
+	}
+	return nil
+}
+
+// validateURL checks that rawURL is an absolute http or https URL.
+func validateURL(path *field.Path, rawURL string) field.ErrorList {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return field.ErrorList{field.Invalid(path, rawURL, "must be an http or https URL")}
+	}
+	return nil
+}
+
+// unknownConfigFields returns the fields of the raw JSON config, at any
+// depth, that the config does not have. They are most likely misspelled,
+// and would otherwise be silently ignored.
+func unknownConfigFields(raw []byte) field.ErrorList {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil
+	}
+	return unknownFields(nil, value, reflect.TypeOf(ddDNSProviderConfig{}))
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+func unknownFields(path *field.Path, value interface{}, t reflect.Type) field.ErrorList {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		// e.g. metav1.Duration, decoded from a string
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	switch value := value.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Map:
+			for _, key := range sortedKeys(value) {
+				allErrs = append(allErrs, unknownFields(path.Key(key), value[key], t.Elem())...)
+			}
+		case reflect.Struct:
+			fields := jsonFields(t)
+			for _, key := range sortedKeys(value) {
+				// encoding/json matches the names case-insensitively
+				f, ok := fields[strings.ToLower(key)]
+				if !ok {
+					allErrs = append(allErrs, field.Forbidden(childPath(path, key), "unknown field"))
+					continue
+				}
+				allErrs = append(allErrs, unknownFields(childPath(path, key), value[key], f.Type)...)
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice {
+			for i, item := range value {
+				allErrs = append(allErrs, unknownFields(path.Index(i), item, t.Elem())...)
+			}
+		}
+	}
+	return allErrs
+}
+
+// jsonFields returns the fields of the struct type t by lower case JSON
+// name, including those of its embedded structs, which its own fields
+// shadow like with encoding/json.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f
+	}
+	for _, et := range embedded {
+		for name, f := range jsonFields(et) {
+			if _, ok := fields[name]; !ok {
+				fields[name] = f
+			}
+		}
+	}
+	return fields
+}
+
+func childPath(path *field.Path, name string) *field.Path {
+	if path == nil {
+		return field.NewPath(name)
+	}
+	return path.Child(name)
+}
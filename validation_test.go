@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidateConfigAggregatesErrors(t *testing.T) {
+	cfg := &ddDNSProviderConfig{
+		ConflictPolicy: "overwrite",
+		AuthScheme:     "basic",
+		RecordTTL:      &metav1.Duration{Duration: 500 * time.Millisecond},
+		AllowedDomains: []string{"example.com", "*.*.example.com"},
+		DomainCredentials: map[string]domainCredentials{
+			"tenant.example": {ApplicationKey: "tenant"},
+		},
+	}
+	var got []string
+	for _, err := range validateConfig(cfg, false) {
+		got = append(got, err.Field)
+	}
+	want := []string{
+		"conflictPolicy",
+		"authScheme",
+		"recordTTL",
+		"allowedDomains[1]",
+		"domainCredentials[tenant.example].applicationSecretRef.name",
+		"endpoint",
+		"applicationKey",
+		"applicationSecretRef.name",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("validateConfig() fields = %v, want %v", got, want)
+	}
+}
+
+func TestValidateConfigValid(t *testing.T) {
+	cfg := &ddDNSProviderConfig{
+		Endpoint:       "https://simple-api.dondominio.net",
+		ApplicationKey: "user",
+		ApplicationSecretRef: corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "credentials"},
+		},
+	}
+	if errs := validateConfig(cfg, false); len(errs) != 0 {
+		t.Errorf("validateConfig() = %v", errs)
+	}
+}
+
+func TestUnknownConfigFields(t *testing.T) {
+	raw := `{
+		"Endpoint": "https://simple-api.dondominio.net",
+		"consumerKey": "x",
+		"applicationSecretRef": {"name": "credentials", "kye": "password"},
+		"recordTTL": "60s",
+		"domainCredentials": {"tenant.example": {"credentialSecretRef": {"name": "tenant"}}}
+	}`
+	var got []string
+	for _, err := range unknownConfigFields([]byte(raw)) {
+		if err.Type != field.ErrorTypeForbidden {
+			t.Errorf("unknownConfigFields() error type = %s", err.Type)
+		}
+		got = append(got, err.Field)
+	}
+	want := []string{
+		"applicationSecretRef.kye",
+		"consumerKey",
+		"domainCredentials[tenant.example].credentialSecretRef",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("unknownConfigFields() fields = %v, want %v", got, want)
+	}
+}
+
+func TestSolverConfigRejectsUnknownFields(t *testing.T) {
+	solver := testSolver()
+	_, err := solver.config(&v1alpha1.ChallengeRequest{
+		AllowAmbientCredentials: true,
+		Config:                  &extapi.JSON{Raw: []byte(`{"applicationSecret": "x", "recordTTL": "0s"}`)},
+	})
+	if err == nil {
+		t.Fatal("config() accepted an unknown field")
+	}
+	for _, want := range []string{"applicationSecret", "recordTTL"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("config() = %v, want an error about %s", err, want)
+		}
+	}
+}
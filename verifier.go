@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// verifierPool runs the DNS checks of the propagation waits on a bounded
+// number of workers. The checks are queued per zone and the zones are served
+// in turn, and a zone never uses more than half of the workers, so that a
+// zone whose records are slow to propagate does not delay the checks of the
+// other zones. Its zero value runs one check at a time.
+type verifierPool struct {
+	// size is the number of workers
+	size int
+
+	mu       sync.Mutex
+	running  int
+	inFlight map[string]int
+	queues   map[string][]*verifyJob
+	// zones lists the zones with queued checks, in the order they are served
+	zones []string
+}
+
+type verifyJob struct {
+	ctx   context.Context
+	check func()
+	done  chan struct{}
+}
+
+// zoneLimit returns the maximum number of checks of a zone run at once.
+func (p *verifierPool) zoneLimit() int {
+	if p.size < 2 {
+		return 1
+	}
+	return p.size / 2
+}
+
+// do runs check once a worker is available for zone, and waits for it to
+// complete. It returns early with the error of ctx if ctx is done before the
+// check starts.
+func (p *verifierPool) do(ctx context.Context, zone string, check func()) error {
+	job := &verifyJob{ctx: ctx, check: check, done: make(chan struct{})}
+
+	p.mu.Lock()
+	if p.queues == nil {
+		p.queues = make(map[string][]*verifyJob)
+		p.inFlight = make(map[string]int)
+	}
+	if len(p.queues[zone]) == 0 {
+		p.zones = append(p.zones, zone)
+	}
+	p.queues[zone] = append(p.queues[zone], job)
+	p.dispatch()
+	p.mu.Unlock()
+
+	select {
+	case <-job.done:
+		return nil
+	case <-ctx.Done():
+		// The job is skipped when its turn comes, or is already running
+		// and its result is ignored.
+		return ctx.Err()
+	}
+}
+
+// dispatch starts the queued checks while workers are available, taking
+// the zones in turn. It must be called with p.mu held.
+func (p *verifierPool) dispatch() {
+	size := p.size
+	if size < 1 {
+		size = 1
+	}
+
+	for p.running < size {
+		i := p.nextZone()
+		if i < 0 {
+			return
+		}
+		zone := p.zones[i]
+		job := p.queues[zone][0]
+		p.queues[zone] = p.queues[zone][1:]
+
+		// The zone goes to the back of the line
+		p.zones = append(p.zones[:i], p.zones[i+1:]...)
+		if len(p.queues[zone]) > 0 {
+			p.zones = append(p.zones, zone)
+		} else {
+			delete(p.queues, zone)
+		}
+
+		if job.ctx.Err() != nil {
+			continue
+		}
+		p.running++
+		p.inFlight[zone]++
+		go p.run(zone, job)
+	}
+}
+
+// nextZone returns the index in p.zones of the first zone below its limit,
+// or -1 if there is none.
+func (p *verifierPool) nextZone() int {
+	for i, zone := range p.zones {
+		if p.inFlight[zone] < p.zoneLimit() {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *verifierPool) run(zone string, job *verifyJob) {
+	job.check()
+	close(job.done)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running--
+	p.inFlight[zone]--
+	if p.inFlight[zone] == 0 {
+		delete(p.inFlight, zone)
+	}
+	p.dispatch()
+}
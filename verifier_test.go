@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVerifierPoolFairness(t *testing.T) {
+	pool := &verifierPool{size: 2}
+	release := make(chan struct{})
+
+	// A slow zone with several checks stuck on its nameservers
+	var slow sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		slow.Add(1)
+		go func() {
+			defer slow.Done()
+			pool.do(context.Background(), "slow.example", func() { <-release })
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ran := false
+	if err := pool.do(ctx, "fast.example", func() { ran = true }); err != nil || !ran {
+		t.Errorf("the check of another zone waited for the slow zone: %v", err)
+	}
+
+	close(release)
+	slow.Wait()
+}
+
+func TestVerifierPoolCancel(t *testing.T) {
+	pool := &verifierPool{size: 1}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go pool.do(context.Background(), "example.com", func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	ran := make(chan bool, 1)
+	if err := pool.do(ctx, "example.org", func() { ran <- true }); err != context.DeadlineExceeded {
+		t.Errorf("do() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case <-ran:
+		t.Error("a cancelled check was run")
+	default:
+	}
+}
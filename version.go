@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata of the webhook, set at build time with
+// -ldflags "-X main.version=... -X main.commit=... -X main.date=...".
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+// buildInfo describes the build of the webhook.
+type buildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+}
+
+// currentBuild returns the build of the webhook. The commit and date not set
+// with -ldflags are read from the VCS stamp of the binary, if any.
+func currentBuild() buildInfo {
+	build := buildInfo{
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && build.Commit == "":
+				build.Commit = setting.Value
+			case setting.Key == "vcs.time" && build.Date == "":
+				build.Date = setting.Value
+			}
+		}
+	}
+	return build
+}
+
+// String returns the build as printed by the version command.
+func (b buildInfo) String() string {
+	s := "cert-manager-webhook-dd " + b.Version
+	if b.Commit != "" {
+		s += " " + shortCommit(b.Commit)
+	}
+	if b.Date != "" {
+		s += " " + b.Date
+	}
+	return fmt.Sprintf("%s %s %s", s, b.GoVersion, b.Platform)
+}
+
+// userAgent returns the user agent of the webhook in the requests to
+// DonDominio, e.g. cert-manager-webhook-dd/v1.2.0+0a1b2c3.
+func (b buildInfo) userAgent() string {
+	ua := "cert-manager-webhook-dd/" + b.Version
+	if b.Commit != "" {
+		ua += "+" + shortCommit(b.Commit)
+	}
+	return ua
+}
+
+// shortCommit returns the abbreviated hash of a commit.
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// serveVersion serves the build of the webhook as JSON.
+func serveVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(currentBuild())
+}
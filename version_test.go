@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBuildUserAgent(t *testing.T) {
+	tests := []struct {
+		build buildInfo
+		want  string
+	}{
+		{buildInfo{Version: "dev"}, "cert-manager-webhook-dd/dev"},
+		{buildInfo{Version: "1.0.7", Commit: "0a1b2c3d4e5f"}, "cert-manager-webhook-dd/1.0.7+0a1b2c3"},
+	}
+	for _, tt := range tests {
+		if got := tt.build.userAgent(); got != tt.want {
+			t.Errorf("%+v.userAgent() = %q, want %q", tt.build, got, tt.want)
+		}
+	}
+}
+
+func TestServeVersion(t *testing.T) {
+	defer func(v, c, d string) { version, commit, date = v, c, d }(version, commit, date)
+	version, commit, date = "1.0.7", "0a1b2c3d4e5f", "2022-10-01T00:00:00Z"
+
+	rec := httptest.NewRecorder()
+	serveVersion(rec, httptest.NewRequest("GET", "/version", nil))
+
+	var got buildInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != "1.0.7" || got.Commit != "0a1b2c3d4e5f" || got.Date != "2022-10-01T00:00:00Z" || got.GoVersion == "" {
+		t.Errorf("version = %+v", got)
+	}
+}
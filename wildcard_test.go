@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// TestWildcardChallenges runs the challenges of an order for example.com and
+// *.example.com, which share the same record name with different keys.
+func TestWildcardChallenges(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, nil)
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	solver := testSolver()
+	apex := testChallenge(t, server.URL, "_acme-challenge.example.com.", "example.com.", "apex-key", nil)
+	wildcard := testChallenge(t, server.URL, "_acme-challenge.example.com.", "example.com.", "wildcard-key", nil)
+
+	records := func(values ...string) []Dns {
+		var records []Dns
+		for _, value := range values {
+			records = append(records, Dns{Name: "_acme-challenge.example.com", Type: "TXT", Value: value})
+		}
+		return records
+	}
+	steps := []struct {
+		name        string
+		run         func() error
+		wantRecords []Dns
+		wantTracked int
+	}{
+		{"present apex", func() error { return solver.Present(apex) }, records("apex-key"), 1},
+		{"present wildcard", func() error { return solver.Present(wildcard) }, records("apex-key", "wildcard-key"), 2},
+		{"clean up apex", func() error { return solver.CleanUp(apex) }, records("wildcard-key"), 1},
+		{"clean up wildcard", func() error { return solver.CleanUp(wildcard) }, nil, 0},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if _, got := api.result(); !reflect.DeepEqual(got, step.wantRecords) {
+			t.Errorf("%s: records = %+v, want %+v", step.name, got, step.wantRecords)
+		}
+		if got := len(solver.ledger.snapshot().Records); got != step.wantTracked {
+			t.Errorf("%s: %d records tracked by the ledger, want %d", step.name, got, step.wantTracked)
+		}
+	}
+}
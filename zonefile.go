@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportZone returns all the records of zone, to be written as a zone file
+// by WriteZoneFile.
+func (c *Client) ExportZone(ctx context.Context, zone string) ([]Record, error) {
+	return c.ListRecords(ctx, zone, RecordFilter{})
+}
+
+// ImportZone creates the records missing from zone and returns them. The
+// records already in the zone with the same name, type and value are skipped,
+// and those not in records are left alone, so that a zone can be restored
+// from its export without duplicating the records that survived.
+func (c *Client) ImportZone(ctx context.Context, zone string, records []RecordParams) ([]Record, error) {
+	for _, params := range records {
+		if err := validateRecordType(params.Type); err != nil {
+			return nil, fmt.Errorf("record %s: %w", params.Name, err)
+		}
+	}
+	existing, err := c.ListRecords(ctx, zone, RecordFilter{})
+	if err != nil {
+		return nil, err
+	}
+	var created []Record
+	for _, params := range records {
+		if hasRecord(existing, params) {
+			continue
+		}
+		record, err := c.CreateRecord(ctx, zone, params)
+		if err != nil {
+			return created, fmt.Errorf("failed to create the %s record %s: %w", params.Type, params.Name, err)
+		}
+		created = append(created, record)
+		existing = append(existing, record)
+	}
+	return created, nil
+}
+
+// hasRecord reports whether one of the records has the name, type and value
+// of params.
+func hasRecord(records []Record, params RecordParams) bool {
+	for _, record := range records {
+		if record.Name == params.Name && record.Type == params.Type && record.Value == params.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteZoneFile writes the records of zone in the BIND zone file format, with
+// fully qualified names. The records of the types the client does not
+// support, like the NS records of DonDominio, are written as comments, so
+// that the file can be imported back as is.
+func WriteZoneFile(w io.Writer, zone string, records []Record) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "$ORIGIN %s.\n", zone)
+	for _, record := range records {
+		ttl := ""
+		if record.TTL > 0 {
+			ttl = strconv.Itoa(int(record.TTL/time.Second)) + "\t"
+		}
+		comment := ""
+		if !recordTypes[record.Type] {
+			comment = "; "
+		}
+		fmt.Fprintf(bw, "%s%s.\t%sIN\t%s\t%s\n", comment, record.Name, ttl, record.Type, zoneFileData(record))
+	}
+	return bw.Flush()
+}
+
+// zoneFileData returns the data of the record in the zone file format.
+func zoneFileData(record Record) string {
+	switch record.Type {
+	case RecordTypeTXT:
+		return quoteTXT(record.Value)
+	case RecordTypeMX, RecordTypeSRV:
+		return strconv.Itoa(record.Priority) + " " + record.Value
+	}
+	return record.Value
+}
+
+// quoteTXT returns the value of a TXT record as a quoted string.
+func quoteTXT(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// ParseZoneFile parses a zone file of zone, as written by WriteZoneFile,
+// into the records to import. The names may be fully qualified, relative to
+// the origin or @, and the TTL and the IN class may be omitted. The
+// $ORIGIN and $TTL directives are supported, but not $INCLUDE nor the
+// records split across lines with parentheses.
+func ParseZoneFile(r io.Reader, zone string) ([]RecordParams, error) {
+	origin := zone
+	var defaultTTL time.Duration
+	var records []RecordParams
+	name := ""
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		fields, err := zoneFileFields(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "$ORIGIN":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: $ORIGIN requires a domain", line)
+			}
+			origin = zoneFileName(fields[1], origin)
+			continue
+		case "$TTL":
+			ttl, err := zoneFileTTL(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: $TTL: %w", line, err)
+			}
+			defaultTTL = ttl
+			continue
+		}
+
+		// A line starting with a blank is a record of the previous name
+		if text[0] != ' ' && text[0] != '\t' {
+			name = zoneFileName(fields[0], origin)
+			fields = fields[1:]
+		} else if name == "" {
+			return nil, fmt.Errorf("line %d: missing record name", line)
+		}
+		if name != zone && !strings.HasSuffix(name, "."+zone) {
+			return nil, fmt.Errorf("line %d: record %s is not in zone %s", line, name, zone)
+		}
+
+		params := RecordParams{Name: name, TTL: defaultTTL}
+		if len(fields) > 0 {
+			if ttl, err := strconv.ParseUint(fields[0], 10, 31); err == nil {
+				params.TTL = time.Duration(ttl) * time.Second
+				fields = fields[1:]
+			}
+		}
+		if len(fields) > 0 && strings.EqualFold(fields[0], "IN") {
+			fields = fields[1:]
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: missing record type or data", line)
+		}
+		params.Type = strings.ToUpper(fields[0])
+		if err := validateRecordType(params.Type); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		data := fields[1:]
+
+		switch params.Type {
+		case RecordTypeMX, RecordTypeSRV:
+			priority, err := strconv.Atoi(data[0])
+			if err != nil || len(data) < 2 {
+				return nil, fmt.Errorf("line %d: %s record requires a priority and a target", line, params.Type)
+			}
+			params.Priority = &priority
+			params.Value = strings.Join(data[1:], " ")
+		case RecordTypeTXT:
+			// The character strings of a TXT record are concatenated
+			for _, s := range data {
+				params.Value += unquoteTXT(s)
+			}
+		default:
+			params.Value = strings.Join(data, " ")
+		}
+		records = append(records, params)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// zoneFileFields splits a line of a zone file into its fields, keeping the
+// quoted strings whole with their quotes and dropping the comment.
+func zoneFileFields(line string) ([]string, error) {
+	var fields []string
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == ';':
+			return fields, nil
+		case c == '(' || c == ')':
+			return nil, fmt.Errorf("records split across lines are not supported")
+		case c == '"':
+			j := i + 1
+			for ; j < len(line) && line[j] != '"'; j++ {
+				if line[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(line) {
+				return nil, fmt.Errorf("unterminated quoted string")
+			}
+			fields = append(fields, line[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			for ; j < len(line) && !strings.ContainsRune(" \t\r;\"", rune(line[j])); j++ {
+			}
+			fields = append(fields, line[i:j])
+			i = j
+		}
+	}
+	return fields, nil
+}
+
+// unquoteTXT returns the value of a character string of a TXT record.
+func unquoteTXT(s string) string {
+	if len(s) < 2 || s[0] != '"' {
+		return s
+	}
+	s = s[1 : len(s)-1]
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// zoneFileName returns the fully qualified name, without the trailing dot,
+// of a name of a zone file.
+func zoneFileName(name, origin string) string {
+	switch {
+	case name == "@":
+		return origin
+	case strings.HasSuffix(name, "."):
+		return strings.ToLower(strings.TrimSuffix(name, "."))
+	}
+	return strings.ToLower(name) + "." + origin
+}
+
+// zoneFileTTL parses the TTL of a $TTL directive, in seconds.
+func zoneFileTTL(fields []string) (time.Duration, error) {
+	if len(fields) != 1 {
+		return 0, fmt.Errorf("requires a TTL")
+	}
+	ttl, err := strconv.ParseUint(fields[0], 10, 31)
+	if err != nil {
+		return 0, fmt.Errorf("invalid TTL %q", fields[0])
+	}
+	return time.Duration(ttl) * time.Second, nil
+}
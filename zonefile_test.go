@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestZoneFileRoundTrip(t *testing.T) {
+	priority := 10
+	records := []Record{
+		{Name: "example.com", Type: RecordTypeMX, TTL: time.Hour, Priority: priority, Value: "mail.example.com"},
+		{Name: "example.com", Type: RecordTypeCAA, Value: `0 issue "letsencrypt.org"`},
+		{Name: "example.com", Type: "NS", Value: "ns1.dondominio.com"},
+		{Name: "www.example.com", Type: RecordTypeA, TTL: 5 * time.Minute, Value: "192.0.2.1"},
+		{Name: "_acme-challenge.example.com", Type: RecordTypeTXT, Value: `key with "quotes" and \ ; not a comment`},
+	}
+	var buf bytes.Buffer
+	if err := WriteZoneFile(&buf, "example.com", records); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseZoneFile(&buf, "example.com")
+	if err != nil {
+		t.Fatalf("ParseZoneFile(%q): %v", buf.String(), err)
+	}
+	want := []RecordParams{
+		{Name: "example.com", Type: RecordTypeMX, TTL: time.Hour, Priority: &priority, Value: "mail.example.com"},
+		{Name: "example.com", Type: RecordTypeCAA, Value: `0 issue "letsencrypt.org"`},
+		{Name: "www.example.com", Type: RecordTypeA, TTL: 5 * time.Minute, Value: "192.0.2.1"},
+		{Name: "_acme-challenge.example.com", Type: RecordTypeTXT, Value: records[4].Value},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseZoneFile() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseZoneFile(t *testing.T) {
+	zoneFile := `$ORIGIN example.com.
+$TTL 600
+@	IN	A	192.0.2.1 ; the apex
+www		AAAA	2001:db8::1
+	300	TXT	"split " "value"
+other.example.org.	A	192.0.2.2
+`
+	_, err := ParseZoneFile(strings.NewReader(zoneFile), "example.com")
+	if err == nil || !strings.Contains(err.Error(), "line 6") {
+		t.Errorf("ParseZoneFile() error = %v, want a record out of the zone on line 6", err)
+	}
+
+	records, err := ParseZoneFile(strings.NewReader(strings.TrimSuffix(zoneFile, "other.example.org.\tA\t192.0.2.2\n")), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RecordParams{
+		{Name: "example.com", Type: RecordTypeA, TTL: 10 * time.Minute, Value: "192.0.2.1"},
+		{Name: "www.example.com", Type: RecordTypeAAAA, TTL: 10 * time.Minute, Value: "2001:db8::1"},
+		{Name: "www.example.com", Type: RecordTypeTXT, TTL: 5 * time.Minute, Value: "split value"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("ParseZoneFile() = %+v, want %+v", records, want)
+	}
+
+	for _, invalid := range []string{
+		"www IN PTR example.com.",
+		"www IN MX mail.example.com.",
+		`www IN TXT "unterminated`,
+		"@ IN SOA ns1 hostmaster (",
+	} {
+		if _, err := ParseZoneFile(strings.NewReader(invalid), "example.com"); err == nil {
+			t.Errorf("ParseZoneFile(%q) succeeded", invalid)
+		}
+	}
+}
+
+func TestImportZone(t *testing.T) {
+	api := newFixtureAPI(map[string]string{"example.com": "active"}, []Dns{
+		{Name: "www.example.com", Type: "A", Value: "192.0.2.1"},
+	})
+	server := httptest.NewServer(api)
+	defer server.Close()
+	ddClient, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := []RecordParams{
+		{Name: "www.example.com", Type: RecordTypeA, Value: "192.0.2.1"},
+		{Name: "mail.example.com", Type: RecordTypeA, Value: "192.0.2.25"},
+	}
+	created, err := ddClient.ImportZone(context.Background(), "example.com", records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 1 || created[0].Name != "mail.example.com" {
+		t.Errorf("ImportZone() = %+v, want only mail.example.com created", created)
+	}
+	exported, err := ddClient.ExportZone(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exported) != 2 {
+		t.Errorf("ExportZone() = %+v, want 2 records", exported)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// zoneHealthWindow is the sliding window over which the outcomes of the
+	// challenges of a zone are kept, and the minimum interval between two
+	// hints for the same zone.
+	zoneHealthWindow = time.Hour
+
+	// zoneHintMinFailures is the number of propagation failures of a zone
+	// within zoneHealthWindow from which a hint is logged, provided that
+	// fewer than half of its challenges succeeded.
+	zoneHintMinFailures = 3
+)
+
+// Stages at which a challenge fails, the empty stage being a success.
+const (
+	stagePresent     = "present"
+	stagePropagation = "propagation"
+)
+
+type challengeOutcome struct {
+	at    time.Time
+	stage string
+}
+
+type zoneOutcomes struct {
+	outcomes []challengeOutcome
+	hintedAt time.Time
+}
+
+// zoneRate sums up the outcomes of the challenges of a zone within
+// zoneHealthWindow.
+type zoneRate struct {
+	Challenges          int
+	Succeeded           int
+	PropagationFailures int
+}
+
+func (r zoneRate) successRate() float64 {
+	if r.Challenges == 0 {
+		return 1
+	}
+	return float64(r.Succeeded) / float64(r.Challenges)
+}
+
+// zoneHealth tracks the outcomes of the challenges of each zone over
+// zoneHealthWindow, so that a zone whose challenges persistently fail to
+// propagate gets a single consolidated hint instead of a failure per
+// challenge. Its zero value is ready to use.
+type zoneHealth struct {
+	mu    sync.Mutex
+	zones map[string]*zoneOutcomes
+}
+
+// record records the outcome of a challenge of zone, which failed at stage
+// or succeeded if stage is empty. It returns the rate of the zone and
+// whether a hint is due.
+func (h *zoneHealth) record(zone, stage string, now time.Time) (zoneRate, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.zones == nil {
+		h.zones = make(map[string]*zoneOutcomes)
+	}
+	z := h.zones[zone]
+	if z == nil {
+		z = &zoneOutcomes{}
+		h.zones[zone] = z
+	}
+
+	kept := z.outcomes[:0]
+	for _, outcome := range z.outcomes {
+		if now.Sub(outcome.at) < zoneHealthWindow {
+			kept = append(kept, outcome)
+		}
+	}
+	z.outcomes = append(kept, challengeOutcome{at: now, stage: stage})
+
+	var rate zoneRate
+	for _, outcome := range z.outcomes {
+		rate.Challenges++
+		switch outcome.stage {
+		case "":
+			rate.Succeeded++
+		case stagePropagation:
+			rate.PropagationFailures++
+		}
+	}
+
+	if stage != stagePropagation || rate.PropagationFailures < zoneHintMinFailures || rate.successRate() >= 0.5 {
+		return rate, false
+	}
+	if !z.hintedAt.IsZero() && now.Sub(z.hintedAt) < zoneHealthWindow {
+		return rate, false
+	}
+	z.hintedAt = now
+	return rate, true
+}
+
+// observeChallenge records the outcome of a challenge of zone and logs the
+// configuration changes likely to fix its propagation failures when they
+// persist.
+func (s *ddDNSProviderSolver) observeChallenge(ctx context.Context, cfg *ddDNSProviderConfig, zone, stage string) {
+	rate, hint := s.zoneHealth.record(zone, stage, time.Now())
+	if !hint {
+		return
+	}
+	klog.FromContext(ctx).Info("The challenges of the zone persistently fail to propagate",
+		"zone", zone, "window", zoneHealthWindow, "challenges", rate.Challenges,
+		"propagationFailures", rate.PropagationFailures, "successRate", rate.successRate(),
+		"recommendations", propagationRecommendations(cfg, zone))
+}
+
+// propagationRecommendations returns the configuration changes likely to fix
+// the propagation failures of the challenges of zone.
+func propagationRecommendations(cfg *ddDNSProviderConfig, zone string) []string {
+	settings := cfg.propagationSettings()
+	var recommendations []string
+	if cfg.RecordTTL == nil || cfg.RecordTTL.Duration > time.Minute {
+		recommendations = append(recommendations,
+			"set a lower recordTTL, e.g. 60s, so that the resolvers do not keep serving the records of a previous challenge")
+	}
+	recommendations = append(recommendations,
+		fmt.Sprintf("raise the propagationTimeout of the issuer above %s", settings.Timeout))
+	if len(settings.Resolvers) > 0 {
+		recommendations = append(recommendations,
+			fmt.Sprintf("check that the propagation resolvers %v can resolve the zone", settings.Resolvers))
+	}
+	recommendations = append(recommendations,
+		fmt.Sprintf("check that the zone is delegated to the DonDominio nameservers, e.g. with dig NS %s", zone))
+	return recommendations
+}
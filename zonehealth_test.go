@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestZoneHealthHint(t *testing.T) {
+	var h zoneHealth
+	start := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		zone  string
+		stage string
+		after time.Duration
+		hint  bool
+	}{
+		{"example.com", "", 0, false},
+		{"example.com", stagePropagation, time.Minute, false},
+		{"example.com", stagePropagation, 2 * time.Minute, false},
+		// Other zones do not count
+		{"example.org", stagePropagation, 3 * time.Minute, false},
+		{"example.com", stagePropagation, 4 * time.Minute, true},
+		// Hinted once per window
+		{"example.com", stagePropagation, 5 * time.Minute, false},
+		{"example.com", stagePropagation, 50 * time.Minute, false},
+		{"example.com", stagePropagation, 4*time.Minute + zoneHealthWindow, true},
+	}
+	for i, step := range steps {
+		_, hint := h.record(step.zone, step.stage, start.Add(step.after))
+		if hint != step.hint {
+			t.Errorf("step %d: hint = %v, want %v", i, hint, step.hint)
+		}
+	}
+}
+
+func TestZoneHealthSucceedingZone(t *testing.T) {
+	var h zoneHealth
+	start := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 4; i++ {
+		h.record("example.com", "", start)
+	}
+	for i := 0; i < zoneHintMinFailures; i++ {
+		rate, hint := h.record("example.com", stagePropagation, start)
+		if hint {
+			t.Fatalf("hint logged for a zone succeeding %.0f%% of the time", rate.successRate()*100)
+		}
+	}
+
+	// The successes leave the window
+	var rate zoneRate
+	var hint bool
+	for i := 0; i < zoneHintMinFailures; i++ {
+		rate, hint = h.record("example.com", stagePropagation, start.Add(zoneHealthWindow))
+	}
+	if !hint || rate.Challenges != zoneHintMinFailures {
+		t.Errorf("record() = %+v, %v, want a hint once only failures are in the window", rate, hint)
+	}
+}
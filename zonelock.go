@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// keyedMutex is a set of mutexes identified by a key, e.g. a name or a zone.
+// Its zero value is ready to use.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*refLock
+}
+
+// refLock is the lock of a key, held while its channel is full, deleted
+// once nobody holds or waits for it.
+type refLock struct {
+	held chan struct{}
+	refs int
+}
+
+// lock waits until nobody else holds key, and returns a function to call to
+// release it. It fails with the error of ctx if ctx is done first.
+func (k *keyedMutex) lock(ctx context.Context, key string) (unlock func(), err error) {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*refLock)
+	}
+	lock, ok := k.locks[key]
+	if !ok {
+		lock = &refLock{held: make(chan struct{}, 1)}
+		k.locks[key] = lock
+	}
+	lock.refs++
+	k.mu.Unlock()
+
+	release := func() {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(k.locks, key)
+		}
+	}
+	// Take a free lock even if ctx is done, select picking randomly among
+	// the ready cases
+	select {
+	case lock.held <- struct{}{}:
+	default:
+		select {
+		case lock.held <- struct{}{}:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return func() {
+		<-lock.held
+		release()
+	}, nil
+}
+
+// zoneSerializedProvider applies the record creations, updates and
+// deletions of a same zone one at a time, since concurrent mutations of a
+// DonDominio service, e.g. for the wildcard and apex names of a
+// certificate, may fail spuriously. The lookups and the mutations of
+// different zones still run in parallel.
+type zoneSerializedProvider struct {
+	DNSProvider
+	zones *keyedMutex
+}
+
+// CreateTXT implements DNSProvider.
+func (p zoneSerializedProvider) CreateTXT(ctx context.Context, zone, name, value string, ttl time.Duration) (TXTRecord, error) {
+	unlock, err := p.zones.lock(ctx, zone)
+	if err != nil {
+		return TXTRecord{}, err
+	}
+	defer unlock()
+	return p.DNSProvider.CreateTXT(ctx, zone, name, value, ttl)
+}
+
+// UpdateTXT implements DNSProvider.
+func (p zoneSerializedProvider) UpdateTXT(ctx context.Context, zone, id, value string, ttl time.Duration) (TXTRecord, error) {
+	unlock, err := p.zones.lock(ctx, zone)
+	if err != nil {
+		return TXTRecord{}, err
+	}
+	defer unlock()
+	return p.DNSProvider.UpdateTXT(ctx, zone, id, value, ttl)
+}
+
+// ListRecords implements recordManager, if the provider does.
+func (p zoneSerializedProvider) ListRecords(ctx context.Context, zone string, filter RecordFilter) ([]Record, error) {
+	manager, ok := p.DNSProvider.(recordManager)
+	if !ok {
+		return nil, errRecordTypesNotSupported
+	}
+	return manager.ListRecords(ctx, zone, filter)
+}
+
+// CreateRecord implements recordManager, if the provider does.
+func (p zoneSerializedProvider) CreateRecord(ctx context.Context, zone string, params RecordParams) (Record, error) {
+	manager, ok := p.DNSProvider.(recordManager)
+	if !ok {
+		return Record{}, errRecordTypesNotSupported
+	}
+	unlock, err := p.zones.lock(ctx, zone)
+	if err != nil {
+		return Record{}, err
+	}
+	defer unlock()
+	return manager.CreateRecord(ctx, zone, params)
+}
+
+// DeleteTXT implements DNSProvider.
+func (p zoneSerializedProvider) DeleteTXT(ctx context.Context, zone, id string) error {
+	unlock, err := p.zones.lock(ctx, zone)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return p.DNSProvider.DeleteTXT(ctx, zone, id)
+}
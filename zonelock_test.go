@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// overlapProvider records the largest number of mutations in flight, per
+// zone and overall.
+type overlapProvider struct {
+	DNSProvider
+	mu         sync.Mutex
+	inFlight   map[string]int
+	maxPerZone int
+	total      int
+	maxTotal   int
+}
+
+func (p *overlapProvider) mutate(zone string) {
+	p.mu.Lock()
+	p.inFlight[zone]++
+	p.total++
+	if p.inFlight[zone] > p.maxPerZone {
+		p.maxPerZone = p.inFlight[zone]
+	}
+	if p.total > p.maxTotal {
+		p.maxTotal = p.total
+	}
+	p.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	p.mu.Lock()
+	p.inFlight[zone]--
+	p.total--
+	p.mu.Unlock()
+}
+
+func (p *overlapProvider) CreateTXT(ctx context.Context, zone, name, value string, ttl time.Duration) (TXTRecord, error) {
+	p.mutate(zone)
+	return TXTRecord{Name: name, Value: value}, nil
+}
+
+func (p *overlapProvider) UpdateTXT(ctx context.Context, zone, id, value string, ttl time.Duration) (TXTRecord, error) {
+	p.mutate(zone)
+	return TXTRecord{ID: id, Value: value}, nil
+}
+
+func (p *overlapProvider) DeleteTXT(ctx context.Context, zone, id string) error {
+	p.mutate(zone)
+	return nil
+}
+
+func TestZoneSerializedProvider(t *testing.T) {
+	backend := &overlapProvider{inFlight: make(map[string]int)}
+	provider := zoneSerializedProvider{DNSProvider: backend, zones: &keyedMutex{}}
+
+	var wg sync.WaitGroup
+	for _, zone := range []string{"example.com", "example.org"} {
+		for i := 0; i < 3; i++ {
+			wg.Add(2)
+			go func(zone string) {
+				defer wg.Done()
+				provider.CreateTXT(context.Background(), zone, "_acme-challenge."+zone, "challenge-key", 0)
+			}(zone)
+			go func(zone string) {
+				defer wg.Done()
+				provider.DeleteTXT(context.Background(), zone, "1")
+			}(zone)
+		}
+	}
+	wg.Wait()
+
+	if backend.maxPerZone != 1 {
+		t.Errorf("%d mutations of a zone in flight at most, want 1", backend.maxPerZone)
+	}
+	if backend.maxTotal != 2 {
+		t.Errorf("%d mutations in flight at most, want the 2 zones in parallel", backend.maxTotal)
+	}
+	if len(provider.zones.locks) != 0 {
+		t.Errorf("%d zone locks left", len(provider.zones.locks))
+	}
+}
+
+func TestKeyedMutexContext(t *testing.T) {
+	var zones keyedMutex
+	unlock, err := zones.lock(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := zones.lock(ctx, "example.com"); err != context.DeadlineExceeded {
+		t.Errorf("lock of a held key = %v, want the deadline of the context", err)
+	}
+	other, err := zones.lock(context.Background(), "example.org")
+	if err != nil {
+		t.Fatalf("lock of another key = %v", err)
+	}
+	other()
+
+	unlock()
+	again, err := zones.lock(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("lock of a released key = %v", err)
+	}
+	again()
+	if len(zones.locks) != 0 {
+		t.Errorf("%d locks left", len(zones.locks))
+	}
+}
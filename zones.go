@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Domain is a domain of the DonDominio account, as listed by /domain/list.
+type Domain struct {
+	ID     int64  `json:"domainID,omitempty"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	TLD    string `json:"tld,omitempty"`
+	// TsExpir is the expiration date of the domain
+	TsExpir string `json:"tsExpir,omitempty"`
+}
+
+// Service is a service of the DonDominio account, as listed by
+// /service/list. The zones managed by the webhook are the DNS of the
+// services.
+type Service struct {
+	Name     string `json:"name"`
+	Type     string `json:"type,omitempty"`
+	Status   string `json:"status"`
+	TsExpir  string `json:"tsExpir,omitempty"`
+	TsCreate string `json:"tsCreate,omitempty"`
+}
+
+type ddDomainList struct {
+	ddResponse
+	ResponseData ddDomainListResponse `json:"responseData"`
+}
+
+type ddDomainListResponse struct {
+	QueryInfo QueryInfo `json:"queryInfo,omitempty"`
+	Domains   []Domain  `json:"domains"`
+}
+
+type ddServiceListAll struct {
+	ddResponse
+	ResponseData ddServiceListAllResponse `json:"responseData"`
+}
+
+type ddServiceListAllResponse struct {
+	QueryInfo QueryInfo `json:"queryInfo,omitempty"`
+	Services  []Service `json:"services"`
+}
+
+type ddPageParams struct {
+	Page int `schema:"page,omitempty"`
+}
+
+// listPages calls list for the pages 1, 2 and so on, until it has returned
+// the total number of results or an empty page. list returns the number of
+// results of the page and the total reported by the API, zero for the older
+// versions not reporting it.
+func listPages(list func(page int) (results int, total uint64, err error)) error {
+	listed := 0
+	for page := 1; ; page++ {
+		results, total, err := list(page)
+		if err != nil {
+			return err
+		}
+		listed += results
+		if results == 0 || uint64(listed) >= total {
+			return nil
+		}
+	}
+}
+
+// ListDomains returns the domains of the account, following the pages of the
+// response.
+func (c *Client) ListDomains(ctx context.Context) ([]Domain, error) {
+	url := "/domain/list"
+	var domains []Domain
+	err := listPages(func(page int) (int, uint64, error) {
+		list := ddDomainList{}
+		if err := c.PostWithContext(ctx, url, &ddPageParams{Page: page}, &list); err != nil {
+			return 0, 0, fmt.Errorf("DonDominio API call failed: POST %s - %w", url, err)
+		}
+		domains = append(domains, list.ResponseData.Domains...)
+		return len(list.ResponseData.Domains), list.ResponseData.QueryInfo.Total, nil
+	})
+	return domains, err
+}
+
+// ListServices returns the services of the account, following the pages of
+// the response.
+func (c *Client) ListServices(ctx context.Context) ([]Service, error) {
+	url := "/service/list"
+	var services []Service
+	err := listPages(func(page int) (int, uint64, error) {
+		list := ddServiceListAll{}
+		if err := c.PostWithContext(ctx, url, &ddPageParams{Page: page}, &list); err != nil {
+			return 0, 0, fmt.Errorf("DonDominio API call failed: POST %s - %w", url, err)
+		}
+		services = append(services, list.ResponseData.Services...)
+		return len(list.ResponseData.Services), list.ResponseData.QueryInfo.Total, nil
+	})
+	return services, err
+}
+
+// ManagedZones returns the sorted names of the active services of the
+// account, i.e. the zones whose records the credentials can manage.
+func (c *Client) ManagedZones(ctx context.Context) ([]string, error) {
+	services, err := c.ListServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var zones []string
+	for _, service := range services {
+		if service.Status == "active" {
+			zones = append(zones, service.Name)
+		}
+	}
+	sort.Strings(zones)
+	return zones, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestListServicesPages(t *testing.T) {
+	services := []string{"example.com", "example.net", "example.org"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		page := r.PostForm.Get("page")
+		var body string
+		switch {
+		case r.URL.Path == "/service/list" && page == "1":
+			body = fmt.Sprintf(`{"name": %q, "status": "active"}, {"name": %q, "status": "inactive"}`, services[0], services[1])
+		case r.URL.Path == "/service/list" && page == "2":
+			body = fmt.Sprintf(`{"name": %q, "status": "active"}`, services[2])
+		case r.URL.Path == "/service/list":
+			t.Errorf("page %s requested past the total", page)
+		case r.URL.Path == "/domain/list":
+			fmt.Fprint(w, `{"success": true, "responseData": {"queryInfo": {"total": 1}, "domains": [{"domainID": 42, "name": "example.com", "status": "active", "tld": "com"}]}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"success": true, "responseData": {"queryInfo": {"total": 3}, "services": [%s]}}`, body)
+	}))
+	defer server.Close()
+
+	ddClient, err := NewClient(server.URL, "apiuser", "apipasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zones, err := ddClient.ManagedZones(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"example.com", "example.org"}; !reflect.DeepEqual(zones, want) {
+		t.Errorf("ManagedZones() = %v, want %v", zones, want)
+	}
+
+	domains, err := ddClient.ListDomains(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Domain{{ID: 42, Name: "example.com", Status: "active", TLD: "com"}}; !reflect.DeepEqual(domains, want) {
+		t.Errorf("ListDomains() = %+v, want %+v", domains, want)
+	}
+}